@@ -0,0 +1,110 @@
+package main
+
+import (
+	"BookStore_API/internal/admin"
+	"BookStore_API/internal/config"
+	"BookStore_API/internal/payment"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/service"
+	"BookStore_API/internal/worker"
+	"BookStore_API/internal/zaplog"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"syscall"
+	"time"
+)
+
+// bookstore-admin changes the catalog and orders from the command line. It connects to the
+// database like the API and goes through the same services, so changes are validated,
+// audited and published as events the running API delivers.
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv == "" {
+		appEnv = "development"
+	}
+	// stdout is kept for the output of the commands, only warnings are logged by default
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "warn"
+	}
+	logger := zaplog.InitLogger(appEnv, "console", logLevel, os.Getenv("LOG_LEVELS"))
+	defer logger.Sync()
+
+	fs := flag.NewFlagSet("bookstore-admin", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), admin.Usage)
+	}
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML file with settings")
+	asJSON := fs.Bool("json", false, "print JSON instead of tables")
+	actor := fs.String("actor", defaultActor(), "name recorded in the audit log")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(appEnv, config.Options{File: *file}, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx = reqctx.WithActor(ctx, *actor)
+
+	connectCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	db, err := postgres.NewPostgresDB(connectCtx, &cfg.DBCfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to the database:", err)
+		return 1
+	}
+	defer db.Close()
+
+	provider, err := payment.NewProvider(cfg.PaymentCfg, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize payment provider:", err)
+		return 1
+	}
+
+	// events are stored in the outbox and delivered by the API, no sinks or workers run here
+	repo := repository.NewRepository(db, cfg, logger)
+	services := service.NewService(repo, cfg, provider, nil, 0, worker.NewGroup(logger), logger)
+
+	err = admin.New(services, os.Stdout, *asJSON).Run(ctx, fs.Args())
+	if errors.Is(err, admin.ErrUsage) {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// defaultActor names the operating system user, so the audit log tells who ran a command.
+func defaultActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "admin:" + u.Username
+	}
+	return "admin"
+}
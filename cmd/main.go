@@ -4,8 +4,12 @@ import (
 	"BookStore_API/internal/app"
 	"BookStore_API/internal/config"
 	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/tracing"
 	"BookStore_API/internal/zaplog"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"go.uber.org/zap"
 	"os"
 	"time"
@@ -21,13 +25,43 @@ func main() {
 		logFormat = "json"
 	}
 
-	logger := zaplog.InitLogger(appEnv, logFormat)
+	logger := zaplog.InitLogger(appEnv, logFormat, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_LEVELS"))
 	logger.Info("Logger successfully initialized.")
 
+	opts, err := config.ParseOptions(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Fatal("Failed to parse command line.", zap.Error(err))
+	}
+	if len(opts.Args) > 0 && opts.Args[0] != "migrate" {
+		logger.Fatal("Unknown command.", zap.String("command", opts.Args[0]))
+	}
+
 	logger.Info("Initializing config...")
-	cfg := config.InitConfig(logger, appEnv)
+	cfg, err := config.Load(appEnv, opts, logger)
+	if opts.PrintConfig {
+		printConfig(cfg, err)
+		return
+	}
+	if err != nil {
+		logger.Fatal("Failed to initialize config.", zap.Error(err))
+	}
+	// the file and flags may set other levels than the environment
+	if err = zaplog.SetLevels(cfg.LogCfg.Level, cfg.LogCfg.Levels); err != nil {
+		logger.Fatal("Failed to set log levels.", zap.Error(err))
+	}
+	// secrets of the config are masked by the logger
 	logger.Info("Config successfully initialized:", zap.Any("cfg", cfg))
 
+	logger.Info("Initializing tracing...")
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracingCfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing.", zap.Error(err))
+	}
+	logger.Info("Tracing successfully initialized.", zap.String("exporter", cfg.TracingCfg.Exporter))
+
 	logger.Info("Initializing DB connection...")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -36,13 +70,55 @@ func main() {
 	if err != nil {
 		logger.Fatal("Failed to initialize DB connection.", zap.Error(err))
 	}
-	defer func() {
-		logger.Info("Closing DB connection...")
-		db.Close()
-	}()
 	logger.Info("DB connection successfully initialized.")
 
+	if len(opts.Args) > 0 {
+		err = app.Migrate(cfg, db, opts.Args[1:], os.Stdout, logger)
+		db.Close()
+		if err != nil {
+			logger.Fatal("Migration failed.", zap.Error(err))
+		}
+		return
+	}
+
+	logger.Info("Checking DB schema...")
+	if err = app.PrepareSchema(cfg, db, logger); err != nil {
+		db.Close()
+		logger.Fatal("DB schema is not ready, run migrate up or set MIGRATE_ON_START.", zap.Error(err))
+	}
+	logger.Info("DB schema is up to date.")
+
 	logger.Info("Running application...")
-	app.ApplicationRun(cfg, logger, db)
+	reload := func() (*config.Config, error) {
+		return config.Load(appEnv, opts, logger)
+	}
+	err = app.ApplicationRun(cfg, reload, logger, db)
+
+	// the pool is closed last, after the server and the workers are done with it
+	logger.Info("Closing DB connection...")
+	db.Close()
+
+	// spans still buffered are sent before exiting
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if tracingErr := shutdownTracing(ctx); tracingErr != nil {
+		logger.Error("Failed to flush traces.", zap.Error(tracingErr))
+	}
+
+	if err != nil {
+		logger.Fatal("Application failed.", zap.Error(err))
+	}
 	logger.Info("Application closed.")
 }
+
+// printConfig writes the effective config to stdout, and its errors to stderr with a failing
+// exit code, so --print-config can check a deployment.
+func printConfig(cfg *config.Config, err error) {
+	if cfg != nil {
+		_ = config.Print(os.Stdout, cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
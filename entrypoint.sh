@@ -11,7 +11,7 @@ done
 echo "$(date) | Database is ready!"
 
 echo "$(date) | Running migrations..."
-migrate -path /migrations -database "$DB_URL" up
+/bookstore-api migrate up
 
 echo "$(date) | Starting application..."
 exec "$@"
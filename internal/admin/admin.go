@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Usage lists the commands of bookstore-admin.
+const Usage = `usage: bookstore-admin [--config FILE] [--json] [--actor NAME] COMMAND
+
+commands:
+  book get ID
+  book create --name NAME --author AUTHOR --isbn ISBN [--price P] [--stock N] [--tax-class C] [--weight-grams G]
+  book update ID [--name NAME] [--author AUTHOR] [--isbn ISBN] [--price P] [--stock N] [--tax-class C] [--weight-grams G] [--version V]
+  book delete ID [--version V]
+  magazine get ID
+  magazine create --name NAME --issue-number N --publication-date YYYY-MM-DD [--price P] [--stock N] [--tax-class C] [--weight-grams G]
+  magazine update ID [--name NAME] [--issue-number N] [--publication-date YYYY-MM-DD] [...] [--version V]
+  magazine delete ID [--version V]
+  stock adjust [--file FILE|-] [--dry-run] [ID:DELTA ...]
+  order get ID
+  order status ID STATUS [--tracking-number T] [--version V]
+  order history ID`
+
+var (
+	ErrUsage = errors.New("invalid command")
+	// ErrPartialFailure is returned when some items of a bulk command failed, the others are applied.
+	ErrPartialFailure = errors.New("some changes failed")
+)
+
+// Admin runs the commands of bookstore-admin through the service layer, so changes pass the
+// same checks and are audited and announced as domain events like API requests.
+type Admin struct {
+	services *service.Service
+	out      io.Writer
+	json     bool
+}
+
+func New(services *service.Service, out io.Writer, asJSON bool) *Admin {
+	return &Admin{
+		services: services,
+		out:      out,
+		json:     asJSON,
+	}
+}
+
+func (a *Admin) Run(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "book":
+		return a.book(ctx, args[1], args[2:])
+	case "magazine":
+		return a.magazine(ctx, args[1], args[2:])
+	case "stock":
+		if args[1] == "adjust" {
+			return a.adjustStock(ctx, args[2:])
+		}
+	case "order":
+		return a.order(ctx, args[1], args[2:])
+	}
+	return ErrUsage
+}
+
+// parseArgs parses the flags of a command, which may come before or after its arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// parseId reads the leading id argument and returns the remaining arguments.
+func parseId(args []string) (int, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return 0, nil, fmt.Errorf("%w: missing id", ErrUsage)
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, nil, fmt.Errorf("%w: invalid id %q", ErrUsage, args[0])
+	}
+	return id, args[1:], nil
+}
+
+// printRecord writes v as JSON, or its fields one per line.
+func (a *Admin) printRecord(v any, fields [][2]string) error {
+	if a.json {
+		return a.printJSON(v)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	for _, f := range fields {
+		fmt.Fprintf(w, "%s\t%s\n", f[0], f[1])
+	}
+	return w.Flush()
+}
+
+// printTable writes v as JSON, or the rows as a table under header.
+func (a *Admin) printTable(v any, header []string, rows [][]string) error {
+	if a.json {
+		return a.printJSON(v)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (a *Admin) printJSON(v any) error {
+	enc := json.NewEncoder(a.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
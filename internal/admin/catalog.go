@@ -0,0 +1,223 @@
+package admin
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/entity"
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+func (a *Admin) book(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "get":
+		id, _, err := parseId(args)
+		if err != nil {
+			return err
+		}
+		return a.printBook(ctx, id)
+	case "create":
+		var doc dto.BookCreateRequest
+		fs := flag.NewFlagSet("book create", flag.ContinueOnError)
+		bookFlags(fs, &doc)
+		if err := parseNoArgs(fs, args); err != nil {
+			return err
+		}
+
+		if err := doc.Validate(); err != nil {
+			return err
+		}
+		id, err := a.services.Book.Create(ctx, doc.ToEntity())
+		if err != nil {
+			return err
+		}
+		return a.printBook(ctx, id)
+	case "update":
+		id, args, err := parseId(args)
+		if err != nil {
+			return err
+		}
+		book, err := a.services.Book.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// flags change the stored book, which is validated like a new one
+		doc := dto.NewBookPatchDocument(book)
+		fs := flag.NewFlagSet("book update", flag.ContinueOnError)
+		bookFlags(fs, &doc)
+		fs.IntVar(&book.Version, "version", book.Version, "expected version, the stored one by default")
+		if err = parseNoArgs(fs, args); err != nil {
+			return err
+		}
+
+		if err = doc.Validate(); err != nil {
+			return err
+		}
+		doc.ApplyToEntity(&book)
+		if err = a.services.Book.Update(ctx, book); err != nil {
+			return err
+		}
+		return a.printBook(ctx, id)
+	case "delete":
+		return a.deleteProduct(ctx, "book delete", args, a.services.Book.Delete)
+	}
+	return ErrUsage
+}
+
+func (a *Admin) magazine(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "get":
+		id, _, err := parseId(args)
+		if err != nil {
+			return err
+		}
+		return a.printMagazine(ctx, id)
+	case "create":
+		var doc dto.MagazineCreateRequest
+		fs := flag.NewFlagSet("magazine create", flag.ContinueOnError)
+		magazineFlags(fs, &doc)
+		if err := parseNoArgs(fs, args); err != nil {
+			return err
+		}
+
+		if err := doc.Validate(); err != nil {
+			return err
+		}
+		id, err := a.services.Magazine.Create(ctx, doc.ToEntity())
+		if err != nil {
+			return err
+		}
+		return a.printMagazine(ctx, id)
+	case "update":
+		id, args, err := parseId(args)
+		if err != nil {
+			return err
+		}
+		mag, err := a.services.Magazine.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		doc := dto.NewMagazinePatchDocument(mag)
+		fs := flag.NewFlagSet("magazine update", flag.ContinueOnError)
+		magazineFlags(fs, &doc)
+		fs.IntVar(&mag.Version, "version", mag.Version, "expected version, the stored one by default")
+		if err = parseNoArgs(fs, args); err != nil {
+			return err
+		}
+
+		if err = doc.Validate(); err != nil {
+			return err
+		}
+		doc.ApplyToEntity(&mag)
+		if err = a.services.Magazine.Update(ctx, mag); err != nil {
+			return err
+		}
+		return a.printMagazine(ctx, id)
+	case "delete":
+		return a.deleteProduct(ctx, "magazine delete", args, a.services.Magazine.Delete)
+	}
+	return ErrUsage
+}
+
+func (a *Admin) deleteProduct(ctx context.Context, name string, args []string, remove func(ctx context.Context, id, version int) error) error {
+	id, args, err := parseId(args)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	version := fs.Int("version", 0, "expected version, any by default")
+	if err = parseNoArgs(fs, args); err != nil {
+		return err
+	}
+
+	if err = remove(ctx, id, *version); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(a.out, "%d moved to the trash\n", id)
+	return err
+}
+
+func productFlags(fs *flag.FlagSet, name *string, price *float64, stock *int, taxClass *string, weightGrams *int) {
+	fs.StringVar(name, "name", *name, "name")
+	fs.Float64Var(price, "price", *price, "price in the base currency")
+	fs.IntVar(stock, "stock", *stock, "units in stock")
+	fs.StringVar(taxClass, "tax-class", *taxClass, "tax class")
+	fs.IntVar(weightGrams, "weight-grams", *weightGrams, "shipping weight in grams")
+}
+
+func bookFlags(fs *flag.FlagSet, doc *dto.BookCreateRequest) {
+	productFlags(fs, &doc.Name, &doc.Price, &doc.Stock, &doc.TaxClass, &doc.WeightGrams)
+	fs.StringVar(&doc.Author, "author", doc.Author, "author")
+	fs.StringVar(&doc.Isbn, "isbn", doc.Isbn, "ISBN-13 with a hyphen")
+}
+
+func magazineFlags(fs *flag.FlagSet, doc *dto.MagazineCreateRequest) {
+	productFlags(fs, &doc.Name, &doc.Price, &doc.Stock, &doc.TaxClass, &doc.WeightGrams)
+	fs.IntVar(&doc.IssueNumber, "issue-number", doc.IssueNumber, "issue number")
+	fs.Func("publication-date", "publication date as YYYY-MM-DD", func(v string) error {
+		date, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return err
+		}
+		doc.PublicationDate = date
+		return nil
+	})
+}
+
+// parseNoArgs parses the flags of a command that takes no further arguments.
+func parseNoArgs(fs *flag.FlagSet, args []string) error {
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", ErrUsage, rest[0])
+	}
+	return nil
+}
+
+func (a *Admin) printBook(ctx context.Context, id int) error {
+	book, err := a.services.Book.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fields := append(productFields(book.BaseProduct),
+		[2]string{"author", book.Author},
+		[2]string{"isbn", book.Isbn},
+	)
+	return a.printRecord(dto.FromEntityBook(book), fields)
+}
+
+func (a *Admin) printMagazine(ctx context.Context, id int) error {
+	mag, err := a.services.Magazine.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fields := append(productFields(mag.BaseProduct),
+		[2]string{"issue number", strconv.Itoa(mag.IssueNumber)},
+		[2]string{"publication date", mag.PublicationDate.Format(dateLayout)},
+	)
+	return a.printRecord(dto.FromEntityMagazine(mag), fields)
+}
+
+func productFields(p entity.BaseProduct) [][2]string {
+	return [][2]string{
+		{"id", strconv.Itoa(p.Id)},
+		{"name", p.Name},
+		{"price", formatAmount(p.Price)},
+		{"stock", strconv.Itoa(p.Stock)},
+		{"tax class", p.TaxClass},
+		{"weight grams", strconv.Itoa(p.WeightGrams)},
+		{"version", strconv.Itoa(p.Version)},
+		{"created at", p.CreatedAt.Format(time.RFC3339)},
+	}
+}
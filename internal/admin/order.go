@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/entity"
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func (a *Admin) order(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "get":
+		id, args, err := parseId(args)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			return fmt.Errorf("%w: unexpected argument %q", ErrUsage, args[0])
+		}
+		return a.printOrder(ctx, id)
+	case "status":
+		return a.changeOrderStatus(ctx, args)
+	case "history":
+		id, args, err := parseId(args)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			return fmt.Errorf("%w: unexpected argument %q", ErrUsage, args[0])
+		}
+		return a.printOrderHistory(ctx, id)
+	case "customer":
+		// orders are placed without a customer, there is nothing to look them up by
+		return fmt.Errorf("%w: orders are not linked to customers, look them up by id", ErrUsage)
+	}
+	return ErrUsage
+}
+
+// changeOrderStatus moves an order to another status with the same rules as the API, so it
+// cannot be marked paid and needs a tracking number to be shipped.
+func (a *Admin) changeOrderStatus(ctx context.Context, args []string) error {
+	id, args, err := parseId(args)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("order status", flag.ContinueOnError)
+	trackingNumber := fs.String("tracking-number", "", "tracking number of a shipped order")
+	version := fs.Int("version", 0, "expected version, any by default")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: expected one status", ErrUsage)
+	}
+
+	req := dto.OrderUpdateRequest{Status: &positional[0]}
+	if *trackingNumber != "" {
+		req.TrackingNumber = trackingNumber
+	}
+	if err = req.Validate(); err != nil {
+		return err
+	}
+
+	order, err := a.services.Order.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if *version != 0 {
+		order.Version = *version
+	}
+
+	req.ApplyToEntity(&order)
+	if err = a.services.Order.Update(ctx, order); err != nil {
+		return err
+	}
+	return a.printOrder(ctx, id)
+}
+
+func (a *Admin) printOrder(ctx context.Context, id int) error {
+	order, err := a.services.Order.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(dto.FromEntityOrder(order))
+	}
+
+	_, taxTotal, total := order.Totals()
+	fields := [][2]string{
+		{"id", strconv.Itoa(order.Id)},
+		{"status", order.Status},
+		{"currency", order.Currency},
+		{"shipping", order.Shipping.Method},
+		{"tracking number", order.Shipping.TrackingNumber},
+		{"tax", formatAmount(taxTotal)},
+		{"total", formatAmount(total)},
+		{"version", strconv.Itoa(order.Version)},
+		{"created at", order.CreatedAt.Format(time.RFC3339)},
+	}
+	if err = a.printRecord(nil, fields); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.out)
+	rows := make([][]string, len(order.Items))
+	for i, item := range order.Items {
+		rows[i] = []string{strconv.Itoa(item.Product.Id), item.ProductType, item.Product.Name,
+			strconv.Itoa(item.Quantity), formatAmount(item.Product.Price)}
+	}
+	return a.printTable(nil, []string{"PRODUCT", "TYPE", "NAME", "QUANTITY", "PRICE"}, rows)
+}
+
+func (a *Admin) printOrderHistory(ctx context.Context, id int) error {
+	// the order is read first, so an unknown id is reported instead of an empty history
+	if _, err := a.services.Order.GetById(ctx, id); err != nil {
+		return err
+	}
+	history, err := a.services.Order.GetHistory(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.OrderHistoryResponse, len(history))
+	rows := make([][]string, len(history))
+	for i, entry := range history {
+		resp[i] = dto.FromEntityOrderHistory(entry)
+		rows[i] = historyRow(entry)
+	}
+	return a.printTable(resp, []string{"TIME", "EVENT", "DETAILS"}, rows)
+}
+
+func historyRow(entry entity.OrderHistoryEntry) []string {
+	return []string{entry.CreatedAt.Format(time.RFC3339), entry.Event, entry.Details}
+}
@@ -0,0 +1,200 @@
+package admin
+
+import (
+	"BookStore_API/internal/repository"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stockRetries is how often an adjustment is retried when the product changed meanwhile.
+const stockRetries = 3
+
+type stockChange struct {
+	Id    int `json:"id"`
+	Delta int `json:"delta"`
+}
+
+type stockResult struct {
+	Id       int    `json:"id"`
+	Type     string `json:"type,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Delta    int    `json:"delta"`
+	Stock    int    `json:"stock"`
+	NewStock int    `json:"newStock"`
+	Error    string `json:"error,omitempty"`
+}
+
+// adjustStock changes the stock of products by a delta each, given as ID:DELTA arguments or
+// one per line of a file, "-" for stdin. Every product is updated on its own, so a failure
+// leaves the other adjustments applied.
+func (a *Admin) adjustStock(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stock adjust", flag.ContinueOnError)
+	file := fs.String("file", "", "file with one ID:DELTA per line, - for stdin")
+	dryRun := fs.Bool("dry-run", false, "print the resulting stock without changing it")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	changes, err := parseStockChanges(positional)
+	if err != nil {
+		return err
+	}
+	if *file != "" {
+		fromFile, err := readStockFile(*file)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, fromFile...)
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("%w: no stock changes given", ErrUsage)
+	}
+
+	results := make([]stockResult, len(changes))
+	failed := false
+	for i, change := range changes {
+		results[i] = a.adjustProduct(ctx, change, *dryRun)
+		failed = failed || results[i].Error != ""
+	}
+
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		status := "ok"
+		if *dryRun {
+			status = "dry run"
+		}
+		if r.Error != "" {
+			status = r.Error
+		}
+		rows[i] = []string{strconv.Itoa(r.Id), r.Type, r.Name, fmt.Sprintf("%+d", r.Delta),
+			strconv.Itoa(r.Stock), strconv.Itoa(r.NewStock), status}
+	}
+	if err = a.printTable(results, []string{"ID", "TYPE", "NAME", "DELTA", "STOCK", "NEW STOCK", "STATUS"}, rows); err != nil {
+		return err
+	}
+
+	if failed {
+		return ErrPartialFailure
+	}
+	return nil
+}
+
+// adjustProduct applies one change to a book or a magazine, whichever has the id. The stock
+// is read again when another request updated the product meanwhile.
+func (a *Admin) adjustProduct(ctx context.Context, change stockChange, dryRun bool) stockResult {
+	result := stockResult{Id: change.Id, Delta: change.Delta}
+
+	var err error
+	for attempt := 0; attempt < stockRetries; attempt++ {
+		err = a.tryAdjust(ctx, change, dryRun, &result)
+		if !errors.Is(err, repository.ErrVersionConflict) {
+			break
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (a *Admin) tryAdjust(ctx context.Context, change stockChange, dryRun bool, result *stockResult) error {
+	book, err := a.services.Book.GetById(ctx, change.Id)
+	if err == nil {
+		result.Type, result.Name = "book", book.Name
+		if err = newStock(&book.Stock, change.Delta, result); err != nil || dryRun {
+			return err
+		}
+		return a.services.Book.Update(ctx, book)
+	}
+	// the id of a magazine is found as a product of another type
+	if !errors.Is(err, repository.ErrProductNotFound) && !errors.Is(err, repository.ErrInvalidProductType) {
+		return err
+	}
+
+	mag, err := a.services.Magazine.GetById(ctx, change.Id)
+	if err != nil {
+		return err
+	}
+	result.Type, result.Name = "magazine", mag.Name
+	if err = newStock(&mag.Stock, change.Delta, result); err != nil || dryRun {
+		return err
+	}
+	return a.services.Magazine.Update(ctx, mag)
+}
+
+func newStock(stock *int, delta int, result *stockResult) error {
+	result.Stock = *stock
+	result.NewStock = *stock + delta
+	if result.NewStock < 0 {
+		return fmt.Errorf("stock would become %d", result.NewStock)
+	}
+	*stock = result.NewStock
+	return nil
+}
+
+func parseStockChanges(args []string) ([]stockChange, error) {
+	changes := make([]stockChange, 0, len(args))
+	for _, arg := range args {
+		change, err := parseStockChange(arg)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func parseStockChange(s string) (stockChange, error) {
+	idPart, deltaPart, ok := strings.Cut(s, ":")
+	if !ok {
+		return stockChange{}, fmt.Errorf("%w: expected ID:DELTA, got %q", ErrUsage, s)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(idPart))
+	if err != nil || id <= 0 {
+		return stockChange{}, fmt.Errorf("%w: invalid id in %q", ErrUsage, s)
+	}
+	delta, err := strconv.Atoi(strings.TrimSpace(deltaPart))
+	if err != nil || delta == 0 {
+		return stockChange{}, fmt.Errorf("%w: invalid delta in %q", ErrUsage, s)
+	}
+	return stockChange{Id: id, Delta: delta}, nil
+}
+
+// readStockFile reads one ID:DELTA per line, blank lines and lines starting with # are skipped.
+func readStockFile(path string) ([]stockChange, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stock file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var changes []stockChange
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		change, err := parseStockChange(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		changes = append(changes, change)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stock file: %w", err)
+	}
+	return changes, nil
+}
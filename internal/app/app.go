@@ -3,31 +3,141 @@ package app
 import (
 	"BookStore_API/internal/config"
 	"BookStore_API/internal/handler"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/outbox"
+	"BookStore_API/internal/payment"
+	"BookStore_API/internal/postgres"
 	"BookStore_API/internal/repository"
 	"BookStore_API/internal/service"
+	"BookStore_API/internal/worker"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func ApplicationRun(cfg *config.Config, logger *zap.Logger, db *pgxpool.Pool) {
-	repo := repository.NewRepository(db, logger)
-	services := service.NewService(repo, logger)
-	handlers := handler.NewHandler(services, logger)
+// ApplicationRun serves requests until SIGINT or SIGTERM and then shuts down gracefully,
+// the caller closes the database pool once it returns. On SIGHUP the config is read again
+// with reload and the settings that are safe to change while running are applied.
+func ApplicationRun(cfg *config.Config, reload func() (*config.Config, error), logger *zap.Logger, db *pgxpool.Pool) error {
+	repo := repository.NewRepository(db, cfg, logger)
 
-	runServer(handlers, cfg.Port, logger)
+	if err := metrics.RegisterPool(db); err != nil {
+		return fmt.Errorf("failed to register pool metrics: %w", err)
+	}
+
+	provider, err := payment.NewProvider(cfg.PaymentCfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize payment provider: %w", err)
+	}
+
+	sinks, err := outbox.NewSinks(cfg.OutboxCfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize outbox sinks: %w", err)
+	}
+	defer outbox.CloseAll(sinks, logger)
+
+	migrationVersion, err := postgres.LatestMigrationVersion()
+	if err != nil {
+		logger.Warn("Failed to read migrations, the schema version is not checked", zap.Error(err))
+	}
+
+	workers := worker.NewGroup(logger)
+	services := service.NewService(repo, cfg, provider, sinks, migrationVersion, workers, logger)
+	handlers := handler.NewHandler(services, cfg, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	workers.Start(newWorkers(cfg, services, logger)...)
+
+	e := newServer(handlers)
+	serverErr := make(chan error, 1)
+	go func() {
+		addr := fmt.Sprintf(":%d", cfg.Port)
+		logger.Info("Starting server...", zap.String("address", addr))
+		serverErr <- e.Start(addr)
+	}()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutdown signal received")
+			break wait
+		case err = <-serverErr:
+			err = fmt.Errorf("failed to start server: %w", err)
+			break wait
+		case <-hup:
+			cfg = reloadConfig(cfg, reload, handlers, logger)
+		}
+	}
+	// a second signal kills the process right away
+	stop()
+
+	return errors.Join(err, shutdown(cfg, e, handlers, workers, logger))
 }
 
-func runServer(h *handler.Handler, port int, logger *zap.Logger) {
+// newWorkers lists the background workers in the order they are stopped,
+// the ones raising events go before the ones delivering them.
+func newWorkers(cfg *config.Config, services *service.Service, logger *zap.Logger) []worker.Worker {
+	return []worker.Worker{
+		worker.NewEventListener(services.Stream, logger),
+		worker.NewPriceScheduler(services.Price, cfg.WorkerCfg.PriceSchedulerInterval, logger),
+		worker.NewIdempotencyCleaner(services.Idempotency, cfg.WorkerCfg.IdempotencyCleanupInterval, logger),
+		worker.NewTrashPurger(services.Trash, cfg.WorkerCfg.TrashPurgeInterval, logger),
+		worker.NewOutboxDispatcher(services.Outbox, cfg.WorkerCfg.OutboxDispatchInterval, logger),
+		worker.NewWebhookDeliverer(services.Webhook, cfg.WorkerCfg.WebhookDeliveryInterval, logger),
+		worker.NewOutboxPurger(services.Outbox, cfg.WorkerCfg.OutboxPurgeInterval, logger),
+		worker.NewStatsCollector(services.Stats, cfg.WorkerCfg.StatsCollectInterval, logger),
+	}
+}
+
+func newServer(h *handler.Handler) *echo.Echo {
 	e := echo.New()
+	e.HideBanner = true
 
 	h.RegisterRoutes(e)
 
-	addr := fmt.Sprintf(":%d", port)
-	logger.Info("Starting server...", zap.String("address", addr))
+	// event streams never finish on their own, they are closed as soon as the shutdown starts
+	e.Server.RegisterOnShutdown(h.CloseStreams)
+
+	return e
+}
 
-	if err := e.Start(addr); err != nil {
-		logger.Fatal("Failed to start server", zap.Error(err))
+// shutdown stops taking traffic, waits for in-flight requests and then stops the workers,
+// all within the drain timeout.
+func shutdown(cfg *config.Config, e *echo.Echo, h *handler.Handler, workers *worker.Group, logger *zap.Logger) error {
+	logger.Info("Shutting down...", zap.Duration("timeout", cfg.ShutdownCfg.Timeout))
+
+	// load balancers stop routing here once they see the replica as not ready
+	h.SetReady(false)
+	time.Sleep(cfg.ShutdownCfg.Delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownCfg.Timeout)
+	defer cancel()
+
+	var errs []error
+	if err := e.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, fmt.Errorf("failed to shut down server: %w", err))
 	}
+	logger.Info("Server stopped")
+
+	if err := workers.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to stop workers: %w", err))
+	}
+	logger.Info("Workers stopped")
+
+	return errors.Join(errs...)
 }
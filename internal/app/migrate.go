@@ -0,0 +1,100 @@
+package app
+
+import (
+	"BookStore_API/internal/config"
+	"BookStore_API/internal/postgres"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"io"
+	"strconv"
+)
+
+var ErrMigrateUsage = errors.New("usage: migrate up | down [N] | status | goto VERSION | force VERSION")
+
+// Migrate runs the migrate command: up applies every pending migration, down reverts the
+// last N, one by default, goto migrates to a version, force marks a version as applied to
+// recover from a failed migration, and status prints the schema version to out.
+func Migrate(cfg *config.Config, db *pgxpool.Pool, args []string, out io.Writer, logger *zap.Logger) (err error) {
+	if len(args) == 0 {
+		return ErrMigrateUsage
+	}
+
+	migrator, err := postgres.NewMigrator(db, cfg.MigrateCfg.LockTimeout, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, migrator.Close())
+	}()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = migrator.Up()
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		err = migrator.Down(steps)
+	case args[0] == "goto" && len(args) == 2:
+		version, parseErr := strconv.ParseUint(args[1], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = migrator.Goto(uint(version))
+	case args[0] == "force" && len(args) == 2:
+		version, parseErr := strconv.ParseUint(args[1], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = migrator.Force(uint(version))
+	case args[0] == "status" && len(args) == 1:
+	default:
+		return ErrMigrateUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	return printMigrationStatus(out, status)
+}
+
+// PrepareSchema applies pending migrations if MIGRATE_ON_START is set and then makes sure the
+// schema is not behind this build.
+func PrepareSchema(cfg *config.Config, db *pgxpool.Pool, logger *zap.Logger) (err error) {
+	migrator, err := postgres.NewMigrator(db, cfg.MigrateCfg.LockTimeout, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, migrator.Close())
+	}()
+
+	if cfg.MigrateCfg.OnStart {
+		logger.Info("Applying migrations...")
+		if err = migrator.Up(); err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+
+	return migrator.Check()
+}
+
+func printMigrationStatus(out io.Writer, status postgres.MigrationStatus) error {
+	pending := "none"
+	if len(status.Pending) > 0 {
+		pending = fmt.Sprint(status.Pending)
+	}
+
+	_, err := fmt.Fprintf(out, "version: %d\ndirty:   %t\nlatest:  %d\npending: %s\n",
+		status.Version, status.Dirty, status.Latest, pending)
+	return err
+}
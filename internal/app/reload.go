@@ -0,0 +1,35 @@
+package app
+
+import (
+	"BookStore_API/internal/config"
+	"BookStore_API/internal/handler"
+	"BookStore_API/internal/zaplog"
+	"go.uber.org/zap"
+)
+
+// reloadConfig reads the config again and applies the settings tagged env-upd: the log levels
+// and REQUIRE_IF_MATCH. Other changed settings are logged and wait for a restart; an invalid
+// config is rejected as a whole.
+func reloadConfig(current *config.Config, reload func() (*config.Config, error), h *handler.Handler, logger *zap.Logger) *config.Config {
+	logger.Info("Reloading config...")
+
+	next, err := reload()
+	if err != nil {
+		logger.Error("Failed to reload config, keeping the current one", zap.Error(err))
+		return current
+	}
+
+	cfg, applied, restart := config.Reload(current, next)
+
+	if err = zaplog.SetLevels(cfg.LogCfg.Level, cfg.LogCfg.Levels); err != nil {
+		// validated by reload, kept for safety
+		logger.Error("Failed to apply log levels", zap.Error(err))
+	}
+	h.SetRequireIfMatch(cfg.RequireIfMatch)
+
+	if len(restart) > 0 {
+		logger.Warn("Some changed settings need a restart", zap.Strings("settings", restart))
+	}
+	logger.Info("Config reloaded", zap.Strings("applied", applied))
+	return cfg
+}
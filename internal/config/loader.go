@@ -0,0 +1,261 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Options are the command line options of the API. Every setting can be given as a flag
+// named after its variable, as --db-host for DB_HOST.
+type Options struct {
+	// File is a YAML or TOML file with settings, from --config or CONFIG_FILE.
+	File string
+	// PrintConfig prints the effective config with secrets masked instead of running.
+	PrintConfig bool
+	// Flags are the settings given as flags, by variable name.
+	Flags map[string]string
+	// Args are the command and its arguments, as migrate up, empty to serve.
+	Args []string
+}
+
+// ParseOptions parses the command line arguments, flag.ErrHelp is returned for -h. Flags
+// may come before, within or after the command.
+func ParseOptions(args []string) (Options, error) {
+	opts := Options{
+		File:  os.Getenv("CONFIG_FILE"),
+		Flags: make(map[string]string),
+	}
+
+	fs := flag.NewFlagSet("bookstore-api", flag.ContinueOnError)
+	fs.StringVar(&opts.File, "config", opts.File, "YAML or TOML file with settings")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective config with secrets masked and exit")
+
+	for _, s := range settings(&Config{}) {
+		set := func(v string) error {
+			opts.Flags[s.name] = v
+			return nil
+		}
+		// booleans may be given without a value, as --migrate-on-start
+		if s.field.Type.Kind() == reflect.Bool {
+			fs.BoolFunc(flagName(s.name), "sets "+s.name, set)
+		} else {
+			fs.Func(flagName(s.name), "sets "+s.name, set)
+		}
+	}
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return Options{}, err
+		}
+		if fs.NArg() == 0 {
+			return opts, nil
+		}
+		opts.Args = append(opts.Args, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// Load reads the config in layers, each overriding the previous one: the defaults, the
+// config file, the environment, with .env.local in development, and the flags. Invalid
+// settings are reported together; the config is returned with them for printing.
+func Load(appEnv string, opts Options, logger *zap.Logger) (*Config, error) {
+	if appEnv == "development" {
+		loadEnvVars(logger)
+	}
+
+	fileValues, err := readFile(opts.File)
+	if err != nil {
+		return nil, err
+	}
+
+	applyLayers(fileValues, opts.Flags)
+
+	var cfg Config
+	if err = cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return &cfg, err
+	}
+	return &cfg, nil
+}
+
+var (
+	envOnce sync.Once
+	// env holds the variables of the process before the file and flags were applied, nil if
+	// unset, so a reload starts again from the real environment
+	env map[string]*string
+)
+
+// applyLayers sets the variables cleanenv reads: those of the file unless set in the
+// environment, and then those of the flags.
+func applyLayers(fileValues, flagValues map[string]string) {
+	envOnce.Do(func() {
+		env = make(map[string]*string)
+		for _, s := range settings(&Config{}) {
+			if v, ok := os.LookupEnv(s.name); ok {
+				env[s.name] = &v
+			} else {
+				env[s.name] = nil
+			}
+		}
+	})
+
+	for name, v := range env {
+		if v != nil {
+			_ = os.Setenv(name, *v)
+		} else {
+			_ = os.Unsetenv(name)
+		}
+	}
+	for name, v := range fileValues {
+		if env[name] == nil {
+			_ = os.Setenv(name, v)
+		}
+	}
+	for name, v := range flagValues {
+		_ = os.Setenv(name, v)
+	}
+}
+
+// readFile reads the settings of a YAML or TOML file. Keys are the names of the variables,
+// given flat as DB_HOST or nested as db: {host: ...}, case does not matter.
+func readFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file %q, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	known := make(map[string]setting)
+	for _, s := range settings(&Config{}) {
+		known[s.name] = s
+	}
+
+	values := make(map[string]string)
+	var errs []error
+	flatten("", raw, known, values, &errs)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config file %s:\n%w", path, errors.Join(errs...))
+	}
+	return values, nil
+}
+
+func flatten(prefix string, raw map[string]any, known map[string]setting, values map[string]string, errs *[]error) {
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		s, ok := known[name]
+		nested, isMap := raw[key].(map[string]any)
+		switch {
+		case ok:
+			values[name] = fileValue(raw[key], s.separator())
+		case isMap:
+			flatten(name, nested, known, values, errs)
+		default:
+			*errs = append(*errs, fmt.Errorf("unknown setting %s", name))
+		}
+	}
+}
+
+// fileValue formats a file value as the variable would be written, lists and maps are
+// joined with the separator of the setting.
+func fileValue(v any, sep string) string {
+	switch v := v.(type) {
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, sep)
+	case map[string]any:
+		items := make([]string, 0, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			items = append(items, key+":"+fmt.Sprint(v[key]))
+		}
+		return strings.Join(items, sep)
+	}
+	return fmt.Sprint(v)
+}
+
+// setting is a config field set by one variable.
+type setting struct {
+	name string
+	// path is the field as named by the validator, as Config.DBCfg.Host
+	path  string
+	field reflect.StructField
+	value reflect.Value
+}
+
+func (s setting) separator() string {
+	if sep := s.field.Tag.Get("env-separator"); sep != "" {
+		return sep
+	}
+	return ","
+}
+
+func (s setting) updatable() bool {
+	_, ok := s.field.Tag.Lookup("env-upd")
+	return ok
+}
+
+func (s setting) secret() bool {
+	return s.field.Tag.Get("log") == "redact"
+}
+
+// settings lists the fields of cfg with a variable, in declaration order.
+func settings(cfg *Config) []setting {
+	var out []setting
+	var walk func(v reflect.Value, path string)
+	walk = func(v reflect.Value, path string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if name := field.Tag.Get("env"); name != "" {
+				out = append(out, setting{name: name, path: path + "." + field.Name, field: field, value: v.Field(i)})
+				continue
+			}
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), path+"."+field.Name)
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "Config")
+	return out
+}
+
+func flagName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"BookStore_API/internal/zaplog"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Print writes every setting as NAME=value, with secrets masked.
+func Print(w io.Writer, cfg *Config) error {
+	for _, s := range settings(cfg) {
+		value := formatValue(s.value, s.separator())
+		if s.secret() && value != "" {
+			value = zaplog.Redacted
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", s.name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reload returns a copy of current with the updatable settings, tagged env-upd, taken from
+// next. It lists the changed settings that were applied and those that need a restart.
+func Reload(current, next *Config) (cfg *Config, applied, restart []string) {
+	reloaded := *current
+	nextSettings := settings(next)
+
+	for i, s := range settings(&reloaded) {
+		if reflect.DeepEqual(s.value.Interface(), nextSettings[i].value.Interface()) {
+			continue
+		}
+		if !s.updatable() {
+			restart = append(restart, s.name)
+			continue
+		}
+		s.value.Set(nextSettings[i].value)
+		applied = append(applied, s.name)
+	}
+	return &reloaded, applied, restart
+}
+
+func formatValue(v reflect.Value, sep string) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i), sep)
+		}
+		return strings.Join(items, sep)
+	case reflect.Map:
+		items := make(map[string]string, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items[fmt.Sprint(iter.Key().Interface())] = formatValue(iter.Value(), sep)
+		}
+		out := make([]string, 0, len(items))
+		for _, key := range slices.Sorted(maps.Keys(items)) {
+			out = append(out, key+":"+items[key])
+		}
+		return strings.Join(out, sep)
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"BookStore_API/internal/zaplog"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// errors name the variable rather than the field
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("env")
+	})
+	return v
+}
+
+// Validate checks every setting and reports all invalid ones together.
+func (c *Config) Validate() error {
+	var errs []error
+
+	var validationErrs validator.ValidationErrors
+	if err := validate.Struct(c); errors.As(err, &validationErrs) {
+		paths := make(map[string]string)
+		for _, s := range settings(c) {
+			paths[s.path] = s.name
+		}
+		for _, e := range validationErrs {
+			errs = append(errs, validationError(e, paths))
+		}
+	} else if err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := zaplog.CheckLevels(c.LogCfg.Level, ""); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL is invalid: %w", err))
+	}
+	if err := zaplog.CheckLevels("", c.LogCfg.Levels); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVELS is invalid: %w", err))
+	}
+	if slices.Contains(c.OutboxCfg.Sinks, "http") && c.OutboxCfg.HTTPURL == "" {
+		errs = append(errs, errors.New("OUTBOX_HTTP_URL is required with the http sink"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func validationError(e validator.FieldError, paths map[string]string) error {
+	name := e.Field()
+
+	// params naming another field are shown as its variable
+	param := e.Param()
+	if sibling, ok := paths[e.StructNamespace()[:strings.LastIndex(e.StructNamespace(), ".")+1]+param]; ok {
+		param = sibling
+	}
+
+	switch e.Tag() {
+	case "required":
+		return fmt.Errorf("%s is required", name)
+	case "required_without":
+		return fmt.Errorf("%s is required unless %s is set", name, param)
+	case "oneof":
+		return fmt.Errorf("%s must be one of %s, got %q", name, strings.ReplaceAll(param, " ", ", "), fmt.Sprint(e.Value()))
+	case "gt":
+		return fmt.Errorf("%s must be greater than %s", name, param)
+	case "gte":
+		return fmt.Errorf("%s must be at least %s", name, param)
+	case "lte":
+		return fmt.Errorf("%s must be at most %s", name, param)
+	case "ltefield":
+		return fmt.Errorf("%s must not be greater than %s", name, param)
+	case "len":
+		return fmt.Errorf("%s must have %s characters", name, param)
+	case "url":
+		return fmt.Errorf("%s must be a URL", name)
+	}
+	return fmt.Errorf("%s is invalid (%s)", name, e.Tag())
+}
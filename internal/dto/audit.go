@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"encoding/json"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+type AuditQueryRequest struct {
+	Entity string `query:"entity" validate:"omitempty,oneof=book magazine order"`
+	Id     int    `query:"id" validate:"gte=0"`
+	Actor  string `query:"actor"`
+	Action string `query:"action" validate:"omitempty,oneof=create update delete"`
+	Limit  int    `query:"limit" validate:"gte=0,lte=200"`
+	Offset int    `query:"offset" validate:"gte=0"`
+}
+
+type AuditEntryResponse struct {
+	Id         int             `json:"id"`
+	Actor      string          `json:"actor"`
+	RequestId  string          `json:"requestId"`
+	EntityType string          `json:"entityType"`
+	EntityId   int             `json:"entityId"`
+	Action     string          `json:"action"`
+	Changes    json.RawMessage `json:"changes"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+func (r *AuditQueryRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+// ToFilter builds the filter of the query; from and to are parsed by the caller.
+func (r *AuditQueryRequest) ToFilter(from, to *time.Time) entity.AuditFilter {
+	limit := r.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+	return entity.AuditFilter{
+		EntityType: r.Entity,
+		EntityId:   r.Id,
+		Actor:      r.Actor,
+		Action:     r.Action,
+		From:       from,
+		To:         to,
+		Limit:      min(limit, maxAuditLimit),
+		Offset:     r.Offset,
+	}
+}
+
+func FromEntityAuditEntry(e entity.AuditEntry) AuditEntryResponse {
+	return AuditEntryResponse{
+		Id:         e.Id,
+		Actor:      e.Actor,
+		RequestId:  e.RequestId,
+		EntityType: e.EntityType,
+		EntityId:   e.EntityId,
+		Action:     e.Action,
+		Changes:    e.Changes,
+		CreatedAt:  e.CreatedAt,
+	}
+}
@@ -9,48 +9,78 @@ import (
 var validate = validator.New()
 
 type BookCreateRequest struct {
-	Name   string  `json:"name" validate:"required"`
-	Price  float64 `json:"price"`
-	Stock  int     `json:"stock"`
-	Author string  `json:"author" validate:"required"`
-	Isbn   string  `json:"isbn" validate:"required,len=14"`
+	Name        string  `json:"name" validate:"required"`
+	Price       float64 `json:"price"`
+	Stock       int     `json:"stock"`
+	TaxClass    string  `json:"taxClass"`
+	WeightGrams int     `json:"weightGrams" validate:"gte=0"`
+	Author      string  `json:"author" validate:"required"`
+	Isbn        string  `json:"isbn" validate:"required,len=14"`
 }
 
 type BookUpdateRequest struct {
-	Name   *string  `json:"name"`
-	Price  *float64 `json:"price"`
-	Stock  *int     `json:"stock"`
-	Author *string  `json:"author"`
-	Isbn   *string  `json:"isbn" validate:"len=14"`
+	Name        *string  `json:"name"`
+	Price       *float64 `json:"price"`
+	Stock       *int     `json:"stock"`
+	TaxClass    *string  `json:"taxClass"`
+	WeightGrams *int     `json:"weightGrams" validate:"omitempty,gte=0"`
+	Author      *string  `json:"author"`
+	Isbn        *string  `json:"isbn" validate:"len=14"`
 }
 
 type BookResponse struct {
-	Id        int       `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	Stock     int       `json:"stock"`
-	Author    string    `json:"author"`
-	Isbn      string    `json:"isbn"`
-	CreatedAt time.Time `json:"createdAt"`
+	Id          int       `json:"id"`
+	Name        string    `json:"name"`
+	Price       float64   `json:"price"`
+	Currency    string    `json:"currency"`
+	Stock       int       `json:"stock"`
+	TaxClass    string    `json:"taxClass"`
+	WeightGrams int       `json:"weightGrams"`
+	Author      string    `json:"author"`
+	Isbn        string    `json:"isbn"`
+	CreatedAt   time.Time `json:"createdAt"`
 }
 
 func (r *BookCreateRequest) Validate() error {
+	if err := validateTaxClass(&r.TaxClass); err != nil {
+		return err
+	}
 	return validate.Struct(r)
 }
 
 func (r *BookUpdateRequest) Validate() error {
+	if err := validateTaxClass(r.TaxClass); err != nil {
+		return err
+	}
 	return validate.Struct(r)
 }
 
 func FromEntityBook(b entity.Book) BookResponse {
 	return BookResponse{
-		Id:        b.Id,
-		Name:      b.Name,
-		Price:     b.Price,
-		Stock:     b.Stock,
-		Author:    b.Author,
-		Isbn:      b.Isbn,
-		CreatedAt: b.CreatedAt,
+		Id:          b.Id,
+		Name:        b.Name,
+		Price:       b.Price,
+		Currency:    b.Currency,
+		Stock:       b.Stock,
+		TaxClass:    b.TaxClass,
+		WeightGrams: b.WeightGrams,
+		Author:      b.Author,
+		Isbn:        b.Isbn,
+		CreatedAt:   b.CreatedAt,
+	}
+}
+
+// NewBookPatchDocument returns the book in the shape of a create request. PATCH requests are
+// applied to this document and the result is validated like a new book.
+func NewBookPatchDocument(b entity.Book) BookCreateRequest {
+	return BookCreateRequest{
+		Name:        b.Name,
+		Price:       b.Price,
+		Stock:       b.Stock,
+		TaxClass:    b.TaxClass,
+		WeightGrams: b.WeightGrams,
+		Author:      b.Author,
+		Isbn:        b.Isbn,
 	}
 }
 
@@ -58,9 +88,11 @@ func FromEntityBook(b entity.Book) BookResponse {
 func (r *BookCreateRequest) ToEntity() entity.Book {
 	return entity.Book{
 		BaseProduct: entity.BaseProduct{
-			Name:  r.Name,
-			Price: r.Price,
-			Stock: r.Stock,
+			Name:        r.Name,
+			Price:       r.Price,
+			Stock:       r.Stock,
+			TaxClass:    r.TaxClass,
+			WeightGrams: r.WeightGrams,
 		},
 		Author: r.Author,
 		Isbn:   r.Isbn,
@@ -78,6 +110,12 @@ func (r *BookUpdateRequest) ApplyToEntity(b *entity.Book) {
 	if r.Stock != nil {
 		b.Stock = *r.Stock
 	}
+	if r.TaxClass != nil {
+		b.TaxClass = *r.TaxClass
+	}
+	if r.WeightGrams != nil {
+		b.WeightGrams = *r.WeightGrams
+	}
 	if r.Author != nil {
 		b.Author = *r.Author
 	}
@@ -85,3 +123,14 @@ func (r *BookUpdateRequest) ApplyToEntity(b *entity.Book) {
 		b.Isbn = *r.Isbn
 	}
 }
+
+// ApplyToEntity method has pointer receiver in case future logic mutates the receiver.
+func (r *BookCreateRequest) ApplyToEntity(b *entity.Book) {
+	b.Name = r.Name
+	b.Price = r.Price
+	b.Stock = r.Stock
+	b.TaxClass = r.TaxClass
+	b.WeightGrams = r.WeightGrams
+	b.Author = r.Author
+	b.Isbn = r.Isbn
+}
@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"fmt"
+	"time"
+)
+
+var validCurrencies = map[string]struct{}{
+	entity.CurrencyEUR: {},
+	entity.CurrencyUSD: {},
+	entity.CurrencyUAH: {},
+}
+
+type ExchangeRateCreateRequest struct {
+	Currency      string     `json:"currency" validate:"required"`
+	Rate          float64    `json:"rate" validate:"gt=0"`
+	EffectiveFrom *time.Time `json:"effectiveFrom"`
+}
+
+type ExchangeRateResponse struct {
+	Id            int       `json:"id"`
+	Currency      string    `json:"currency"`
+	Rate          float64   `json:"rate"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type PriceOverrideRequest struct {
+	Price float64 `json:"price" validate:"gt=0"`
+}
+
+type PriceOverrideResponse struct {
+	Currency  string    `json:"currency"`
+	Price     float64   `json:"price"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsValidCurrency reports whether the currency is one of the supported ones.
+func IsValidCurrency(currency string) bool {
+	_, ok := validCurrencies[currency]
+	return ok
+}
+
+func (r *ExchangeRateCreateRequest) Validate() error {
+	if !IsValidCurrency(r.Currency) {
+		return fmt.Errorf("invalid currency: %s", r.Currency)
+	}
+	return validate.Struct(r)
+}
+
+func (r *PriceOverrideRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func FromEntityExchangeRate(rate entity.ExchangeRate) ExchangeRateResponse {
+	return ExchangeRateResponse{
+		Id:            rate.Id,
+		Currency:      rate.Currency,
+		Rate:          rate.Rate,
+		EffectiveFrom: rate.EffectiveFrom,
+		CreatedAt:     rate.CreatedAt,
+	}
+}
+
+func FromEntityPriceOverride(po entity.PriceOverride) PriceOverrideResponse {
+	return PriceOverrideResponse{
+		Currency:  po.Currency,
+		Price:     po.Price,
+		UpdatedAt: po.UpdatedAt,
+	}
+}
+
+// ToEntity method has pointer receiver in case future logic mutates the receiver.
+func (r *ExchangeRateCreateRequest) ToEntity() entity.ExchangeRate {
+	rate := entity.ExchangeRate{
+		Currency:      r.Currency,
+		Rate:          r.Rate,
+		EffectiveFrom: time.Now(),
+	}
+	if r.EffectiveFrom != nil {
+		rate.EffectiveFrom = *r.EffectiveFrom
+	}
+	return rate
+}
+
+func (r *PriceOverrideRequest) ToEntity(productId int, currency string) entity.PriceOverride {
+	return entity.PriceOverride{
+		ProductId: productId,
+		Currency:  currency,
+		Price:     r.Price,
+	}
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"time"
+)
+
+type HealthCheckResponse struct {
+	Name      string         `json:"name"`
+	Status    string         `json:"status"`
+	LatencyMs float64        `json:"latencyMs"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+type PoolStatsResponse struct {
+	MaxConns          int32   `json:"maxConns"`
+	TotalConns        int32   `json:"totalConns"`
+	IdleConns         int32   `json:"idleConns"`
+	AcquiredConns     int32   `json:"acquiredConns"`
+	AcquireCount      int64   `json:"acquireCount"`
+	EmptyAcquireCount int64   `json:"emptyAcquireCount"`
+	AcquireDurationMs float64 `json:"acquireDurationMs"`
+}
+
+type HealthResponse struct {
+	Status    string                `json:"status"`
+	Checks    []HealthCheckResponse `json:"checks"`
+	Pool      PoolStatsResponse     `json:"pool"`
+	CheckedAt time.Time             `json:"checkedAt"`
+}
+
+func FromEntityHealthReport(r entity.HealthReport) HealthResponse {
+	checks := make([]HealthCheckResponse, len(r.Checks))
+	for i, c := range r.Checks {
+		checks[i] = HealthCheckResponse{
+			Name:      c.Name,
+			Status:    c.Status,
+			LatencyMs: milliseconds(c.Latency),
+			Error:     c.Error,
+			Details:   c.Details,
+		}
+	}
+
+	return HealthResponse{
+		Status: r.Status,
+		Checks: checks,
+		Pool: PoolStatsResponse{
+			MaxConns:          r.Pool.MaxConns,
+			TotalConns:        r.Pool.TotalConns,
+			IdleConns:         r.Pool.IdleConns,
+			AcquiredConns:     r.Pool.AcquiredConns,
+			AcquireCount:      r.Pool.AcquireCount,
+			EmptyAcquireCount: r.Pool.EmptyAcquireCount,
+			AcquireDurationMs: milliseconds(r.Pool.AcquireDuration),
+		},
+		CheckedAt: r.CheckedAt,
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -9,6 +9,8 @@ type MagazineCreateRequest struct {
 	Name            string    `json:"name" validate:"required"`
 	Price           float64   `json:"price"`
 	Stock           int       `json:"stock"`
+	TaxClass        string    `json:"taxClass"`
+	WeightGrams     int       `json:"weightGrams" validate:"gte=0"`
 	IssueNumber     int       `json:"issueNumber" validate:"required"`
 	PublicationDate time.Time `json:"publicationDate" validate:"required"`
 }
@@ -17,6 +19,8 @@ type MagazineUpdateRequest struct {
 	Name            *string    `json:"name"`
 	Price           *float64   `json:"price"`
 	Stock           *int       `json:"stock"`
+	TaxClass        *string    `json:"taxClass"`
+	WeightGrams     *int       `json:"weightGrams" validate:"omitempty,gte=0"`
 	IssueNumber     *int       `json:"issueNumber"`
 	PublicationDate *time.Time `json:"publicationDate"`
 }
@@ -25,17 +29,26 @@ type MagazineResponse struct {
 	Id              int       `json:"id"`
 	Name            string    `json:"name"`
 	Price           float64   `json:"price"`
+	Currency        string    `json:"currency"`
 	Stock           int       `json:"stock"`
+	TaxClass        string    `json:"taxClass"`
+	WeightGrams     int       `json:"weightGrams"`
 	IssueNumber     int       `json:"issueNumber"`
 	PublicationDate time.Time `json:"publicationDate"`
 	CreatedAt       time.Time `json:"createdAt"`
 }
 
 func (r *MagazineCreateRequest) Validate() error {
+	if err := validateTaxClass(&r.TaxClass); err != nil {
+		return err
+	}
 	return validate.Struct(r)
 }
 
 func (r *MagazineUpdateRequest) Validate() error {
+	if err := validateTaxClass(r.TaxClass); err != nil {
+		return err
+	}
 	return validate.Struct(r)
 }
 
@@ -44,20 +57,39 @@ func FromEntityMagazine(m entity.Magazine) MagazineResponse {
 		Id:              m.Id,
 		Name:            m.Name,
 		Price:           m.Price,
+		Currency:        m.Currency,
 		Stock:           m.Stock,
+		TaxClass:        m.TaxClass,
+		WeightGrams:     m.WeightGrams,
 		IssueNumber:     m.IssueNumber,
 		PublicationDate: m.PublicationDate,
 		CreatedAt:       m.CreatedAt,
 	}
 }
 
+// NewMagazinePatchDocument returns the magazine in the shape of a create request. PATCH requests
+// are applied to this document and the result is validated like a new magazine.
+func NewMagazinePatchDocument(m entity.Magazine) MagazineCreateRequest {
+	return MagazineCreateRequest{
+		Name:            m.Name,
+		Price:           m.Price,
+		Stock:           m.Stock,
+		TaxClass:        m.TaxClass,
+		WeightGrams:     m.WeightGrams,
+		IssueNumber:     m.IssueNumber,
+		PublicationDate: m.PublicationDate,
+	}
+}
+
 // ToEntity method has pointer receiver in case future logic mutates the receiver.
 func (r *MagazineCreateRequest) ToEntity() entity.Magazine {
 	return entity.Magazine{
 		BaseProduct: entity.BaseProduct{
-			Name:  r.Name,
-			Price: r.Price,
-			Stock: r.Stock,
+			Name:        r.Name,
+			Price:       r.Price,
+			Stock:       r.Stock,
+			TaxClass:    r.TaxClass,
+			WeightGrams: r.WeightGrams,
 		},
 		IssueNumber:     r.IssueNumber,
 		PublicationDate: r.PublicationDate,
@@ -75,6 +107,12 @@ func (r *MagazineUpdateRequest) ApplyToEntity(m *entity.Magazine) {
 	if r.Stock != nil {
 		m.Stock = *r.Stock
 	}
+	if r.TaxClass != nil {
+		m.TaxClass = *r.TaxClass
+	}
+	if r.WeightGrams != nil {
+		m.WeightGrams = *r.WeightGrams
+	}
 	if r.IssueNumber != nil {
 		m.IssueNumber = *r.IssueNumber
 	}
@@ -82,3 +120,14 @@ func (r *MagazineUpdateRequest) ApplyToEntity(m *entity.Magazine) {
 		m.PublicationDate = *r.PublicationDate
 	}
 }
+
+// ApplyToEntity method has pointer receiver in case future logic mutates the receiver.
+func (r *MagazineCreateRequest) ApplyToEntity(m *entity.Magazine) {
+	m.Name = r.Name
+	m.Price = r.Price
+	m.Stock = r.Stock
+	m.TaxClass = r.TaxClass
+	m.WeightGrams = r.WeightGrams
+	m.IssueNumber = r.IssueNumber
+	m.PublicationDate = r.PublicationDate
+}
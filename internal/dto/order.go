@@ -3,6 +3,7 @@ package dto
 import (
 	"BookStore_API/internal/entity"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -22,33 +23,72 @@ type OrderItemRequest struct {
 }
 
 type OrderItemResponse struct {
-	ProductId int     `json:"productId" validate:"required"`
-	Name      string  `json:"name"`
-	Price     float64 `json:"price"`
-	Quantity  int     `json:"quantity" validate:"required,min=1"`
+	ProductId   int                     `json:"productId" validate:"required"`
+	Name        string                  `json:"name"`
+	ProductType string                  `json:"productType"`
+	Isbn        string                  `json:"isbn,omitempty"`
+	IssueNumber int                     `json:"issueNumber,omitempty"`
+	Price       float64                 `json:"price"`
+	Quantity    int                     `json:"quantity" validate:"required,min=1"`
+	TaxClass    string                  `json:"taxClass"`
+	TaxRate     float64                 `json:"taxRate"`
+	TaxAmount   float64                 `json:"taxAmount"`
+	Current     *CurrentProductResponse `json:"current,omitempty"`
+}
+
+type CurrentProductResponse struct {
+	Exists bool    `json:"exists"`
+	Price  float64 `json:"price,omitempty"`
 }
 
 type OrderCreateRequest struct {
-	Items  []OrderItemRequest `json:"items" validate:"required,dive"`
-	Status string             `json:"status" validate:"required"`
+	Items          []OrderItemRequest `json:"items" validate:"required,dive"`
+	Status         string             `json:"status" validate:"required"`
+	Currency       string             `json:"currency"`
+	Destination    *AddressRequest    `json:"destination"`
+	ShippingMethod string             `json:"shippingMethod" validate:"omitempty,oneof=standard express pickup"`
 }
 
 type OrderUpdateRequest struct {
-	Items  *[]OrderItemRequest `json:"items"`
-	Status *string             `json:"status"`
+	Items          *[]OrderItemRequest `json:"items"`
+	Status         *string             `json:"status"`
+	TrackingNumber *string             `json:"trackingNumber"`
+}
+
+// OrderPatchDocument is the editable part of an order PATCH requests are applied to.
+// Items are order lines, so JSON Patch can add, replace or remove a single line.
+type OrderPatchDocument struct {
+	Status         string             `json:"status" validate:"required"`
+	TrackingNumber string             `json:"trackingNumber"`
+	Items          []OrderItemRequest `json:"items" validate:"required,min=1,unique=ProductId,dive"`
 }
 
 type OrderResponse struct {
-	Id        int                 `json:"id"`
-	Status    string              `json:"status"`
-	Items     []OrderItemResponse `json:"items"`
-	CreatedAt time.Time           `json:"createdAt"`
+	Id           int                   `json:"id"`
+	Status       string                `json:"status"`
+	Items        []OrderItemResponse   `json:"items"`
+	Currency     string                `json:"currency"`
+	ExchangeRate float64               `json:"exchangeRate"`
+	Destination  AddressResponse       `json:"destination"`
+	TaxMode      string                `json:"taxMode"`
+	Taxes        []TaxLineResponse     `json:"taxes"`
+	Shipping     OrderShippingResponse `json:"shipping"`
+	Subtotal     float64               `json:"subtotal"`
+	TaxTotal     float64               `json:"taxTotal"`
+	Total        float64               `json:"total"`
+	CreatedAt    time.Time             `json:"createdAt"`
 }
 
 func (r *OrderCreateRequest) Validate() error {
 	if _, ok := validOrderStatuses[r.Status]; !ok {
 		return fmt.Errorf("invalid order status: %s", r.Status)
 	}
+	if r.Currency != "" && !IsValidCurrency(r.Currency) {
+		return fmt.Errorf("invalid currency: %s", r.Currency)
+	}
+	if r.ShippingMethod != "" && r.Destination == nil {
+		return fmt.Errorf("destination is required when shipping method is set")
+	}
 	return validate.Struct(r)
 }
 
@@ -61,13 +101,33 @@ func (r *OrderUpdateRequest) Validate() error {
 	return validate.Struct(r)
 }
 
+func (r *OrderPatchDocument) Validate() error {
+	if _, ok := validOrderStatuses[r.Status]; !ok {
+		return fmt.Errorf("invalid order status: %s", r.Status)
+	}
+	return validate.Struct(r)
+}
+
 func FromEntityOrderItem(p entity.OrderItem) OrderItemResponse {
-	return OrderItemResponse{
-		ProductId: p.Product.Id,
-		Name:      p.Product.Name,
-		Price:     p.Product.Price,
-		Quantity:  p.Quantity,
+	resp := OrderItemResponse{
+		ProductId:   p.Product.Id,
+		Name:        p.Product.Name,
+		ProductType: p.ProductType,
+		Isbn:        p.Isbn,
+		IssueNumber: p.IssueNumber,
+		Price:       p.Product.Price,
+		Quantity:    p.Quantity,
+		TaxClass:    p.Product.TaxClass,
+		TaxRate:     p.TaxRate,
+		TaxAmount:   p.TaxAmount,
+	}
+	if p.Current != nil {
+		resp.Current = &CurrentProductResponse{
+			Exists: p.Current.Exists,
+			Price:  p.Current.Price,
+		}
 	}
+	return resp
 }
 
 func FromEntityOrder(o entity.Order) OrderResponse {
@@ -76,11 +136,27 @@ func FromEntityOrder(o entity.Order) OrderResponse {
 		items[i] = FromEntityOrderItem(item)
 	}
 
+	taxes := make([]TaxLineResponse, len(o.TaxLines))
+	for i, line := range o.TaxLines {
+		taxes[i] = FromEntityTaxLine(line)
+	}
+
+	subtotal, taxTotal, total := o.Totals()
+
 	return OrderResponse{
-		Id:        o.Id,
-		Items:     items,
-		Status:    o.Status,
-		CreatedAt: o.CreatedAt,
+		Id:           o.Id,
+		Items:        items,
+		Status:       o.Status,
+		Currency:     o.Currency,
+		ExchangeRate: o.ExchangeRate,
+		Destination:  FromEntityAddress(o.Destination),
+		TaxMode:      o.TaxMode,
+		Taxes:        taxes,
+		Shipping:     FromEntityOrderShipping(o.Shipping),
+		Subtotal:     roundAmount(subtotal),
+		TaxTotal:     roundAmount(taxTotal),
+		Total:        roundAmount(total),
+		CreatedAt:    o.CreatedAt,
 	}
 }
 
@@ -98,10 +174,16 @@ func (r *OrderCreateRequest) ToEntity() entity.Order {
 	for i, item := range r.Items {
 		items[i] = item.ToEntity()
 	}
-	return entity.Order{
-		Items:  items,
-		Status: r.Status,
+	order := entity.Order{
+		Items:    items,
+		Status:   r.Status,
+		Currency: r.Currency,
 	}
+	if r.Destination != nil {
+		order.Destination = r.Destination.ToEntity()
+	}
+	order.Shipping.Method = r.ShippingMethod
+	return order
 }
 
 func (r *OrderUpdateRequest) ApplyToEntity(o *entity.Order) {
@@ -115,4 +197,36 @@ func (r *OrderUpdateRequest) ApplyToEntity(o *entity.Order) {
 	if r.Status != nil {
 		o.Status = *r.Status
 	}
+	if r.TrackingNumber != nil {
+		o.Shipping.TrackingNumber = *r.TrackingNumber
+	}
+}
+
+func NewOrderPatchDocument(o entity.Order) OrderPatchDocument {
+	items := make([]OrderItemRequest, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = OrderItemRequest{
+			ProductId: item.Product.Id,
+			Quantity:  item.Quantity,
+		}
+	}
+	return OrderPatchDocument{
+		Status:         o.Status,
+		TrackingNumber: o.Shipping.TrackingNumber,
+		Items:          items,
+	}
+}
+
+func (r *OrderPatchDocument) ApplyToEntity(o *entity.Order) {
+	items := make([]entity.OrderItem, len(r.Items))
+	for i, item := range r.Items {
+		items[i] = item.ToEntity()
+	}
+	o.Items = items
+	o.Status = r.Status
+	o.Shipping.TrackingNumber = r.TrackingNumber
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
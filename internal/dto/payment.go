@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"time"
+)
+
+type PaymentCreateRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type PaymentRefundRequest struct {
+	// Amount of zero refunds the whole remaining amount.
+	Amount float64 `json:"amount" validate:"gte=0"`
+}
+
+type PaymentResponse struct {
+	Id             int       `json:"id"`
+	OrderId        int       `json:"orderId"`
+	Provider       string    `json:"provider"`
+	ProviderRef    string    `json:"providerRef"`
+	Status         string    `json:"status"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	RefundedAmount float64   `json:"refundedAmount"`
+	FailureReason  string    `json:"failureReason,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func (r *PaymentCreateRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *PaymentRefundRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func FromEntityPayment(p entity.Payment) PaymentResponse {
+	return PaymentResponse{
+		Id:             p.Id,
+		OrderId:        p.OrderId,
+		Provider:       p.Provider,
+		ProviderRef:    p.ProviderRef,
+		Status:         p.Status,
+		Amount:         p.Amount,
+		Currency:       p.Currency,
+		RefundedAmount: p.RefundedAmount,
+		FailureReason:  p.FailureReason,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
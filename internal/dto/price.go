@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"fmt"
+	"time"
+)
+
+type ScheduledPriceCreateRequest struct {
+	Price    float64    `json:"price" validate:"gt=0"`
+	StartsAt time.Time  `json:"startsAt" validate:"required"`
+	EndsAt   *time.Time `json:"endsAt"`
+}
+
+type PriceChangeResponse struct {
+	Price     float64   `json:"price"`
+	Source    string    `json:"source"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+type ScheduledPriceResponse struct {
+	Id            int        `json:"id"`
+	Price         float64    `json:"price"`
+	PreviousPrice *float64   `json:"previousPrice,omitempty"`
+	StartsAt      time.Time  `json:"startsAt"`
+	EndsAt        *time.Time `json:"endsAt,omitempty"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type PriceTimelineResponse struct {
+	ProductId int                      `json:"productId"`
+	History   []PriceChangeResponse    `json:"history"`
+	Scheduled []ScheduledPriceResponse `json:"scheduled"`
+	PriceAt   *float64                 `json:"priceAt,omitempty"`
+}
+
+func (r *ScheduledPriceCreateRequest) Validate() error {
+	if r.EndsAt != nil && !r.EndsAt.After(r.StartsAt) {
+		return fmt.Errorf("endsAt must be after startsAt")
+	}
+	return validate.Struct(r)
+}
+
+func (r *ScheduledPriceCreateRequest) ToEntity(productId int) entity.ScheduledPrice {
+	return entity.ScheduledPrice{
+		ProductId: productId,
+		Price:     r.Price,
+		StartsAt:  r.StartsAt,
+		EndsAt:    r.EndsAt,
+	}
+}
+
+func FromEntityPriceChange(pc entity.PriceChange) PriceChangeResponse {
+	return PriceChangeResponse{
+		Price:     pc.Price,
+		Source:    pc.Source,
+		ChangedAt: pc.ChangedAt,
+	}
+}
+
+func FromEntityScheduledPrice(sp entity.ScheduledPrice) ScheduledPriceResponse {
+	return ScheduledPriceResponse{
+		Id:            sp.Id,
+		Price:         sp.Price,
+		PreviousPrice: sp.PreviousPrice,
+		StartsAt:      sp.StartsAt,
+		EndsAt:        sp.EndsAt,
+		Status:        sp.Status,
+		CreatedAt:     sp.CreatedAt,
+	}
+}
+
+func FromEntityPriceTimeline(productId int, history []entity.PriceChange, scheduled []entity.ScheduledPrice) PriceTimelineResponse {
+	resp := PriceTimelineResponse{
+		ProductId: productId,
+		History:   make([]PriceChangeResponse, len(history)),
+		Scheduled: make([]ScheduledPriceResponse, len(scheduled)),
+	}
+	for i, pc := range history {
+		resp.History[i] = FromEntityPriceChange(pc)
+	}
+	for i, sp := range scheduled {
+		resp.Scheduled[i] = FromEntityScheduledPrice(sp)
+	}
+	return resp
+}
@@ -0,0 +1,130 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"time"
+)
+
+type ReturnItemRequest struct {
+	ProductId int `json:"productId" validate:"required"`
+	Quantity  int `json:"quantity" validate:"required,min=1"`
+}
+
+type ReturnCreateRequest struct {
+	Reason string              `json:"reason" validate:"required"`
+	Items  []ReturnItemRequest `json:"items" validate:"required,min=1,dive"`
+}
+
+type ReturnNoteRequest struct {
+	Note string `json:"note"`
+}
+
+type ReturnReceiveRequest struct {
+	Note string `json:"note"`
+	// Damaged lists the returned quantities that can not be sold again.
+	Damaged []ReturnItemRequest `json:"damaged" validate:"dive"`
+}
+
+type ReturnRefundRequest struct {
+	// Amount of zero refunds the whole return amount.
+	Amount float64 `json:"amount" validate:"gte=0"`
+}
+
+type ReturnItemResponse struct {
+	ProductId int     `json:"productId"`
+	Quantity  int     `json:"quantity"`
+	Damaged   int     `json:"damaged"`
+	Price     float64 `json:"price"`
+	TaxAmount float64 `json:"taxAmount"`
+}
+
+type ReturnResponse struct {
+	Id             int                  `json:"id"`
+	OrderId        int                  `json:"orderId"`
+	Status         string               `json:"status"`
+	Reason         string               `json:"reason"`
+	Note           string               `json:"note,omitempty"`
+	Items          []ReturnItemResponse `json:"items"`
+	RefundAmount   float64              `json:"refundAmount"`
+	RefundedAmount float64              `json:"refundedAmount"`
+	PaymentId      *int                 `json:"paymentId,omitempty"`
+	CreatedAt      time.Time            `json:"createdAt"`
+	UpdatedAt      time.Time            `json:"updatedAt"`
+}
+
+type OrderHistoryResponse struct {
+	Event     string    `json:"event"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (r *ReturnCreateRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *ReturnReceiveRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *ReturnRefundRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+// ToEntity method has pointer receiver in case future logic mutates the receiver.
+func (r *ReturnCreateRequest) ToEntity(orderId int) entity.OrderReturn {
+	items := make([]entity.ReturnItem, len(r.Items))
+	for i, item := range r.Items {
+		items[i] = entity.ReturnItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		}
+	}
+	return entity.OrderReturn{
+		OrderId: orderId,
+		Reason:  r.Reason,
+		Items:   items,
+	}
+}
+
+// DamagedByProduct returns the damaged quantities keyed by product id.
+func (r *ReturnReceiveRequest) DamagedByProduct() map[int]int {
+	damaged := make(map[int]int, len(r.Damaged))
+	for _, item := range r.Damaged {
+		damaged[item.ProductId] += item.Quantity
+	}
+	return damaged
+}
+
+func FromEntityReturn(ret entity.OrderReturn) ReturnResponse {
+	items := make([]ReturnItemResponse, len(ret.Items))
+	for i, item := range ret.Items {
+		items[i] = ReturnItemResponse{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+			Damaged:   item.Damaged,
+			Price:     item.Price,
+			TaxAmount: item.TaxAmount,
+		}
+	}
+	return ReturnResponse{
+		Id:             ret.Id,
+		OrderId:        ret.OrderId,
+		Status:         ret.Status,
+		Reason:         ret.Reason,
+		Note:           ret.Note,
+		Items:          items,
+		RefundAmount:   ret.RefundAmount,
+		RefundedAmount: ret.RefundedAmount,
+		PaymentId:      ret.PaymentId,
+		CreatedAt:      ret.CreatedAt,
+		UpdatedAt:      ret.UpdatedAt,
+	}
+}
+
+func FromEntityOrderHistory(entry entity.OrderHistoryEntry) OrderHistoryResponse {
+	return OrderHistoryResponse{
+		Event:     entry.Event,
+		Details:   entry.Details,
+		CreatedAt: entry.CreatedAt,
+	}
+}
@@ -0,0 +1,181 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ShippingZoneCreateRequest struct {
+	Name      string   `json:"name" validate:"required"`
+	Countries []string `json:"countries" validate:"required,min=1,dive,required"`
+}
+
+type ShippingRateRequest struct {
+	MinValue float64 `json:"minValue" validate:"gte=0"`
+	Price    float64 `json:"price" validate:"gte=0"`
+}
+
+type ShippingMethodCreateRequest struct {
+	Code          string                `json:"code" validate:"required,oneof=standard express pickup"`
+	Name          string                `json:"name" validate:"required"`
+	Basis         string                `json:"basis" validate:"required,oneof=weight item_count order_value"`
+	FreeThreshold *float64              `json:"freeThreshold" validate:"omitempty,gte=0"`
+	Rates         []ShippingRateRequest `json:"rates" validate:"dive"`
+}
+
+type ShippingQuoteRequest struct {
+	OrderId     *int               `json:"orderId"`
+	Items       []OrderItemRequest `json:"items" validate:"dive"`
+	Destination AddressRequest     `json:"destination"`
+	Currency    string             `json:"currency"`
+}
+
+type ShippingRateResponse struct {
+	MinValue float64 `json:"minValue"`
+	Price    float64 `json:"price"`
+}
+
+type ShippingMethodResponse struct {
+	Id            int                    `json:"id"`
+	Code          string                 `json:"code"`
+	Name          string                 `json:"name"`
+	Basis         string                 `json:"basis"`
+	FreeThreshold *float64               `json:"freeThreshold,omitempty"`
+	Rates         []ShippingRateResponse `json:"rates"`
+}
+
+type ShippingZoneResponse struct {
+	Id        int                      `json:"id"`
+	Name      string                   `json:"name"`
+	Countries []string                 `json:"countries"`
+	Methods   []ShippingMethodResponse `json:"methods"`
+	CreatedAt time.Time                `json:"createdAt"`
+}
+
+type ShippingQuoteResponse struct {
+	MethodId int     `json:"methodId"`
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Cost     float64 `json:"cost"`
+	Currency string  `json:"currency"`
+	Free     bool    `json:"free"`
+}
+
+type OrderShippingResponse struct {
+	Method         string  `json:"method,omitempty"`
+	Cost           float64 `json:"cost"`
+	TrackingNumber string  `json:"trackingNumber,omitempty"`
+}
+
+func (r *ShippingZoneCreateRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *ShippingMethodCreateRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *ShippingQuoteRequest) Validate() error {
+	if r.OrderId == nil && len(r.Items) == 0 {
+		return fmt.Errorf("either orderId or items must be provided")
+	}
+	if r.Currency != "" && !IsValidCurrency(r.Currency) {
+		return fmt.Errorf("invalid currency: %s", r.Currency)
+	}
+	return validate.Struct(r)
+}
+
+func FromEntityShippingZone(zone entity.ShippingZone) ShippingZoneResponse {
+	methods := make([]ShippingMethodResponse, len(zone.Methods))
+	for i, m := range zone.Methods {
+		methods[i] = FromEntityShippingMethod(m)
+	}
+
+	return ShippingZoneResponse{
+		Id:        zone.Id,
+		Name:      zone.Name,
+		Countries: zone.Countries,
+		Methods:   methods,
+		CreatedAt: zone.CreatedAt,
+	}
+}
+
+func FromEntityShippingMethod(m entity.ShippingMethod) ShippingMethodResponse {
+	rates := make([]ShippingRateResponse, len(m.Rates))
+	for i, rate := range m.Rates {
+		rates[i] = ShippingRateResponse{
+			MinValue: rate.MinValue,
+			Price:    rate.Price,
+		}
+	}
+
+	return ShippingMethodResponse{
+		Id:            m.Id,
+		Code:          m.Code,
+		Name:          m.Name,
+		Basis:         m.Basis,
+		FreeThreshold: m.FreeThreshold,
+		Rates:         rates,
+	}
+}
+
+func FromEntityShippingQuote(q entity.ShippingQuote) ShippingQuoteResponse {
+	return ShippingQuoteResponse{
+		MethodId: q.MethodId,
+		Code:     q.Code,
+		Name:     q.Name,
+		Cost:     q.Cost,
+		Currency: q.Currency,
+		Free:     q.Free,
+	}
+}
+
+func FromEntityOrderShipping(s entity.OrderShipping) OrderShippingResponse {
+	return OrderShippingResponse{
+		Method:         s.Method,
+		Cost:           s.Cost,
+		TrackingNumber: s.TrackingNumber,
+	}
+}
+
+// ToEntity method has pointer receiver in case future logic mutates the receiver.
+func (r *ShippingZoneCreateRequest) ToEntity() entity.ShippingZone {
+	countries := make([]string, len(r.Countries))
+	for i, c := range r.Countries {
+		countries[i] = strings.ToUpper(c)
+	}
+
+	return entity.ShippingZone{
+		Name:      r.Name,
+		Countries: countries,
+	}
+}
+
+func (r *ShippingMethodCreateRequest) ToEntity(zoneId int) entity.ShippingMethod {
+	rates := make([]entity.ShippingRate, len(r.Rates))
+	for i, rate := range r.Rates {
+		rates[i] = entity.ShippingRate{
+			MinValue: rate.MinValue,
+			Price:    rate.Price,
+		}
+	}
+
+	return entity.ShippingMethod{
+		ZoneId:        zoneId,
+		Code:          r.Code,
+		Name:          r.Name,
+		Basis:         r.Basis,
+		FreeThreshold: r.FreeThreshold,
+		Rates:         rates,
+	}
+}
+
+func (r *ShippingQuoteRequest) ItemsToEntity() []entity.OrderItem {
+	items := make([]entity.OrderItem, len(r.Items))
+	for i, item := range r.Items {
+		items[i] = item.ToEntity()
+	}
+	return items
+}
@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var validTaxClasses = map[string]struct{}{
+	entity.TaxClassStandard: {},
+	entity.TaxClassReduced:  {},
+	entity.TaxClassZero:     {},
+}
+
+type TaxRuleCreateRequest struct {
+	Country  string  `json:"country" validate:"required,len=2"`
+	Region   string  `json:"region"`
+	TaxClass string  `json:"taxClass" validate:"required"`
+	Rate     float64 `json:"rate" validate:"gte=0,lte=1"`
+}
+
+type TaxRuleResponse struct {
+	Id        int       `json:"id"`
+	Country   string    `json:"country"`
+	Region    string    `json:"region,omitempty"`
+	TaxClass  string    `json:"taxClass"`
+	Rate      float64   `json:"rate"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type TaxLineResponse struct {
+	TaxClass      string  `json:"taxClass"`
+	Rate          float64 `json:"rate"`
+	TaxableAmount float64 `json:"taxableAmount"`
+	TaxAmount     float64 `json:"taxAmount"`
+}
+
+type AddressRequest struct {
+	Country string `json:"country" validate:"required,len=2"`
+	Region  string `json:"region"`
+}
+
+type AddressResponse struct {
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+}
+
+func validateTaxClass(taxClass *string) error {
+	if taxClass == nil || *taxClass == "" {
+		return nil
+	}
+	if _, ok := validTaxClasses[*taxClass]; !ok {
+		return fmt.Errorf("invalid tax class: %s", *taxClass)
+	}
+	return nil
+}
+
+func (r *TaxRuleCreateRequest) Validate() error {
+	if err := validateTaxClass(&r.TaxClass); err != nil {
+		return err
+	}
+	return validate.Struct(r)
+}
+
+func FromEntityTaxRule(rule entity.TaxRule) TaxRuleResponse {
+	return TaxRuleResponse{
+		Id:        rule.Id,
+		Country:   rule.Country,
+		Region:    rule.Region,
+		TaxClass:  rule.TaxClass,
+		Rate:      rule.Rate,
+		CreatedAt: rule.CreatedAt,
+	}
+}
+
+func FromEntityTaxLine(line entity.TaxLine) TaxLineResponse {
+	return TaxLineResponse{
+		TaxClass:      line.TaxClass,
+		Rate:          line.Rate,
+		TaxableAmount: line.TaxableAmount,
+		TaxAmount:     line.TaxAmount,
+	}
+}
+
+func FromEntityAddress(a entity.Address) AddressResponse {
+	return AddressResponse{
+		Country: a.Country,
+		Region:  a.Region,
+	}
+}
+
+// ToEntity method has pointer receiver in case future logic mutates the receiver.
+func (r *TaxRuleCreateRequest) ToEntity() entity.TaxRule {
+	return entity.TaxRule{
+		Country:  strings.ToUpper(r.Country),
+		Region:   r.Region,
+		TaxClass: r.TaxClass,
+		Rate:     r.Rate,
+	}
+}
+
+func (r *AddressRequest) ToEntity() entity.Address {
+	return entity.Address{
+		Country: strings.ToUpper(r.Country),
+		Region:  r.Region,
+	}
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"time"
+)
+
+type TrashItemResponse struct {
+	Kind      string    `json:"kind"`
+	Id        int       `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
+
+func FromEntityTrashItem(item entity.TrashItem) TrashItemResponse {
+	return TrashItemResponse{
+		Kind:      item.Kind,
+		Id:        item.Id,
+		Name:      item.Name,
+		Status:    item.Status,
+		DeletedAt: item.DeletedAt,
+		PurgeAt:   item.PurgeAt,
+	}
+}
@@ -0,0 +1,141 @@
+package dto
+
+import (
+	"BookStore_API/internal/entity"
+	"encoding/json"
+	"time"
+)
+
+const (
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 200
+)
+
+type WebhookEndpointCreateRequest struct {
+	URL string `json:"url" validate:"required,http_url"`
+	// Events limits the endpoint to these event types, all events are sent if empty.
+	Events []string `json:"events" validate:"unique,dive,oneof=OrderCreated OrderStatusChanged ProductPriceChanged ProductStockChanged StockLow"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=128"`
+}
+
+type WebhookEndpointUpdateRequest struct {
+	URL     string   `json:"url" validate:"required,http_url"`
+	Events  []string `json:"events" validate:"unique,dive,oneof=OrderCreated OrderStatusChanged ProductPriceChanged ProductStockChanged StockLow"`
+	Enabled *bool    `json:"enabled" validate:"required"`
+}
+
+type WebhookDeliveryQueryRequest struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending delivered failed"`
+	Limit  int    `query:"limit" validate:"gte=0,lte=200"`
+	Offset int    `query:"offset" validate:"gte=0"`
+}
+
+type WebhookEndpointResponse struct {
+	Id     int      `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is returned only when the endpoint is created.
+	Secret              string     `json:"secret,omitempty"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+type WebhookDeliveryResponse struct {
+	Id            int64           `json:"id"`
+	EventId       int64           `json:"eventId"`
+	EventType     string          `json:"eventType"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+	ResponseCode  *int            `json:"responseCode,omitempty"`
+	ResponseBody  string          `json:"responseBody,omitempty"`
+	LastError     string          `json:"lastError,omitempty"`
+	RedeliveryOf  *int64          `json:"redeliveryOf,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+func (r *WebhookEndpointCreateRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *WebhookEndpointCreateRequest) ToEntity() entity.WebhookEndpoint {
+	return entity.WebhookEndpoint{
+		URL:    r.URL,
+		Events: r.Events,
+		Secret: r.Secret,
+	}
+}
+
+func (r *WebhookEndpointUpdateRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *WebhookEndpointUpdateRequest) ToEntity(id int) entity.WebhookEndpoint {
+	return entity.WebhookEndpoint{
+		Id:      id,
+		URL:     r.URL,
+		Events:  r.Events,
+		Enabled: *r.Enabled,
+	}
+}
+
+func (r *WebhookDeliveryQueryRequest) Validate() error {
+	return validate.Struct(r)
+}
+
+func (r *WebhookDeliveryQueryRequest) ToFilter(endpointId int) entity.WebhookDeliveryFilter {
+	limit := r.Limit
+	if limit == 0 {
+		limit = defaultWebhookDeliveryLimit
+	}
+	return entity.WebhookDeliveryFilter{
+		EndpointId: endpointId,
+		Status:     r.Status,
+		Limit:      min(limit, maxWebhookDeliveryLimit),
+		Offset:     r.Offset,
+	}
+}
+
+// FromEntityWebhookEndpoint leaves the secret out, it is shown only once by the caller.
+func FromEntityWebhookEndpoint(e entity.WebhookEndpoint) WebhookEndpointResponse {
+	events := e.Events
+	if events == nil {
+		events = []string{}
+	}
+	return WebhookEndpointResponse{
+		Id:                  e.Id,
+		URL:                 e.URL,
+		Events:              events,
+		Enabled:             e.Enabled,
+		ConsecutiveFailures: e.ConsecutiveFailures,
+		DisabledAt:          e.DisabledAt,
+		CreatedAt:           e.CreatedAt,
+		UpdatedAt:           e.UpdatedAt,
+	}
+}
+
+func FromEntityWebhookDelivery(d entity.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		Id:           d.Id,
+		EventId:      d.EventId,
+		EventType:    d.EventType,
+		Payload:      d.Payload,
+		Status:       d.Status,
+		Attempts:     d.Attempts,
+		ResponseCode: d.ResponseCode,
+		ResponseBody: d.ResponseBody,
+		LastError:    d.LastError,
+		RedeliveryOf: d.RedeliveryOf,
+		CreatedAt:    d.CreatedAt,
+		DeliveredAt:  d.DeliveredAt,
+	}
+	if d.Status == entity.WebhookDeliveryPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	return resp
+}
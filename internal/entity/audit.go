@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditEntityBook     = "book"
+	AuditEntityMagazine = "magazine"
+	AuditEntityOrder    = "order"
+)
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditEntry records a change of a book, magazine or order. Changes maps every changed
+// field to its old and new value, old values are null on create and new ones on delete.
+type AuditEntry struct {
+	Id         int
+	Actor      string
+	RequestId  string
+	EntityType string
+	EntityId   int
+	Action     string
+	Changes    json.RawMessage
+	CreatedAt  time.Time
+}
+
+// AuditFilter selects audit entries; zero values match everything.
+type AuditFilter struct {
+	EntityType string
+	EntityId   int
+	Actor      string
+	Action     string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
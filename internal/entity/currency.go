@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+const (
+	CurrencyEUR = "EUR"
+	CurrencyUSD = "USD"
+	CurrencyUAH = "UAH"
+)
+
+// ExchangeRate is the amount of Currency per one unit of the base currency,
+// effective from EffectiveFrom until the next rate for the same currency.
+type ExchangeRate struct {
+	Id            int
+	Currency      string
+	Rate          float64
+	EffectiveFrom time.Time
+	CreatedAt     time.Time
+}
+
+// PriceOverride is a fixed product price in a specific currency that is used
+// instead of the converted base price.
+type PriceOverride struct {
+	ProductId int
+	Currency  string
+	Price     float64
+	UpdatedAt time.Time
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+const (
+	HealthCheckDatabase   = "database"
+	HealthCheckMigrations = "migrations"
+	HealthCheckWorkers    = "workers"
+)
+
+// HealthCheck is the result of checking one dependency; Details holds what the check found.
+type HealthCheck struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	Error   string
+	Details map[string]any
+}
+
+type PoolStats struct {
+	MaxConns          int32
+	TotalConns        int32
+	IdleConns         int32
+	AcquiredConns     int32
+	AcquireCount      int64
+	EmptyAcquireCount int64
+	AcquireDuration   time.Duration
+}
+
+// HealthReport is up when all its checks are.
+type HealthReport struct {
+	Status    string
+	Checks    []HealthCheck
+	Pool      PoolStats
+	CheckedAt time.Time
+}
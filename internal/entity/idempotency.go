@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyRecord is the stored outcome of a request sent with an idempotency key.
+// Route is the request method and path, RequestHash identifies the request body.
+type IdempotencyRecord struct {
+	Key            string
+	Route          string
+	RequestHash    string
+	Status         string
+	ResponseStatus int
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
@@ -13,13 +13,63 @@ const (
 )
 
 type Order struct {
-	Id        int
-	Items     []OrderItem
-	Status    string
-	CreatedAt time.Time
+	Id           int
+	Items        []OrderItem
+	Status       string
+	Currency     string
+	ExchangeRate float64
+	Destination  Address
+	TaxMode      string
+	TaxLines     []TaxLine
+	Shipping     OrderShipping
+	Version      int
+	CreatedAt    time.Time
+}
+
+// Totals returns the item subtotal, the tax total and the amount due. In inclusive
+// mode tax is already part of the item prices and is not added to the total.
+func (o Order) Totals() (subtotal, taxTotal, total float64) {
+	for _, item := range o.Items {
+		subtotal += item.Product.Price * float64(item.Quantity)
+		taxTotal += item.TaxAmount
+	}
+
+	total = subtotal + o.Shipping.Cost
+	if o.TaxMode != TaxModeInclusive {
+		total += taxTotal
+	}
+	return subtotal, taxTotal, total
+}
+
+// OrderShipping is the shipping method chosen for the order and its cost in the order currency.
+type OrderShipping struct {
+	Method         string
+	Cost           float64
+	TrackingNumber string
 }
 
 type OrderItem struct {
-	Product  BaseProduct
-	Quantity int
+	Product   BaseProduct
+	Quantity  int
+	TaxRate   float64
+	TaxAmount float64
+
+	// ProductType, Isbn and IssueNumber are taken when the item is ordered, like the name and price.
+	ProductType string
+	Isbn        string
+	IssueNumber int
+	// Current describes the product as it is now, it is set only when the order is annotated.
+	Current *CurrentProduct
+}
+
+// CurrentProduct tells whether an ordered product still exists and its price now, in the order currency.
+type CurrentProduct struct {
+	Exists bool
+	Price  float64
+}
+
+// Address is a shipping destination; Country is an ISO 3166-1 alpha-2 code.
+type Address struct {
+	Country string
+	Region  string
 }
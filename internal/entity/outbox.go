@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+const (
+	EventOrderCreated        = "OrderCreated"
+	EventOrderStatusChanged  = "OrderStatusChanged"
+	EventProductPriceChanged = "ProductPriceChanged"
+	EventProductStockChanged = "ProductStockChanged"
+	EventStockLow            = "StockLow"
+)
+
+const (
+	AggregateOrder   = "order"
+	AggregateProduct = "product"
+)
+
+// OutboxEvent is a domain event stored together with the change that raised it.
+// Events of the same aggregate are delivered in the order of their Id.
+type OutboxEvent struct {
+	Id            int64
+	AggregateType string
+	AggregateId   int
+	EventType     string
+	Payload       json.RawMessage
+	Attempts      int
+	CreatedAt     time.Time
+}
+
+// EventFilter selects events of the given orders and products, an empty filter matches all.
+// There is no customer filter, orders are not linked to customers.
+type EventFilter struct {
+	OrderIds   []int
+	ProductIds []int
+}
+
+func (f EventFilter) Matches(e OutboxEvent) bool {
+	if len(f.OrderIds) == 0 && len(f.ProductIds) == 0 {
+		return true
+	}
+	switch e.AggregateType {
+	case AggregateOrder:
+		return slices.Contains(f.OrderIds, e.AggregateId)
+	case AggregateProduct:
+		return slices.Contains(f.ProductIds, e.AggregateId)
+	}
+	return false
+}
+
+type OrderCreatedPayload struct {
+	OrderId  int                `json:"orderId"`
+	Status   string             `json:"status"`
+	Currency string             `json:"currency"`
+	Total    float64            `json:"total"`
+	Items    []OrderCreatedItem `json:"items"`
+}
+
+type OrderCreatedItem struct {
+	ProductId int     `json:"productId"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+type OrderStatusChangedPayload struct {
+	OrderId int    `json:"orderId"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+type ProductPriceChangedPayload struct {
+	ProductId int     `json:"productId"`
+	OldPrice  float64 `json:"oldPrice"`
+	NewPrice  float64 `json:"newPrice"`
+	Source    string  `json:"source"`
+}
+
+type ProductStockChangedPayload struct {
+	ProductId int `json:"productId"`
+	OldStock  int `json:"oldStock"`
+	NewStock  int `json:"newStock"`
+}
+
+// StockLowPayload is raised when the stock of a product drops to the threshold or below.
+type StockLowPayload struct {
+	ProductId int `json:"productId"`
+	Stock     int `json:"stock"`
+	Threshold int `json:"threshold"`
+}
@@ -0,0 +1,60 @@
+package entity
+
+import "time"
+
+const (
+	PaymentStatusPending           = "pending"
+	PaymentStatusAuthorized        = "authorized"
+	PaymentStatusCaptured          = "captured"
+	PaymentStatusDeclined          = "declined"
+	PaymentStatusVoided            = "voided"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+	PaymentStatusFailed            = "failed"
+)
+
+// paymentTransitions lists the statuses a payment may move to from each status.
+var paymentTransitions = map[string][]string{
+	PaymentStatusPending: {
+		PaymentStatusAuthorized, PaymentStatusCaptured, PaymentStatusDeclined,
+		PaymentStatusVoided, PaymentStatusFailed,
+	},
+	PaymentStatusAuthorized:        {PaymentStatusCaptured, PaymentStatusVoided, PaymentStatusFailed},
+	PaymentStatusCaptured:          {PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+	PaymentStatusPartiallyRefunded: {PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+}
+
+// CanTransitionPayment reports whether a payment in status from may move to status to.
+func CanTransitionPayment(from, to string) bool {
+	for _, status := range paymentTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Payment is a single attempt to pay for an order through a payment provider.
+type Payment struct {
+	Id             int
+	OrderId        int
+	Provider       string
+	ProviderRef    string
+	Status         string
+	Amount         float64
+	Currency       string
+	RefundedAmount float64
+	FailureReason  string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// PaymentEvent is a provider callback about a payment state change.
+type PaymentEvent struct {
+	Provider    string
+	EventId     string
+	ProviderRef string
+	Status      string
+	Amount      float64
+	Payload     []byte
+}
@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+const (
+	PriceSourceInitial     = "initial"
+	PriceSourceManual      = "manual"
+	PriceSourceScheduled   = "scheduled"
+	PriceSourceScheduleEnd = "schedule_end"
+)
+
+const (
+	ScheduledPriceStatusPending   = "pending"
+	ScheduledPriceStatusActive    = "active"
+	ScheduledPriceStatusCompleted = "completed"
+	ScheduledPriceStatusCanceled  = "canceled"
+)
+
+type PriceChange struct {
+	Id        int
+	ProductId int
+	Price     float64
+	Source    string
+	ChangedAt time.Time
+}
+
+// ScheduledPrice is a future price for a product. When EndsAt is set the price is
+// temporary (a sale) and PreviousPrice is restored once the period is over.
+type ScheduledPrice struct {
+	Id            int
+	ProductId     int
+	Price         float64
+	PreviousPrice *float64
+	StartsAt      time.Time
+	EndsAt        *time.Time
+	Status        string
+	CreatedAt     time.Time
+}
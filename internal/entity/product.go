@@ -3,9 +3,18 @@ package entity
 import "time"
 
 type BaseProduct struct {
-	Id        int
-	Name      string
-	Price     float64
-	Stock     int
+	Id          int
+	Name        string
+	Price       float64
+	Stock       int
+	TaxClass    string
+	WeightGrams int
+	// Version is bumped on every update and guards against lost updates.
+	Version   int
 	CreatedAt time.Time
+	// DeletedAt is set while the product is in the trash.
+	DeletedAt *time.Time
+
+	// Currency of Price; empty means the base currency.
+	Currency string
 }
@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	ReturnStatusReceived  = "received"
+	// ReturnStatusRefunding is held while the refund is made, so it is made only once.
+	ReturnStatusRefunding = "refunding"
+	ReturnStatusRefunded  = "refunded"
+)
+
+const (
+	OrderEventReturnRequested = "return_requested"
+	OrderEventReturnApproved  = "return_approved"
+	OrderEventReturnRejected  = "return_rejected"
+	OrderEventReturnReceived  = "return_received"
+	OrderEventReturnRefunded  = "return_refunded"
+)
+
+// OrderReturn is a request to send back some items of a delivered order.
+// RefundAmount is what the items cost the customer, RefundedAmount what was paid back.
+type OrderReturn struct {
+	Id             int
+	OrderId        int
+	Status         string
+	Reason         string
+	Note           string
+	Items          []ReturnItem
+	RefundAmount   float64
+	RefundedAmount float64
+	PaymentId      *int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ReturnItem is a returned quantity of an order item. Price is the unit price paid,
+// TaxAmount the tax paid for the returned quantity and Damaged the part that can not be restocked.
+type ReturnItem struct {
+	ProductId int
+	Quantity  int
+	Damaged   int
+	Price     float64
+	TaxAmount float64
+}
+
+type OrderHistoryEntry struct {
+	Id        int
+	OrderId   int
+	Event     string
+	Details   string
+	CreatedAt time.Time
+}
@@ -0,0 +1,56 @@
+package entity
+
+import "time"
+
+const (
+	ShippingMethodStandard = "standard"
+	ShippingMethodExpress  = "express"
+	ShippingMethodPickup   = "pickup"
+)
+
+const (
+	// ShippingBasisWeight prices shipping by total weight in grams.
+	ShippingBasisWeight = "weight"
+	// ShippingBasisItemCount prices shipping by total quantity of items.
+	ShippingBasisItemCount = "item_count"
+	// ShippingBasisOrderValue prices shipping by order value in the base currency.
+	ShippingBasisOrderValue = "order_value"
+)
+
+// ShippingZoneAnyCountry matches every country that is not covered by another zone.
+const ShippingZoneAnyCountry = "*"
+
+type ShippingZone struct {
+	Id        int
+	Name      string
+	Countries []string
+	Methods   []ShippingMethod
+	CreatedAt time.Time
+}
+
+type ShippingMethod struct {
+	Id     int
+	ZoneId int
+	Code   string
+	Name   string
+	Basis  string
+	// FreeThreshold is the order value in the base currency from which shipping is free.
+	FreeThreshold *float64
+	Rates         []ShippingRate
+	CreatedAt     time.Time
+}
+
+// ShippingRate is a price that applies when the basis value is at least MinValue.
+type ShippingRate struct {
+	MinValue float64
+	Price    float64
+}
+
+type ShippingQuote struct {
+	MethodId int
+	Code     string
+	Name     string
+	Cost     float64
+	Currency string
+	Free     bool
+}
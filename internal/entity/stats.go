@@ -0,0 +1,7 @@
+package entity
+
+// BusinessStats is a snapshot of the store exported as metrics.
+type BusinessStats struct {
+	OrdersByStatus   map[string]int
+	LowStockProducts int
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+const (
+	TaxClassStandard = "standard"
+	TaxClassReduced  = "reduced"
+	TaxClassZero     = "zero"
+)
+
+const (
+	// TaxModeExclusive means product prices are net and tax is added on top.
+	TaxModeExclusive = "exclusive"
+	// TaxModeInclusive means product prices already contain tax.
+	TaxModeInclusive = "inclusive"
+)
+
+// TaxRule is a tax rate for a product tax class in a country, optionally narrowed to a region.
+// An empty Region applies to the whole country.
+type TaxRule struct {
+	Id        int
+	Country   string
+	Region    string
+	TaxClass  string
+	Rate      float64
+	CreatedAt time.Time
+}
+
+// TaxLine is an order tax breakdown entry aggregated by tax class and rate.
+type TaxLine struct {
+	TaxClass      string
+	Rate          float64
+	TaxableAmount float64
+	TaxAmount     float64
+}
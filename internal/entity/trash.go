@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+const (
+	TrashKindBook     = "book"
+	TrashKindMagazine = "magazine"
+	TrashKindOrder    = "order"
+)
+
+// TrashItem is a soft deleted product or order. Name is set for products, Status for orders.
+// PurgeAt is when the purge job may delete it for good.
+type TrashItem struct {
+	Kind      string
+	Id        int
+	Name      string
+	Status    string
+	DeletedAt time.Time
+	PurgeAt   time.Time
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint is a partner URL notified about domain events. It is disabled
+// automatically after too many failed attempts in a row.
+type WebhookEndpoint struct {
+	Id     int
+	URL    string
+	Secret string `log:"redact"`
+	// Events lists the event types sent to the endpoint, empty means all.
+	Events              []string
+	Enabled             bool
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// WebhookDelivery is the delivery of one event to one endpoint and the outcome of its last attempt.
+type WebhookDelivery struct {
+	Id            int64
+	EndpointId    int
+	EventId       int64
+	EventType     string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	ResponseCode  *int
+	ResponseBody  string
+	LastError     string
+	RedeliveryOf  *int64
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+
+	// URL and Secret of the endpoint, set only on claimed deliveries.
+	URL    string
+	Secret string `log:"redact"`
+}
+
+// WebhookAttempt is the result of sending a delivery.
+type WebhookAttempt struct {
+	DeliveryId   int64
+	EndpointId   int
+	Status       string
+	ResponseCode *int
+	ResponseBody string
+	Error        string
+	// NextAttemptAt is set when the delivery is retried.
+	NextAttemptAt time.Time
+	At            time.Time
+}
+
+// WebhookDeliveryFilter selects deliveries of an endpoint; an empty status matches all.
+type WebhookDeliveryFilter struct {
+	EndpointId int
+	Status     string
+	Limit      int
+	Offset     int
+}
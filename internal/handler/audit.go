@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type GetAuditResponse struct {
+	Entries []dto.AuditEntryResponse `json:"entries"`
+	Limit   int                      `json:"limit"`
+	Offset  int                      `json:"offset"`
+	Message string                   `json:"message"`
+}
+
+func (h *Handler) getAudit(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get audit request started")
+
+	var req dto.AuditQueryRequest
+
+	// query params binding
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &req); err != nil {
+		h.requestLogger(c).Error("failed to bind query params",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrParamResponse{
+			Message: "invalid query params",
+		})
+	}
+
+	// query params validation
+	if err := req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// optional time range
+	from, err := h.parseTimeParam(c, "from", start)
+	if err != nil {
+		return err
+	}
+	to, err := h.parseTimeParam(c, "to", start)
+	if err != nil {
+		return err
+	}
+
+	filter := req.ToFilter(from, to)
+
+	// get audit service
+	entries, err := h.services.Audit.Get(c.Request().Context(), filter)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get audit entries",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.AuditEntryResponse, len(entries))
+	for i, e := range entries {
+		resp[i] = dto.FromEntityAuditEntry(e)
+	}
+
+	return c.JSON(http.StatusOK, GetAuditResponse{
+		Entries: resp,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+		Message: "here is your audit log",
+	})
+}
+
+// parseTimeParam reads an optional time from the named query param.
+func (h *Handler) parseTimeParam(c echo.Context, name string, start time.Time) (*time.Time, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	parsed, err := parseTimeQuery(raw)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get query param",
+			zap.String("param", name),
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, ErrParamResponse{
+			Message: "invalid '" + name + "' format, expected RFC3339 or YYYY-MM-DD",
+		})
+	}
+	return &parsed, nil
+}
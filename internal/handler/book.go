@@ -2,6 +2,9 @@ package handler
 
 import (
 	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 	"net/http"
@@ -32,7 +35,7 @@ func (h *Handler) createBook(c echo.Context) error {
 
 	// request binding
 	if err := c.Bind(&req); err != nil {
-		h.logger.Error("failed to bind request",
+		h.requestLogger(c).Error("failed to bind request",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -43,7 +46,7 @@ func (h *Handler) createBook(c echo.Context) error {
 
 	// request validation
 	if err := req.Validate(); err != nil {
-		h.logger.Error("validation failed",
+		h.requestLogger(c).Error("validation failed",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -57,7 +60,7 @@ func (h *Handler) createBook(c echo.Context) error {
 	// create book service
 	id, err := h.services.Book.Create(c.Request().Context(), book)
 	if err != nil {
-		h.logger.Error("failed to create book",
+		h.requestLogger(c).Error("failed to create book",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -82,10 +85,38 @@ func (h *Handler) getByIdBook(c echo.Context) error {
 		return err
 	}
 
+	// get currency query param
+	currency, err := h.parseCurrency(c, "", start)
+	if err != nil {
+		return err
+	}
+
 	// get by id book service
 	book, err := h.services.Book.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to get by id book",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	// price conversion into requested currency
+	_, err = h.services.Currency.Localize(c.Request().Context(), currency, time.Now(), &book.BaseProduct)
+	if errors.Is(err, repository.ErrRateNotFound) {
+		return c.JSON(http.StatusUnprocessableEntity, ErrParamResponse{
+			Message: "no exchange rate for currency " + currency,
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to get by id book",
+		h.requestLogger(c).Error("failed to convert book price",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -94,6 +125,15 @@ func (h *Handler) getByIdBook(c echo.Context) error {
 		})
 	}
 
+	// converted prices depend on the rate, so they are part of the tag
+	variant := ""
+	if currency != "" {
+		variant = fmt.Sprintf("%s-%.2f", book.Currency, book.Price)
+	}
+	if setETag(c, etag(book.Version, variant)) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
 	resp := dto.FromEntityBook(book)
 
 	return c.JSON(http.StatusOK, GetByIdBookResponse{
@@ -116,7 +156,7 @@ func (h *Handler) updateBook(c echo.Context) error {
 
 	// request binding
 	if err = c.Bind(&req); err != nil {
-		h.logger.Error("failed to bind request",
+		h.requestLogger(c).Error("failed to bind request",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -127,7 +167,7 @@ func (h *Handler) updateBook(c echo.Context) error {
 
 	// request validation
 	if err = req.Validate(); err != nil {
-		h.logger.Error("validation failed",
+		h.requestLogger(c).Error("validation failed",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -136,10 +176,21 @@ func (h *Handler) updateBook(c echo.Context) error {
 		})
 	}
 
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
 	// get by id book service
 	book, err := h.services.Book.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to get by id book",
+		h.requestLogger(c).Error("failed to get by id book",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -147,13 +198,19 @@ func (h *Handler) updateBook(c echo.Context) error {
 			Message: "internal server error",
 		})
 	}
+	if version != 0 && version != book.Version {
+		return versionMismatch(c)
+	}
 
 	req.ApplyToEntity(&book)
 
 	// update book service
 	err = h.services.Book.Update(c.Request().Context(), book)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
 	if err != nil {
-		h.logger.Error("failed to update book",
+		h.requestLogger(c).Error("failed to update book",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -166,6 +223,81 @@ func (h *Handler) updateBook(c echo.Context) error {
 		Message: "book successfully updated",
 	})
 }
+func (h *Handler) patchBook(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Patch book request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get by id book service
+	book, err := h.services.Book.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to get by id book",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+	if version != 0 && version != book.Version {
+		return versionMismatch(c)
+	}
+
+	// patch binding
+	doc := dto.NewBookPatchDocument(book)
+	if err = h.bindPatch(c, &doc, start); err != nil {
+		return err
+	}
+
+	// patched document validation
+	if err = doc.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	doc.ApplyToEntity(&book)
+
+	// update book service
+	err = h.services.Book.Update(c.Request().Context(), book)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to patch book",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, UpdateBookResponse{
+		Message: "book successfully patched",
+	})
+}
 func (h *Handler) deleteBook(c echo.Context) error {
 	start := time.Now()
 
@@ -177,10 +309,24 @@ func (h *Handler) deleteBook(c echo.Context) error {
 		return err
 	}
 
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
 	// delete book service
-	err = h.services.Book.Delete(c.Request().Context(), id)
+	err = h.services.Book.Delete(c.Request().Context(), id, version)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrDeleteByIdResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to delete by id book",
+		h.requestLogger(c).Error("failed to delete by id book",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
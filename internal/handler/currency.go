@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/service"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type CreateExchangeRateResponse struct {
+	Id      int    `json:"id"`
+	Message string `json:"message"`
+}
+type GetExchangeRatesResponse struct {
+	BaseCurrency string                     `json:"baseCurrency"`
+	Rates        []dto.ExchangeRateResponse `json:"rates"`
+	Message      string                     `json:"message"`
+}
+type GetPriceOverridesResponse struct {
+	Overrides []dto.PriceOverrideResponse `json:"overrides"`
+	Message   string                      `json:"message"`
+}
+type SetPriceOverrideResponse struct {
+	Message string `json:"message"`
+}
+type DeletePriceOverrideResponse struct {
+	Message string `json:"message"`
+}
+
+func (h *Handler) createExchangeRate(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Create exchange rate request started")
+
+	var req dto.ExchangeRateCreateRequest
+
+	// request binding
+	if err := c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err := req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	rate := req.ToEntity()
+
+	// create exchange rate service
+	id, err := h.services.Currency.CreateRate(c.Request().Context(), rate)
+	if errors.Is(err, service.ErrBaseCurrencyRate) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to create exchange rate",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, CreateExchangeRateResponse{
+		Id:      id,
+		Message: "exchange rate created",
+	})
+}
+func (h *Handler) getExchangeRates(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get exchange rates request started")
+
+	// get currency query param
+	currency, err := h.parseCurrency(c, "", start)
+	if err != nil {
+		return err
+	}
+
+	// get exchange rates service
+	rates, err := h.services.Currency.GetRates(c.Request().Context(), currency)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get exchange rates",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.ExchangeRateResponse, len(rates))
+	for i, rate := range rates {
+		resp[i] = dto.FromEntityExchangeRate(rate)
+	}
+
+	return c.JSON(http.StatusOK, GetExchangeRatesResponse{
+		BaseCurrency: h.services.Currency.BaseCurrency(),
+		Rates:        resp,
+		Message:      "here are your exchange rates",
+	})
+}
+
+func (h *Handler) getPriceOverrides(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get price overrides request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get price overrides service
+	overrides, err := h.services.Currency.GetPriceOverrides(c.Request().Context(), id)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get price overrides",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.PriceOverrideResponse, len(overrides))
+	for i, po := range overrides {
+		resp[i] = dto.FromEntityPriceOverride(po)
+	}
+
+	return c.JSON(http.StatusOK, GetPriceOverridesResponse{
+		Overrides: resp,
+		Message:   "here are your price overrides",
+	})
+}
+func (h *Handler) setPriceOverride(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Set price override request started")
+
+	// get id and currency params
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+	currency, err := h.parseCurrency(c, "currency", start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.PriceOverrideRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	po := req.ToEntity(id, currency)
+
+	// set price override service
+	err = h.services.Currency.SetPriceOverride(c.Request().Context(), po)
+	if errors.Is(err, service.ErrBaseCurrencyOverride) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to set price override",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, SetPriceOverrideResponse{
+		Message: "price override successfully set",
+	})
+}
+func (h *Handler) deletePriceOverride(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Delete price override request started")
+
+	// get id and currency params
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+	currency, err := h.parseCurrency(c, "currency", start)
+	if err != nil {
+		return err
+	}
+
+	// delete price override service
+	err = h.services.Currency.DeletePriceOverride(c.Request().Context(), id, currency)
+	if err != nil {
+		h.requestLogger(c).Error("failed to delete price override",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrDeleteByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, DeletePriceOverrideResponse{
+		Message: "price override successfully deleted",
+	})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// etag formats the entity tag of a resource version. The variant tells apart
+// representations of the same version, e.g. prices converted with a changing rate.
+func etag(version int, variant string) string {
+	if variant == "" {
+		return fmt.Sprintf(`"%d"`, version)
+	}
+	return fmt.Sprintf(`"%d-%s"`, version, variant)
+}
+
+// setETag adds the ETag header and reports whether the client copy is still fresh,
+// in which case 304 must be returned instead of the body.
+func setETag(c echo.Context, tag string) bool {
+	c.Response().Header().Set("ETag", tag)
+
+	ifNoneMatch := c.Request().Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfMatch returns the resource version the client expects from the If-Match header,
+// 0 if any version is accepted. A missing header is rejected with 428 if it is required.
+func (h *Handler) parseIfMatch(c echo.Context, start time.Time) (int, error) {
+	raw := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if raw == "" {
+		if h.requireIfMatch.Load() {
+			return 0, echo.NewHTTPError(http.StatusPreconditionRequired, ErrParamResponse{
+				Message: "If-Match header is required",
+			})
+		}
+		return 0, nil
+	}
+	if raw == "*" {
+		return 0, nil
+	}
+
+	// the version is the leading number of the first tag
+	tag := strings.TrimSpace(strings.Split(raw, ",")[0])
+	tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+	if i := strings.IndexByte(tag, '-'); i >= 0 {
+		tag = tag[:i]
+	}
+
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		h.requestLogger(c).Error("failed to parse If-Match header",
+			zap.String("if_match", raw),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return 0, echo.NewHTTPError(http.StatusBadRequest, ErrParamResponse{
+			Message: "invalid If-Match header",
+		})
+	}
+	return version, nil
+}
+
+// versionMismatch writes the 412 response for a stale If-Match version.
+func versionMismatch(c echo.Context) error {
+	return c.JSON(http.StatusPreconditionFailed, ErrUpdateResponse{
+		Message: "resource was modified, fetch it again",
+	})
+}
@@ -1,11 +1,20 @@
 package handler
 
 import (
+	"BookStore_API/internal/config"
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
 	"BookStore_API/internal/service"
+	"errors"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,29 +43,107 @@ type ErrDeleteByIdResponse struct {
 
 type Handler struct {
 	services *service.Service
-	logger   *zap.Logger
+	// requireIfMatch changes when the config is reloaded
+	requireIfMatch    atomic.Bool
+	heartbeatInterval time.Duration
+	logger            *zap.Logger
+	// ready is false from the start of the shutdown
+	ready atomic.Bool
+	// closing is closed when the server shuts down, ending the event streams
+	closing   chan struct{}
+	closeOnce sync.Once
 }
 
-func NewHandler(s *service.Service, logger *zap.Logger) *Handler {
-	return &Handler{
-		services: s,
-		logger:   logger,
+func NewHandler(s *service.Service, cfg *config.Config, logger *zap.Logger) *Handler {
+	h := &Handler{
+		services:          s,
+		heartbeatInterval: cfg.StreamCfg.HeartbeatInterval,
+		logger:            logger,
+		closing:           make(chan struct{}),
 	}
+	h.ready.Store(true)
+	h.requireIfMatch.Store(cfg.RequireIfMatch)
+	return h
+}
+
+// SetReady changes the answer of the readiness probe.
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// SetRequireIfMatch changes whether updates and deletes of versioned resources need If-Match.
+func (h *Handler) SetRequireIfMatch(require bool) {
+	h.requireIfMatch.Store(require)
+}
+
+// CloseStreams ends the open event streams, their clients reconnect to another replica.
+func (h *Handler) CloseStreams() {
+	h.closeOnce.Do(func() { close(h.closing) })
 }
 
 func (h *Handler) RegisterRoutes(e *echo.Echo) {
+	e.Use(h.observeRequest)
+	e.Use(h.traceRequest)
+	e.Use(h.requestContext)
+	e.Use(h.idempotency)
+
 	e.GET("/ping", h.serverPing)
+	e.GET("/healthz", h.liveness)
+	e.GET("/readyz", h.readiness)
+	e.GET("/health", h.health)
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 
+	h.registerProductRoutes(e)
+	h.registerExchangeRateRoutes(e)
+	h.registerTaxRoutes(e)
+	h.registerShippingRoutes(e)
 	h.registerBookRoutes(e)
 	h.registerMagazineRoutes(e)
 	h.registerOrderRoutes(e)
+	h.registerPaymentRoutes(e)
+	h.registerReturnRoutes(e)
+	h.registerTrashRoutes(e)
+	h.registerAuditRoutes(e)
+	h.registerWebhookRoutes(e)
+	h.registerEventRoutes(e)
 }
 
+func (h *Handler) registerProductRoutes(e *echo.Echo) {
+	products := e.Group("/products")
+	products.GET("/:id/prices", h.getPrices)
+	products.POST("/:id/prices/schedule", h.schedulePrice)
+	products.DELETE("/:id/prices/schedule/:scheduleId", h.cancelScheduledPrice)
+	products.GET("/:id/price-overrides", h.getPriceOverrides)
+	products.PUT("/:id/price-overrides/:currency", h.setPriceOverride)
+	products.DELETE("/:id/price-overrides/:currency", h.deletePriceOverride)
+	products.POST("/:id/restore", h.restoreProduct)
+}
+func (h *Handler) registerExchangeRateRoutes(e *echo.Echo) {
+	rates := e.Group("/exchange-rates")
+	rates.POST("", h.createExchangeRate)
+	rates.GET("", h.getExchangeRates)
+}
+func (h *Handler) registerTaxRoutes(e *echo.Echo) {
+	taxes := e.Group("/tax-rules")
+	taxes.POST("", h.createTaxRule)
+	taxes.GET("", h.getTaxRules)
+	taxes.DELETE("/:id", h.deleteTaxRule)
+}
+func (h *Handler) registerShippingRoutes(e *echo.Echo) {
+	shipping := e.Group("/shipping")
+	shipping.POST("/zones", h.createShippingZone)
+	shipping.GET("/zones", h.getShippingZones)
+	shipping.DELETE("/zones/:id", h.deleteShippingZone)
+	shipping.POST("/zones/:id/methods", h.createShippingMethod)
+	shipping.DELETE("/methods/:id", h.deleteShippingMethod)
+	shipping.POST("/quote", h.quoteShipping)
+}
 func (h *Handler) registerBookRoutes(e *echo.Echo) {
 	notes := e.Group("/books")
 	notes.POST("", h.createBook)
 	notes.GET("/:id", h.getByIdBook)
 	notes.PUT("/:id", h.updateBook)
+	notes.PATCH("/:id", h.patchBook)
 	notes.DELETE("/:id", h.deleteBook)
 }
 func (h *Handler) registerMagazineRoutes(e *echo.Echo) {
@@ -64,6 +151,7 @@ func (h *Handler) registerMagazineRoutes(e *echo.Echo) {
 	magz.POST("", h.createMagazine)
 	magz.GET("/:id", h.getByIdMagazine)
 	magz.PUT("/:id", h.updateMagazine)
+	magz.PATCH("/:id", h.patchMagazine)
 	magz.DELETE("/:id", h.deleteMagazine)
 }
 func (h *Handler) registerOrderRoutes(e *echo.Echo) {
@@ -71,7 +159,48 @@ func (h *Handler) registerOrderRoutes(e *echo.Echo) {
 	orders.POST("", h.createOrder)
 	orders.GET("/:id", h.getByIdOrder)
 	orders.PUT("/:id", h.updateOrder)
+	orders.PATCH("/:id", h.patchOrder)
 	orders.DELETE("/:id", h.deleteOrder)
+	orders.POST("/:id/payments", h.createPayment)
+	orders.GET("/:id/payments", h.getPayments)
+	orders.POST("/:id/returns", h.createReturn)
+	orders.GET("/:id/returns", h.getReturns)
+	orders.GET("/:id/history", h.getOrderHistory)
+	orders.POST("/:id/restore", h.restoreOrder)
+}
+func (h *Handler) registerPaymentRoutes(e *echo.Echo) {
+	payments := e.Group("/payments")
+	payments.POST("/:id/capture", h.capturePayment)
+	payments.POST("/:id/refund", h.refundPayment)
+	payments.POST("/:id/void", h.voidPayment)
+	payments.POST("/webhooks/:provider", h.handlePaymentWebhook)
+}
+func (h *Handler) registerReturnRoutes(e *echo.Echo) {
+	returns := e.Group("/returns")
+	returns.POST("/:id/approve", h.approveReturn)
+	returns.POST("/:id/reject", h.rejectReturn)
+	returns.POST("/:id/receive", h.receiveReturn)
+	returns.POST("/:id/refund", h.refundReturn)
+}
+func (h *Handler) registerTrashRoutes(e *echo.Echo) {
+	e.GET("/trash", h.getTrash)
+}
+func (h *Handler) registerAuditRoutes(e *echo.Echo) {
+	e.GET("/audit", h.getAudit)
+}
+func (h *Handler) registerWebhookRoutes(e *echo.Echo) {
+	webhooks := e.Group("/webhooks")
+	webhooks.POST("", h.createWebhook)
+	webhooks.GET("", h.getWebhooks)
+	webhooks.GET("/:id", h.getByIdWebhook)
+	webhooks.PUT("/:id", h.updateWebhook)
+	webhooks.DELETE("/:id", h.deleteWebhook)
+	webhooks.GET("/:id/deliveries", h.getWebhookDeliveries)
+	webhooks.POST("/:id/deliveries/:deliveryId/redeliver", h.redeliverWebhook)
+}
+func (h *Handler) registerEventRoutes(e *echo.Echo) {
+	events := e.Group("/events")
+	events.GET("/stream", h.streamEvents)
 }
 
 func (h *Handler) serverPing(c echo.Context) error {
@@ -79,22 +208,63 @@ func (h *Handler) serverPing(c echo.Context) error {
 }
 
 func (h *Handler) parseIdParam(c echo.Context, start time.Time) (int, error) {
-	id, err := strconv.Atoi(c.Param("id"))
+	return h.parseIntParam(c, "id", start)
+}
+func (h *Handler) parseIntParam(c echo.Context, name string, start time.Time) (int, error) {
+	value, err := strconv.Atoi(c.Param(name))
 	if err != nil {
-		h.logger.Error("failed to get param",
+		h.requestLogger(c).Error("failed to get param",
+			zap.String("param", name),
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
 		return 0, echo.NewHTTPError(http.StatusBadRequest, ErrParamResponse{
-			Message: "invalid id format",
+			Message: "invalid " + name + " format",
 		})
 	}
-	return id, nil
+	return value, nil
+}
+
+// parseCurrency reads an optional currency from the named path param or, if name is empty,
+// from the 'currency' query param. An empty result means the base currency.
+func (h *Handler) parseCurrency(c echo.Context, name string, start time.Time) (string, error) {
+	raw := c.QueryParam("currency")
+	if name != "" {
+		raw = c.Param(name)
+	}
+
+	currency := strings.ToUpper(raw)
+	if currency != "" && !dto.IsValidCurrency(currency) {
+		h.requestLogger(c).Error("failed to get currency",
+			zap.String("currency", raw),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return "", echo.NewHTTPError(http.StatusBadRequest, ErrParamResponse{
+			Message: "invalid currency",
+		})
+	}
+	return currency, nil
+}
+
+// requestLogger returns the logger of the request, which tags every line with the request id,
+// route, client IP and actor.
+func (h *Handler) requestLogger(c echo.Context) *zap.Logger {
+	return reqctx.Logger(c.Request().Context(), h.logger)
 }
 
 func (h *Handler) logRequestStart(c echo.Context, msg string) {
-	h.logger.Info(msg,
+	h.requestLogger(c).Info(msg,
 		zap.String("method", c.Request().Method),
 		zap.String("path", c.Request().URL.Path),
 	)
 }
+
+// isUnprocessable reports whether err is caused by something the request refers to
+// (product, currency, destination, shipping method) rather than by an internal failure.
+func isUnprocessable(err error) bool {
+	return errors.Is(err, repository.ErrProductNotFound) ||
+		errors.Is(err, service.ErrShippingMethodNotFound) ||
+		errors.Is(err, service.ErrDestinationRequired) ||
+		errors.Is(err, repository.ErrShippingZoneNotFound) ||
+		errors.Is(err, repository.ErrRateNotFound)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/entity"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type ProbeResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// liveness answers as long as the process serves requests, dependencies are not checked
+// so a database outage does not get the replica restarted.
+func (h *Handler) liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, ProbeResponse{
+		Status: entity.HealthUp,
+	})
+}
+
+// readiness tells whether the replica should get traffic.
+func (h *Handler) readiness(c echo.Context) error {
+	if !h.ready.Load() {
+		return c.JSON(http.StatusServiceUnavailable, ProbeResponse{
+			Status: entity.HealthDown,
+			Reason: "shutting down",
+		})
+	}
+
+	report := h.services.Health.Check(c.Request().Context())
+	for _, check := range report.Checks {
+		if check.Status != entity.HealthUp {
+			return c.JSON(http.StatusServiceUnavailable, ProbeResponse{
+				Status: entity.HealthDown,
+				Reason: check.Name + ": " + check.Error,
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, ProbeResponse{
+		Status: entity.HealthUp,
+	})
+}
+
+func (h *Handler) health(c echo.Context) error {
+	report := h.services.Health.Check(c.Request().Context())
+
+	status := http.StatusOK
+	if report.Status != entity.HealthUp {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, dto.FromEntityHealthReport(report))
+}
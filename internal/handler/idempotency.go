@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"BookStore_API/internal/service"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// responseRecorder keeps a copy of the response body while it is written to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// idempotency makes POST requests carrying an Idempotency-Key header safe to retry.
+// The first response is stored for the key, route and request body; a retry gets the
+// stored response back, a retry while the first request is running gets 409 and reusing
+// the key for another body gets 422.
+func (h *Handler) idempotency(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(IdempotencyKeyHeader)
+		if c.Request().Method != http.MethodPost || key == "" {
+			return next(c)
+		}
+
+		start := time.Now()
+
+		if len(key) > maxIdempotencyKeyLength {
+			return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+				Message: "idempotency key is too long",
+			})
+		}
+
+		// the body is hashed and put back for the handler
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			h.requestLogger(c).Error("failed to read request body",
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return c.JSON(http.StatusBadRequest, ErrBindResponse{
+				Message: "invalid request body",
+			})
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(body)
+		route := c.Request().Method + " " + c.Request().URL.Path
+		ctx := c.Request().Context()
+
+		stored, err := h.services.Idempotency.Begin(ctx, key, route, hex.EncodeToString(sum[:]))
+		if errors.Is(err, service.ErrIdempotencyInProgress) {
+			return c.JSON(http.StatusConflict, ErrCreateResponse{
+				Message: err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrIdempotencyKeyReused) {
+			return c.JSON(http.StatusUnprocessableEntity, ErrValidationResponse{
+				Message: err.Error(),
+			})
+		}
+		if err != nil {
+			h.requestLogger(c).Error("failed to check idempotency key",
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+				Message: "internal server error",
+			})
+		}
+
+		// replay of a finished request
+		if stored != nil {
+			c.Response().Header().Set(IdempotentReplayedHeader, "true")
+			return c.Blob(stored.ResponseStatus, echo.MIMEApplicationJSON, stored.ResponseBody)
+		}
+
+		// the key is completed or released even if the client went away meanwhile, otherwise a
+		// retry after the lock timeout would process the request a second time
+		ctx = context.WithoutCancel(ctx)
+
+		// a panicking handler releases the key too
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if relErr := h.services.Idempotency.Release(ctx, key, route); relErr != nil {
+				h.requestLogger(c).Error("failed to release idempotency key", zap.Error(relErr))
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = rec
+
+		err = next(c)
+
+		// server errors are not stored, so a retry can still succeed
+		res := c.Response()
+		if err != nil || !res.Committed || res.Status >= http.StatusInternalServerError {
+			return err
+		}
+
+		completed = true
+		if err = h.services.Idempotency.Complete(ctx, key, route, res.Status, rec.body.Bytes()); err != nil {
+			h.requestLogger(c).Error("failed to store idempotent response",
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+		}
+		return nil
+	}
+}
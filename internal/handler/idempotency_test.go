@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/service"
+	"context"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIdempotencyRepository keeps the records in memory. Like the database it fails on a
+// canceled context, so the middleware has to store outcomes without the request context.
+type fakeIdempotencyRepository struct {
+	repository.Idempotency
+
+	records map[string]entity.IdempotencyRecord
+}
+
+func (r *fakeIdempotencyRepository) Acquire(ctx context.Context, rec entity.IdempotencyRecord, staleBefore time.Time) (entity.IdempotencyRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.IdempotencyRecord{}, false, err
+	}
+	id := rec.Key + " " + rec.Route
+	stored, ok := r.records[id]
+	if ok && (stored.Status == entity.IdempotencyStatusCompleted || stored.CreatedAt.After(staleBefore)) {
+		return stored, false, nil
+	}
+	rec.Status = entity.IdempotencyStatusInProgress
+	r.records[id] = rec
+	return rec, true, nil
+}
+
+func (r *fakeIdempotencyRepository) Complete(ctx context.Context, key, route string, status int, body []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rec := r.records[key+" "+route]
+	rec.Status = entity.IdempotencyStatusCompleted
+	rec.ResponseStatus = status
+	rec.ResponseBody = append([]byte(nil), body...)
+	r.records[key+" "+route] = rec
+	return nil
+}
+
+func (r *fakeIdempotencyRepository) Release(ctx context.Context, key, route string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	delete(r.records, key+" "+route)
+	return nil
+}
+
+func newIdempotencyTest(next echo.HandlerFunc) (echo.HandlerFunc, *fakeIdempotencyRepository) {
+	fake := &fakeIdempotencyRepository{records: make(map[string]entity.IdempotencyRecord)}
+	repo := &repository.Repository{Idempotency: fake}
+	h := &Handler{
+		services: &service.Service{Idempotency: service.NewIdempotencyService(repo, time.Hour, zap.NewNop())},
+		logger:   zap.NewNop(),
+	}
+	return h.idempotency(next), fake
+}
+
+func serveIdempotent(t *testing.T, handler echo.HandlerFunc, ctx context.Context, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+	if err := handler(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rec
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := 0
+	handler, _ := newIdempotencyTest(func(c echo.Context) error {
+		calls++
+		return c.JSON(http.StatusCreated, map[string]int{"id": calls})
+	})
+
+	first := serveIdempotent(t, handler, context.Background(), "key-1", `{"items":[]}`)
+	second := serveIdempotent(t, handler, context.Background(), "key-1", `{"items":[]}`)
+
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("%s header missing on the replay", IdempotentReplayedHeader)
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	var handler echo.HandlerFunc
+	var retry *httptest.ResponseRecorder
+	handler, _ = newIdempotencyTest(func(c echo.Context) error {
+		// the retry arrives while the first request is still running
+		if retry == nil {
+			retry = serveIdempotent(t, handler, context.Background(), "key-1", `{}`)
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+
+	serveIdempotent(t, handler, context.Background(), "key-1", `{}`)
+	if retry.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", retry.Code, http.StatusConflict)
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	handler, _ := newIdempotencyTest(func(c echo.Context) error {
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+
+	serveIdempotent(t, handler, context.Background(), "key-1", `{"quantity":1}`)
+	rec := serveIdempotent(t, handler, context.Background(), "key-1", `{"quantity":2}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handler, fake := newIdempotencyTest(func(c echo.Context) error {
+		// the client disconnects after the order was created
+		cancel()
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+
+	serveIdempotent(t, handler, ctx, "key-1", `{}`)
+	if rec := fake.records["key-1 POST /orders"]; rec.Status != entity.IdempotencyStatusCompleted {
+		t.Fatalf("record status = %q, want %q", rec.Status, entity.IdempotencyStatusCompleted)
+	}
+}
+
+func TestIdempotencyRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+	}{
+		{
+			name: "server error",
+			handler: func(c echo.Context) error {
+				return c.JSON(http.StatusInternalServerError, ErrCreateResponse{Message: "internal server error"})
+			},
+		},
+		{
+			name: "panic",
+			handler: func(c echo.Context) error {
+				panic("handler failed")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, fake := newIdempotencyTest(tt.handler)
+
+			func() {
+				defer func() { _ = recover() }()
+				serveIdempotent(t, handler, context.Background(), "key-1", `{}`)
+			}()
+			if _, ok := fake.records["key-1 POST /orders"]; ok {
+				t.Fatal("key was not released")
+			}
+		})
+	}
+}
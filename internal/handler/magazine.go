@@ -2,6 +2,9 @@ package handler
 
 import (
 	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 	"net/http"
@@ -32,7 +35,7 @@ func (h *Handler) createMagazine(c echo.Context) error {
 
 	// request binding
 	if err := c.Bind(&req); err != nil {
-		h.logger.Error("failed to bind request",
+		h.requestLogger(c).Error("failed to bind request",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -43,7 +46,7 @@ func (h *Handler) createMagazine(c echo.Context) error {
 
 	// request validation
 	if err := req.Validate(); err != nil {
-		h.logger.Error("validation failed",
+		h.requestLogger(c).Error("validation failed",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -57,7 +60,7 @@ func (h *Handler) createMagazine(c echo.Context) error {
 	// create magazine service
 	id, err := h.services.Magazine.Create(c.Request().Context(), magazine)
 	if err != nil {
-		h.logger.Error("failed to create magazine",
+		h.requestLogger(c).Error("failed to create magazine",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -82,10 +85,38 @@ func (h *Handler) getByIdMagazine(c echo.Context) error {
 		return err
 	}
 
+	// get currency query param
+	currency, err := h.parseCurrency(c, "", start)
+	if err != nil {
+		return err
+	}
+
 	// get by id magazine service
 	magazine, err := h.services.Magazine.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to get by id magazine",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	// price conversion into requested currency
+	_, err = h.services.Currency.Localize(c.Request().Context(), currency, time.Now(), &magazine.BaseProduct)
+	if errors.Is(err, repository.ErrRateNotFound) {
+		return c.JSON(http.StatusUnprocessableEntity, ErrParamResponse{
+			Message: "no exchange rate for currency " + currency,
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to get by id magazine",
+		h.requestLogger(c).Error("failed to convert magazine price",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -94,6 +125,15 @@ func (h *Handler) getByIdMagazine(c echo.Context) error {
 		})
 	}
 
+	// converted prices depend on the rate, so they are part of the tag
+	variant := ""
+	if currency != "" {
+		variant = fmt.Sprintf("%s-%.2f", magazine.Currency, magazine.Price)
+	}
+	if setETag(c, etag(magazine.Version, variant)) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
 	resp := dto.FromEntityMagazine(magazine)
 
 	return c.JSON(http.StatusOK, GetByIdMagazineResponse{
@@ -116,7 +156,7 @@ func (h *Handler) updateMagazine(c echo.Context) error {
 
 	// request binding
 	if err = c.Bind(&req); err != nil {
-		h.logger.Error("failed to bind request",
+		h.requestLogger(c).Error("failed to bind request",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -127,7 +167,7 @@ func (h *Handler) updateMagazine(c echo.Context) error {
 
 	// request validation
 	if err = req.Validate(); err != nil {
-		h.logger.Error("validation failed",
+		h.requestLogger(c).Error("validation failed",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -136,10 +176,21 @@ func (h *Handler) updateMagazine(c echo.Context) error {
 		})
 	}
 
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
 	// get by id magazine service
 	magazine, err := h.services.Magazine.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to get by id magazine",
+		h.requestLogger(c).Error("failed to get by id magazine",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -147,13 +198,19 @@ func (h *Handler) updateMagazine(c echo.Context) error {
 			Message: "internal server error",
 		})
 	}
+	if version != 0 && version != magazine.Version {
+		return versionMismatch(c)
+	}
 
 	req.ApplyToEntity(&magazine)
 
 	// update magazine service
 	err = h.services.Magazine.Update(c.Request().Context(), magazine)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
 	if err != nil {
-		h.logger.Error("failed to update magazine",
+		h.requestLogger(c).Error("failed to update magazine",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -166,6 +223,81 @@ func (h *Handler) updateMagazine(c echo.Context) error {
 		Message: "magazine successfully updated",
 	})
 }
+func (h *Handler) patchMagazine(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Patch magazine request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get by id magazine service
+	magazine, err := h.services.Magazine.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to get by id magazine",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+	if version != 0 && version != magazine.Version {
+		return versionMismatch(c)
+	}
+
+	// patch binding
+	doc := dto.NewMagazinePatchDocument(magazine)
+	if err = h.bindPatch(c, &doc, start); err != nil {
+		return err
+	}
+
+	// patched document validation
+	if err = doc.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	doc.ApplyToEntity(&magazine)
+
+	// update magazine service
+	err = h.services.Magazine.Update(c.Request().Context(), magazine)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to patch magazine",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, UpdateMagazineResponse{
+		Message: "magazine successfully patched",
+	})
+}
 func (h *Handler) deleteMagazine(c echo.Context) error {
 	start := time.Now()
 
@@ -177,10 +309,24 @@ func (h *Handler) deleteMagazine(c echo.Context) error {
 		return err
 	}
 
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
 	// delete magazine service
-	err = h.services.Magazine.Delete(c.Request().Context(), id)
+	err = h.services.Magazine.Delete(c.Request().Context(), id, version)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrDeleteByIdResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to delete by id magazine",
+		h.requestLogger(c).Error("failed to delete by id magazine",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"BookStore_API/internal/metrics"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+// observeRequest records every request by its route template, never by the raw path,
+// so ids and unknown paths do not create new series.
+func (h *Handler) observeRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		err := next(c)
+
+		metrics.ObserveRequest(c.Request().Method, routeOf(c, err), responseStatus(c, err), time.Since(start))
+		return err
+	}
+}
+
+// responseStatus is the status the request is answered with, an error returned by the
+// handler is turned into a response only after the middlewares return.
+func responseStatus(c echo.Context, err error) int {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return c.Response().Status
+}
+
+// routeOf returns the route template the request matched, RouteUnmatched if none.
+func routeOf(c echo.Context, err error) string {
+	if c.Path() == "" || errors.Is(err, echo.ErrNotFound) || errors.Is(err, echo.ErrMethodNotAllowed) {
+		return metrics.RouteUnmatched
+	}
+	return c.Path()
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/service"
+	"errors"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -22,6 +26,10 @@ type UpdateOrderResponse struct {
 type DeleteOrderResponse struct {
 	Message string `json:"message"`
 }
+type GetOrderHistoryResponse struct {
+	History []dto.OrderHistoryResponse `json:"history"`
+	Message string                     `json:"message"`
+}
 
 func (h *Handler) createOrder(c echo.Context) error {
 	start := time.Now()
@@ -32,7 +40,7 @@ func (h *Handler) createOrder(c echo.Context) error {
 
 	// request binding
 	if err := c.Bind(&req); err != nil {
-		h.logger.Error("failed to bind request",
+		h.requestLogger(c).Error("failed to bind request",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -41,9 +49,15 @@ func (h *Handler) createOrder(c echo.Context) error {
 		})
 	}
 
+	// currency may also be passed as a query param
+	if req.Currency == "" {
+		req.Currency = c.QueryParam("currency")
+	}
+	req.Currency = strings.ToUpper(req.Currency)
+
 	// request validation
 	if err := req.Validate(); err != nil {
-		h.logger.Error("validation failed",
+		h.requestLogger(c).Error("validation failed",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -56,8 +70,18 @@ func (h *Handler) createOrder(c echo.Context) error {
 
 	// create book service
 	id, err := h.services.Order.Create(c.Request().Context(), order)
+	if errors.Is(err, service.ErrPaidStatusReserved) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if isUnprocessable(err) {
+		return c.JSON(http.StatusUnprocessableEntity, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to create order",
+		h.requestLogger(c).Error("failed to create order",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -84,8 +108,13 @@ func (h *Handler) getByIdOrder(c echo.Context) error {
 
 	// get by id order service
 	order, err := h.services.Order.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrOrderNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to get by id order",
+		h.requestLogger(c).Error("failed to get by id order",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -94,6 +123,27 @@ func (h *Handler) getByIdOrder(c echo.Context) error {
 		})
 	}
 
+	// current products are optional, their prices change without the order version
+	if c.QueryParam("current") == "true" {
+		err = h.services.Order.AnnotateProducts(c.Request().Context(), &order)
+		if errors.Is(err, repository.ErrRateNotFound) {
+			return c.JSON(http.StatusUnprocessableEntity, ErrParamResponse{
+				Message: "no exchange rate for currency " + order.Currency,
+			})
+		}
+		if err != nil {
+			h.requestLogger(c).Error("failed to annotate order products",
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+				Message: "internal server error",
+			})
+		}
+	} else if setETag(c, etag(order.Version, "")) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
 	resp := dto.FromEntityOrder(order)
 
 	return c.JSON(http.StatusOK, GetByIdOrderResponse{
@@ -116,7 +166,7 @@ func (h *Handler) updateOrder(c echo.Context) error {
 
 	// request binding
 	if err = c.Bind(&req); err != nil {
-		h.logger.Error("failed to bind request",
+		h.requestLogger(c).Error("failed to bind request",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -127,7 +177,7 @@ func (h *Handler) updateOrder(c echo.Context) error {
 
 	// request validation
 	if err = req.Validate(); err != nil {
-		h.logger.Error("validation failed",
+		h.requestLogger(c).Error("validation failed",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -136,10 +186,21 @@ func (h *Handler) updateOrder(c echo.Context) error {
 		})
 	}
 
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
 	// get by id order service
 	order, err := h.services.Order.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrOrderNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to get by id order",
+		h.requestLogger(c).Error("failed to get by id order",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -147,13 +208,30 @@ func (h *Handler) updateOrder(c echo.Context) error {
 			Message: "internal server error",
 		})
 	}
+	if version != 0 && version != order.Version {
+		return versionMismatch(c)
+	}
 
 	req.ApplyToEntity(&order)
 
 	// update order service
 	err = h.services.Order.Update(c.Request().Context(), order)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
+	if errors.Is(err, service.ErrTrackingNumberRequired) || errors.Is(err, service.ErrPaidStatusReserved) ||
+		errors.Is(err, service.ErrPaidStatusTransition) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if isUnprocessable(err) {
+		return c.JSON(http.StatusUnprocessableEntity, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to update order",
+		h.requestLogger(c).Error("failed to update order",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -166,6 +244,92 @@ func (h *Handler) updateOrder(c echo.Context) error {
 		Message: "order successfully updated",
 	})
 }
+func (h *Handler) patchOrder(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Patch order request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get by id order service
+	order, err := h.services.Order.GetById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrOrderNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to get by id order",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+	if version != 0 && version != order.Version {
+		return versionMismatch(c)
+	}
+
+	// patch binding
+	doc := dto.NewOrderPatchDocument(order)
+	if err = h.bindPatch(c, &doc, start); err != nil {
+		return err
+	}
+
+	// patched document validation
+	if err = doc.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	doc.ApplyToEntity(&order)
+
+	// update order service
+	err = h.services.Order.Update(c.Request().Context(), order)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
+	if errors.Is(err, service.ErrTrackingNumberRequired) || errors.Is(err, service.ErrPaidStatusReserved) ||
+		errors.Is(err, service.ErrPaidStatusTransition) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if isUnprocessable(err) {
+		return c.JSON(http.StatusUnprocessableEntity, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to patch order",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, UpdateOrderResponse{
+		Message: "order successfully patched",
+	})
+}
 func (h *Handler) deleteOrder(c echo.Context) error {
 	start := time.Now()
 
@@ -177,10 +341,24 @@ func (h *Handler) deleteOrder(c echo.Context) error {
 		return err
 	}
 
+	// expected version
+	version, err := h.parseIfMatch(c, start)
+	if err != nil {
+		return err
+	}
+
 	// delete order service
-	err = h.services.Order.Delete(c.Request().Context(), id)
+	err = h.services.Order.Delete(c.Request().Context(), id, version)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return versionMismatch(c)
+	}
+	if errors.Is(err, repository.ErrOrderNotFound) {
+		return c.JSON(http.StatusNotFound, ErrDeleteByIdResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to delete by id book",
+		h.requestLogger(c).Error("failed to delete by id order",
 			zap.Error(err),
 			zap.Duration("duration", time.Since(start)),
 		)
@@ -193,3 +371,36 @@ func (h *Handler) deleteOrder(c echo.Context) error {
 		Message: "order successfully deleted",
 	})
 }
+func (h *Handler) getOrderHistory(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get order history request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get order history service
+	history, err := h.services.Order.GetHistory(c.Request().Context(), id)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get order history",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.OrderHistoryResponse, len(history))
+	for i, entry := range history {
+		resp[i] = dto.FromEntityOrderHistory(entry)
+	}
+
+	return c.JSON(http.StatusOK, GetOrderHistoryResponse{
+		History: resp,
+		Message: "here is your order history",
+	})
+}
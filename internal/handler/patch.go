@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"BookStore_API/internal/jsonpatch"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// bindPatch applies the request body to doc, which holds the current state of the resource.
+// The body is a JSON Merge Patch or a JSON Patch depending on Content-Type, and the patched
+// document is decoded back into doc, so removed members end up with their zero value.
+func (h *Handler) bindPatch(c echo.Context, doc any, start time.Time) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != jsonpatch.MergePatchMediaType && mediaType != jsonpatch.PatchMediaType {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, ErrBindResponse{
+			Message: "content type must be " + jsonpatch.MergePatchMediaType + " or " + jsonpatch.PatchMediaType,
+		})
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	if mediaType == jsonpatch.MergePatchMediaType {
+		patched, err = jsonpatch.MergePatch(original, body)
+	} else {
+		patched, err = jsonpatch.Apply(original, body)
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to apply patch",
+			zap.String("content_type", mediaType),
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			status = http.StatusConflict
+		case errors.Is(err, jsonpatch.ErrPathNotFound):
+			status = http.StatusUnprocessableEntity
+		}
+		return echo.NewHTTPError(status, ErrBindResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// the document is decoded from scratch, unknown members are rejected
+	v := reflect.ValueOf(doc).Elem()
+	v.Set(reflect.Zero(v.Type()))
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(doc); err != nil {
+		h.requestLogger(c).Error("failed to decode patched document",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return echo.NewHTTPError(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid patched document: " + err.Error(),
+		})
+	}
+	return nil
+}
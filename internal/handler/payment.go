@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/payment"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/service"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+type CreatePaymentResponse struct {
+	Payment dto.PaymentResponse `json:"payment"`
+	Message string              `json:"message"`
+}
+type GetPaymentsResponse struct {
+	Payments []dto.PaymentResponse `json:"payments"`
+	Message  string                `json:"message"`
+}
+type PaymentOperationResponse struct {
+	Payment dto.PaymentResponse `json:"payment"`
+	Message string              `json:"message"`
+}
+type PaymentWebhookResponse struct {
+	Message string `json:"message"`
+}
+
+func (h *Handler) createPayment(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Create payment request started")
+
+	// get order id param
+	orderId, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.PaymentCreateRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// create payment service
+	p, err := h.services.Payment.Create(c.Request().Context(), orderId, req.Token)
+	if errors.Is(err, repository.ErrOrderNotFound) {
+		return c.JSON(http.StatusNotFound, ErrCreateResponse{
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrOrderNotPayable) || errors.Is(err, repository.ErrPaymentInProgress) {
+		return c.JSON(http.StatusConflict, ErrCreateResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to create payment",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	// a declined attempt is still recorded, the client sees it in the status
+	code, msg := http.StatusCreated, "payment created"
+	switch p.Status {
+	case entity.PaymentStatusDeclined, entity.PaymentStatusFailed:
+		code, msg = http.StatusPaymentRequired, "payment declined"
+	case entity.PaymentStatusPending:
+		code, msg = http.StatusAccepted, "payment is being processed"
+	}
+
+	return c.JSON(code, CreatePaymentResponse{
+		Payment: dto.FromEntityPayment(p),
+		Message: msg,
+	})
+}
+func (h *Handler) getPayments(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get payments request started")
+
+	// get order id param
+	orderId, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get payments service
+	payments, err := h.services.Payment.GetByOrderId(c.Request().Context(), orderId)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get payments",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.PaymentResponse, len(payments))
+	for i, p := range payments {
+		resp[i] = dto.FromEntityPayment(p)
+	}
+
+	return c.JSON(http.StatusOK, GetPaymentsResponse{
+		Payments: resp,
+		Message:  "here are your payments",
+	})
+}
+
+func (h *Handler) capturePayment(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Capture payment request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// capture payment service
+	p, err := h.services.Payment.Capture(c.Request().Context(), id)
+	return h.paymentOperationResult(c, p, err, "payment captured", start)
+}
+func (h *Handler) refundPayment(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Refund payment request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.PaymentRefundRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// refund payment service
+	p, err := h.services.Payment.Refund(c.Request().Context(), id, req.Amount)
+	return h.paymentOperationResult(c, p, err, "payment refunded", start)
+}
+func (h *Handler) voidPayment(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Void payment request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// void payment service
+	p, err := h.services.Payment.Void(c.Request().Context(), id)
+	return h.paymentOperationResult(c, p, err, "payment voided", start)
+}
+
+// paymentOperationResult writes the response shared by capture, refund and void.
+func (h *Handler) paymentOperationResult(c echo.Context, p entity.Payment, err error, msg string, start time.Time) error {
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusOK, PaymentOperationResponse{
+			Payment: dto.FromEntityPayment(p),
+			Message: msg,
+		})
+	case errors.Is(err, repository.ErrPaymentNotFound):
+		return c.JSON(http.StatusNotFound, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	case errors.Is(err, service.ErrPaymentStatus):
+		return c.JSON(http.StatusConflict, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	case errors.Is(err, service.ErrRefundExceedsCaptured):
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	h.requestLogger(c).Error("failed payment operation",
+		zap.Error(err),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+		Message: "internal server error",
+	})
+}
+
+func (h *Handler) handlePaymentWebhook(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Payment webhook request started")
+
+	// the raw body is needed to check the signature
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		h.requestLogger(c).Error("failed to read webhook body",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// handle webhook service
+	err = h.services.Payment.HandleWebhook(c.Request().Context(),
+		c.Param("provider"), body, c.Request().Header.Get(payment.SignatureHeader))
+	switch {
+	case errors.Is(err, service.ErrUnknownPaymentProvider):
+		return c.JSON(http.StatusNotFound, ErrParamResponse{
+			Message: err.Error(),
+		})
+	case errors.Is(err, payment.ErrInvalidSignature):
+		return c.JSON(http.StatusUnauthorized, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	case errors.Is(err, repository.ErrPaymentAmountMismatch):
+		return c.JSON(http.StatusUnprocessableEntity, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	case errors.Is(err, repository.ErrPaymentNotFound):
+		// the provider retries, the payment may not be stored yet
+		return c.JSON(http.StatusNotFound, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	case err != nil:
+		h.requestLogger(c).Error("failed to handle payment webhook",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, PaymentWebhookResponse{
+		Message: "webhook received",
+	})
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type GetPricesResponse struct {
+	Prices  dto.PriceTimelineResponse `json:"prices"`
+	Message string                    `json:"message"`
+}
+type SchedulePriceResponse struct {
+	Id      int    `json:"id"`
+	Message string `json:"message"`
+}
+type CancelScheduledPriceResponse struct {
+	Message string `json:"message"`
+}
+
+func (h *Handler) getPrices(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get prices request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get optional 'at' query param
+	var at *time.Time
+	if raw := c.QueryParam("at"); raw != "" {
+		parsed, err := parseTimeQuery(raw)
+		if err != nil {
+			h.requestLogger(c).Error("failed to get query param",
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return c.JSON(http.StatusBadRequest, ErrParamResponse{
+				Message: "invalid 'at' format, expected RFC3339 or YYYY-MM-DD",
+			})
+		}
+		at = &parsed
+	}
+
+	ctx := c.Request().Context()
+
+	// get price history service
+	history, err := h.services.Price.GetHistory(ctx, id)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get price history",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	// get scheduled prices service
+	scheduled, err := h.services.Price.GetSchedules(ctx, id)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get scheduled prices",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := dto.FromEntityPriceTimeline(id, history, scheduled)
+
+	// get price at date service
+	if at != nil {
+		price, err := h.services.Price.GetPriceAt(ctx, id, *at)
+		if errors.Is(err, repository.ErrPriceNotFound) {
+			return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+				Message: err.Error(),
+			})
+		}
+		if err != nil {
+			h.requestLogger(c).Error("failed to get price at date",
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+				Message: "internal server error",
+			})
+		}
+		resp.PriceAt = &price
+	}
+
+	return c.JSON(http.StatusOK, GetPricesResponse{
+		Prices:  resp,
+		Message: "here is your price timeline",
+	})
+}
+func (h *Handler) schedulePrice(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Schedule price request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.ScheduledPriceCreateRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	sp := req.ToEntity(id)
+
+	// schedule price service
+	scheduleId, err := h.services.Price.Schedule(c.Request().Context(), sp)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return c.JSON(http.StatusNotFound, ErrCreateResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to schedule price",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, SchedulePriceResponse{
+		Id:      scheduleId,
+		Message: "price scheduled",
+	})
+}
+func (h *Handler) cancelScheduledPrice(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Cancel scheduled price request started")
+
+	// get id params
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+	scheduleId, err := h.parseIntParam(c, "scheduleId", start)
+	if err != nil {
+		return err
+	}
+
+	// cancel scheduled price service
+	err = h.services.Price.CancelSchedule(c.Request().Context(), id, scheduleId)
+	if errors.Is(err, repository.ErrScheduleNotPending) {
+		return c.JSON(http.StatusConflict, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to cancel scheduled price",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, CancelScheduledPriceResponse{
+		Message: "scheduled price canceled",
+	})
+}
+
+// parseTimeQuery accepts either a full RFC3339 timestamp or a plain date.
+func parseTimeQuery(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/zaplog"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxRequestIdLength = 128
+
+// maxActorLength is the size of audit_log.actor, longer actors are cut to fit.
+const maxActorLength = 255
+
+// requestContext puts the request id, the actor and a logger tagged with both into the
+// request context. The request id is taken from X-Request-ID or generated, and it is sent
+// back in the response. Every request ends with an access log line.
+func (h *Handler) requestContext(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		req := c.Request()
+
+		id := strings.TrimSpace(req.Header.Get(echo.HeaderXRequestID))
+		if id == "" || len(id) > maxRequestIdLength {
+			id = newRequestId()
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+		ctx := reqctx.WithRequestId(req.Context(), id)
+		ctx = reqctx.WithActor(ctx, truncateActor(strings.TrimSpace(req.Header.Get(reqctx.ActorHeader))))
+
+		fields := append([]zap.Field{
+			zap.String("request_id", id),
+			zap.String("route", c.Path()),
+			zap.String("client_ip", c.RealIP()),
+			zap.String("actor", reqctx.Actor(ctx)),
+		}, zaplog.TraceFields(ctx)...)
+		logger := h.logger.With(fields...)
+
+		ctx = reqctx.WithLogger(ctx, logger)
+		c.SetRequest(req.WithContext(ctx))
+
+		err := next(c)
+
+		logger.Info("Request completed",
+			zap.String("method", req.Method),
+			zap.String("path", req.URL.Path),
+			zap.Int("status", responseStatus(c, err)),
+			zap.Int64("bytes", c.Response().Size),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return err
+	}
+}
+
+// truncateActor cuts the actor to maxActorLength characters, the column counts characters
+// and not bytes.
+func truncateActor(actor string) string {
+	if utf8.RuneCountInString(actor) <= maxActorLength {
+		return actor
+	}
+	return string([]rune(actor)[:maxActorLength])
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/service"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type CreateReturnResponse struct {
+	Id      int    `json:"id"`
+	Message string `json:"message"`
+}
+type GetReturnsResponse struct {
+	Returns []dto.ReturnResponse `json:"returns"`
+	Message string               `json:"message"`
+}
+type UpdateReturnResponse struct {
+	Message string `json:"message"`
+}
+type RefundReturnResponse struct {
+	Return  dto.ReturnResponse `json:"return"`
+	Message string             `json:"message"`
+}
+
+func (h *Handler) createReturn(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Create return request started")
+
+	// get order id param
+	orderId, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.ReturnCreateRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// create return service
+	id, err := h.services.Return.Create(c.Request().Context(), req.ToEntity(orderId))
+	if errors.Is(err, service.ErrOrderNotReturnable) {
+		return c.JSON(http.StatusConflict, ErrCreateResponse{
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, repository.ErrReturnQuantity) || errors.Is(err, service.ErrReturnItemDuplicated) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to create return",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, CreateReturnResponse{
+		Id:      id,
+		Message: "return requested",
+	})
+}
+func (h *Handler) getReturns(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get returns request started")
+
+	// get order id param
+	orderId, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get returns service
+	returns, err := h.services.Return.GetByOrderId(c.Request().Context(), orderId)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get returns",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.ReturnResponse, len(returns))
+	for i, ret := range returns {
+		resp[i] = dto.FromEntityReturn(ret)
+	}
+
+	return c.JSON(http.StatusOK, GetReturnsResponse{
+		Returns: resp,
+		Message: "here are your returns",
+	})
+}
+
+func (h *Handler) approveReturn(c echo.Context) error {
+	return h.decideReturn(c, "Approve", "return approved", h.services.Return.Approve)
+}
+func (h *Handler) rejectReturn(c echo.Context) error {
+	return h.decideReturn(c, "Reject", "return rejected", h.services.Return.Reject)
+}
+
+// decideReturn handles the approval step, both outcomes take an optional note.
+func (h *Handler) decideReturn(
+	c echo.Context,
+	action, msg string,
+	decide func(ctx context.Context, id int, note string) error,
+) error {
+	start := time.Now()
+
+	h.logRequestStart(c, action+" return request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.ReturnNoteRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// approve or reject return service
+	err = decide(c.Request().Context(), id, req.Note)
+	if err != nil {
+		return h.returnOperationError(c, err, start)
+	}
+
+	return c.JSON(http.StatusOK, UpdateReturnResponse{
+		Message: msg,
+	})
+}
+func (h *Handler) receiveReturn(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Receive return request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.ReturnReceiveRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// receive return service
+	err = h.services.Return.Receive(c.Request().Context(), id, req.DamagedByProduct(), req.Note)
+	if err != nil {
+		return h.returnOperationError(c, err, start)
+	}
+
+	return c.JSON(http.StatusOK, UpdateReturnResponse{
+		Message: "return received and restocked",
+	})
+}
+func (h *Handler) refundReturn(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Refund return request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.ReturnRefundRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// refund return service
+	ret, err := h.services.Return.Refund(c.Request().Context(), id, req.Amount)
+	if err != nil {
+		return h.returnOperationError(c, err, start)
+	}
+
+	return c.JSON(http.StatusOK, RefundReturnResponse{
+		Return:  dto.FromEntityReturn(ret),
+		Message: "return refunded",
+	})
+}
+
+// returnOperationError maps errors of the return workflow steps to responses.
+func (h *Handler) returnOperationError(c echo.Context, err error, start time.Time) error {
+	switch {
+	case errors.Is(err, repository.ErrReturnNotFound):
+		return c.JSON(http.StatusNotFound, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	case errors.Is(err, repository.ErrReturnStatus),
+		errors.Is(err, service.ErrNoRefundablePayment),
+		errors.Is(err, service.ErrPaymentStatus),
+		errors.Is(err, service.ErrRefundExceedsCaptured):
+		return c.JSON(http.StatusConflict, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	case errors.Is(err, service.ErrDamagedQuantity),
+		errors.Is(err, service.ErrRefundExceedsReturn):
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	h.requestLogger(c).Error("failed return operation",
+		zap.Error(err),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+		Message: "internal server error",
+	})
+}
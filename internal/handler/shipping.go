@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type CreateShippingZoneResponse struct {
+	Id      int    `json:"id"`
+	Message string `json:"message"`
+}
+type GetShippingZonesResponse struct {
+	Zones   []dto.ShippingZoneResponse `json:"zones"`
+	Message string                     `json:"message"`
+}
+type DeleteShippingZoneResponse struct {
+	Message string `json:"message"`
+}
+type CreateShippingMethodResponse struct {
+	Id      int    `json:"id"`
+	Message string `json:"message"`
+}
+type DeleteShippingMethodResponse struct {
+	Message string `json:"message"`
+}
+type ShippingQuoteResponse struct {
+	Quotes  []dto.ShippingQuoteResponse `json:"quotes"`
+	Message string                      `json:"message"`
+}
+
+func (h *Handler) createShippingZone(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Create shipping zone request started")
+
+	var req dto.ShippingZoneCreateRequest
+
+	// request binding
+	if err := c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err := req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	zone := req.ToEntity()
+
+	// create shipping zone service
+	id, err := h.services.Shipping.CreateZone(c.Request().Context(), zone)
+	if err != nil {
+		h.requestLogger(c).Error("failed to create shipping zone",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, CreateShippingZoneResponse{
+		Id:      id,
+		Message: "shipping zone created",
+	})
+}
+func (h *Handler) getShippingZones(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get shipping zones request started")
+
+	// get shipping zones service
+	zones, err := h.services.Shipping.GetZones(c.Request().Context())
+	if err != nil {
+		h.requestLogger(c).Error("failed to get shipping zones",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.ShippingZoneResponse, len(zones))
+	for i, zone := range zones {
+		resp[i] = dto.FromEntityShippingZone(zone)
+	}
+
+	return c.JSON(http.StatusOK, GetShippingZonesResponse{
+		Zones:   resp,
+		Message: "here are your shipping zones",
+	})
+}
+func (h *Handler) deleteShippingZone(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Delete shipping zone request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// delete shipping zone service
+	err = h.services.Shipping.DeleteZone(c.Request().Context(), id)
+	if err != nil {
+		h.requestLogger(c).Error("failed to delete shipping zone",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrDeleteByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, DeleteShippingZoneResponse{
+		Message: "shipping zone successfully deleted",
+	})
+}
+
+func (h *Handler) createShippingMethod(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Create shipping method request started")
+
+	// get zone id param
+	zoneId, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.ShippingMethodCreateRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	method := req.ToEntity(zoneId)
+
+	// create shipping method service
+	id, err := h.services.Shipping.CreateMethod(c.Request().Context(), method)
+	if err != nil {
+		h.requestLogger(c).Error("failed to create shipping method",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, CreateShippingMethodResponse{
+		Id:      id,
+		Message: "shipping method created",
+	})
+}
+func (h *Handler) deleteShippingMethod(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Delete shipping method request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// delete shipping method service
+	err = h.services.Shipping.DeleteMethod(c.Request().Context(), id)
+	if err != nil {
+		h.requestLogger(c).Error("failed to delete shipping method",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrDeleteByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, DeleteShippingMethodResponse{
+		Message: "shipping method successfully deleted",
+	})
+}
+
+func (h *Handler) quoteShipping(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Shipping quote request started")
+
+	var req dto.ShippingQuoteRequest
+
+	// request binding
+	if err := c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err := req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	ctx := c.Request().Context()
+
+	items := req.ItemsToEntity()
+	dest := req.Destination.ToEntity()
+	currency := req.Currency
+
+	// draft order items, destination and currency are used unless given explicitly
+	if req.OrderId != nil {
+		order, err := h.services.Order.GetById(ctx, *req.OrderId)
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+				Message: err.Error(),
+			})
+		}
+		if err != nil {
+			h.requestLogger(c).Error("failed to get by id order",
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+				Message: "internal server error",
+			})
+		}
+
+		if len(items) == 0 {
+			items = make([]entity.OrderItem, len(order.Items))
+			for i, item := range order.Items {
+				items[i] = entity.OrderItem{Product: entity.BaseProduct{Id: item.Product.Id}, Quantity: item.Quantity}
+			}
+		}
+		if dest.Country == "" {
+			dest = order.Destination
+		}
+		if currency == "" {
+			currency = order.Currency
+		}
+	}
+
+	// shipping quote service
+	quotes, err := h.services.Shipping.Quote(ctx, dest, items, currency)
+	if isUnprocessable(err) {
+		return c.JSON(http.StatusUnprocessableEntity, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to quote shipping",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.ShippingQuoteResponse, len(quotes))
+	for i, q := range quotes {
+		resp[i] = dto.FromEntityShippingQuote(q)
+	}
+
+	return c.JSON(http.StatusOK, ShippingQuoteResponse{
+		Quotes:  resp,
+		Message: "here is your shipping quote",
+	})
+}
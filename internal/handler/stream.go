@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/outbox"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamRetry is the reconnection delay in milliseconds suggested to clients.
+const streamRetry = 3000
+
+func (h *Handler) streamEvents(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Event stream request started")
+
+	// orders are placed without a customer, so events can not be selected by one
+	if c.QueryParams().Has("customerId") {
+		return c.JSON(http.StatusBadRequest, ErrParamResponse{
+			Message: "filtering by customer is not supported, orders are not linked to customers",
+		})
+	}
+
+	// get filter params
+	var filter entity.EventFilter
+	var err error
+	if filter.OrderIds, err = h.parseIdsQuery(c, "orderId", start); err != nil {
+		return err
+	}
+	if filter.ProductIds, err = h.parseIdsQuery(c, "productId", start); err != nil {
+		return err
+	}
+
+	// resume after the last event the client received
+	lastEventId := c.Request().Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.QueryParam("lastEventId")
+	}
+	var afterId int64
+	if lastEventId != "" {
+		afterId, err = strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil || afterId < 0 {
+			h.requestLogger(c).Error("invalid last event id",
+				zap.String("last_event_id", lastEventId),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return c.JSON(http.StatusBadRequest, ErrParamResponse{
+				Message: "invalid Last-Event-ID format",
+			})
+		}
+	}
+
+	// subscribe before replaying, so nothing is missed in between
+	events, unsubscribe := h.services.Stream.Subscribe(filter)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if _, err = fmt.Fprintf(res, "retry: %d\n\n", streamRetry); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	ctx := c.Request().Context()
+
+	// replayed events may be delivered live as well
+	replayed := make(map[int64]struct{})
+	if lastEventId != "" {
+		err = h.services.Stream.Replay(ctx, afterId, filter, func(e entity.OutboxEvent) error {
+			replayed[e.Id] = struct{}{}
+			return writeEvent(res, e)
+		})
+		if err != nil {
+			h.requestLogger(c).Error("failed to replay events",
+				zap.Int64("after_id", afterId),
+				zap.Error(err),
+				zap.Duration("duration", time.Since(start)),
+			)
+			return nil
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.requestLogger(c).Info("Event stream closed", zap.Duration("duration", time.Since(start)))
+			return nil
+		case <-h.closing:
+			h.requestLogger(c).Info("Event stream closed for shutdown", zap.Duration("duration", time.Since(start)))
+			return nil
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case e, ok := <-events:
+			// the client fell behind, it resumes from its last event after reconnecting
+			if !ok {
+				h.requestLogger(c).Warn("Event stream subscriber dropped", zap.Duration("duration", time.Since(start)))
+				return nil
+			}
+			if _, ok = replayed[e.Id]; ok {
+				continue
+			}
+			if err = writeEvent(res, e); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// writeEvent sends the event in the envelope used by the outbox sinks.
+func writeEvent(res *echo.Response, e entity.OutboxEvent) error {
+	body, err := outbox.Encode(e)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.EventType, body); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// parseIdsQuery reads ids given as repeated or comma separated query params.
+func (h *Handler) parseIdsQuery(c echo.Context, name string, start time.Time) ([]int, error) {
+	var ids []int
+	for _, param := range c.QueryParams()[name] {
+		for _, raw := range strings.Split(param, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil || id <= 0 {
+				h.requestLogger(c).Error("failed to get query param",
+					zap.String("param", name),
+					zap.String("value", raw),
+					zap.Duration("duration", time.Since(start)),
+				)
+				return nil, echo.NewHTTPError(http.StatusBadRequest, ErrParamResponse{
+					Message: "invalid " + name + " format",
+				})
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type CreateTaxRuleResponse struct {
+	Id      int    `json:"id"`
+	Message string `json:"message"`
+}
+type GetTaxRulesResponse struct {
+	Rules   []dto.TaxRuleResponse `json:"rules"`
+	Message string                `json:"message"`
+}
+type DeleteTaxRuleResponse struct {
+	Message string `json:"message"`
+}
+
+func (h *Handler) createTaxRule(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Create tax rule request started")
+
+	var req dto.TaxRuleCreateRequest
+
+	// request binding
+	if err := c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err := req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	rule := req.ToEntity()
+
+	// create tax rule service
+	id, err := h.services.Tax.CreateRule(c.Request().Context(), rule)
+	if err != nil {
+		h.requestLogger(c).Error("failed to create tax rule",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, CreateTaxRuleResponse{
+		Id:      id,
+		Message: "tax rule saved",
+	})
+}
+func (h *Handler) getTaxRules(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get tax rules request started")
+
+	country := strings.ToUpper(c.QueryParam("country"))
+
+	// get tax rules service
+	rules, err := h.services.Tax.GetRules(c.Request().Context(), country)
+	if err != nil {
+		h.requestLogger(c).Error("failed to get tax rules",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.TaxRuleResponse, len(rules))
+	for i, rule := range rules {
+		resp[i] = dto.FromEntityTaxRule(rule)
+	}
+
+	return c.JSON(http.StatusOK, GetTaxRulesResponse{
+		Rules:   resp,
+		Message: "here are your tax rules",
+	})
+}
+func (h *Handler) deleteTaxRule(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Delete tax rule request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// delete tax rule service
+	err = h.services.Tax.DeleteRule(c.Request().Context(), id)
+	if err != nil {
+		h.requestLogger(c).Error("failed to delete tax rule",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrDeleteByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, DeleteTaxRuleResponse{
+		Message: "tax rule successfully deleted",
+	})
+}
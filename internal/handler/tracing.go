@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/tracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// traceRequest opens the server span of the request, continuing the trace of the caller
+// when the request carries a traceparent header.
+func (h *Handler) traceRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+
+		// a made up method is named OTHER like in the metrics, the attribute keeps it as sent
+		method := metrics.Method(req.Method)
+		methodAttrs := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(method)}
+		if method == metrics.MethodOther {
+			methodAttrs = []attribute.KeyValue{semconv.HTTPRequestMethodOther, semconv.HTTPRequestMethodOriginal(req.Method)}
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracing.Tracer().Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(methodAttrs...),
+			trace.WithAttributes(
+				semconv.URLPath(req.URL.Path),
+				semconv.ClientAddress(c.RealIP()),
+			),
+		)
+		defer span.End()
+
+		c.SetRequest(req.WithContext(ctx))
+
+		err := next(c)
+
+		route := routeOf(c, err)
+		status := responseStatus(c, err)
+
+		span.SetName(method + " " + route)
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+		)
+		if status >= http.StatusInternalServerError {
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
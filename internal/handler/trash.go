@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type GetTrashResponse struct {
+	Items   []dto.TrashItemResponse `json:"items"`
+	Message string                  `json:"message"`
+}
+type RestoreResponse struct {
+	Message string `json:"message"`
+}
+
+func (h *Handler) getTrash(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get trash request started")
+
+	// get trash service
+	items, err := h.services.Trash.GetAll(c.Request().Context())
+	if err != nil {
+		h.requestLogger(c).Error("failed to get trash",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.TrashItemResponse, len(items))
+	for i, item := range items {
+		resp[i] = dto.FromEntityTrashItem(item)
+	}
+
+	return c.JSON(http.StatusOK, GetTrashResponse{
+		Items:   resp,
+		Message: "here is your trash",
+	})
+}
+func (h *Handler) restoreProduct(c echo.Context) error {
+	return h.restore(c, "product", h.services.Trash.RestoreProduct)
+}
+func (h *Handler) restoreOrder(c echo.Context) error {
+	return h.restore(c, "order", h.services.Trash.RestoreOrder)
+}
+
+// restore takes a product or an order out of the trash.
+func (h *Handler) restore(c echo.Context, kind string, restore func(ctx context.Context, id int) error) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Restore "+kind+" request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// restore service
+	err = restore(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotInTrash) {
+		return c.JSON(http.StatusNotFound, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to restore "+kind,
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, RestoreResponse{
+		Message: kind + " restored",
+	})
+}
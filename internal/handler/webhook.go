@@ -0,0 +1,359 @@
+package handler
+
+import (
+	"BookStore_API/internal/dto"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/service"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type CreateWebhookResponse struct {
+	Webhook dto.WebhookEndpointResponse `json:"webhook"`
+	Message string                      `json:"message"`
+}
+type GetWebhooksResponse struct {
+	Webhooks []dto.WebhookEndpointResponse `json:"webhooks"`
+	Message  string                        `json:"message"`
+}
+type GetWebhookResponse struct {
+	Webhook dto.WebhookEndpointResponse `json:"webhook"`
+	Message string                      `json:"message"`
+}
+type UpdateWebhookResponse struct {
+	Message string `json:"message"`
+}
+type DeleteWebhookResponse struct {
+	Message string `json:"message"`
+}
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []dto.WebhookDeliveryResponse `json:"deliveries"`
+	Limit      int                           `json:"limit"`
+	Offset     int                           `json:"offset"`
+	Message    string                        `json:"message"`
+}
+type RedeliverWebhookResponse struct {
+	Id      int64  `json:"id"`
+	Message string `json:"message"`
+}
+
+func (h *Handler) createWebhook(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Create webhook request started")
+
+	var req dto.WebhookEndpointCreateRequest
+
+	// request binding
+	if err := c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err := req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// create webhook service
+	endpoint, err := h.services.Webhook.CreateEndpoint(c.Request().Context(), req.ToEntity())
+	if errors.Is(err, service.ErrWebhookURLNotAllowed) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to create webhook",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	// the secret is shown only here, the receiver needs it to verify signatures
+	resp := dto.FromEntityWebhookEndpoint(endpoint)
+	resp.Secret = endpoint.Secret
+
+	return c.JSON(http.StatusCreated, CreateWebhookResponse{
+		Webhook: resp,
+		Message: "webhook created",
+	})
+}
+func (h *Handler) getWebhooks(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get webhooks request started")
+
+	// get webhooks service
+	endpoints, err := h.services.Webhook.GetEndpoints(c.Request().Context())
+	if err != nil {
+		h.requestLogger(c).Error("failed to get webhooks",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.WebhookEndpointResponse, len(endpoints))
+	for i, endpoint := range endpoints {
+		resp[i] = dto.FromEntityWebhookEndpoint(endpoint)
+	}
+
+	return c.JSON(http.StatusOK, GetWebhooksResponse{
+		Webhooks: resp,
+		Message:  "here are your webhooks",
+	})
+}
+func (h *Handler) getByIdWebhook(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get webhook request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// get webhook service
+	endpoint, err := h.services.Webhook.GetEndpointById(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrWebhookEndpointNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to get webhook",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, GetWebhookResponse{
+		Webhook: dto.FromEntityWebhookEndpoint(endpoint),
+		Message: "here is your webhook",
+	})
+}
+func (h *Handler) updateWebhook(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Update webhook request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.WebhookEndpointUpdateRequest
+
+	// request binding
+	if err = c.Bind(&req); err != nil {
+		h.requestLogger(c).Error("failed to bind request",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrBindResponse{
+			Message: "invalid request body",
+		})
+	}
+
+	// request validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	// update webhook service
+	err = h.services.Webhook.UpdateEndpoint(c.Request().Context(), req.ToEntity(id))
+	if errors.Is(err, repository.ErrWebhookEndpointNotFound) {
+		return c.JSON(http.StatusNotFound, ErrUpdateResponse{
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrWebhookURLNotAllowed) {
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to update webhook",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrUpdateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, UpdateWebhookResponse{
+		Message: "webhook successfully updated",
+	})
+}
+func (h *Handler) deleteWebhook(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Delete webhook request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	// delete webhook service
+	err = h.services.Webhook.DeleteEndpoint(c.Request().Context(), id)
+	if errors.Is(err, repository.ErrWebhookEndpointNotFound) {
+		return c.JSON(http.StatusNotFound, ErrDeleteByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to delete webhook",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrDeleteByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, DeleteWebhookResponse{
+		Message: "webhook successfully deleted",
+	})
+}
+
+func (h *Handler) getWebhookDeliveries(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Get webhook deliveries request started")
+
+	// get id param
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+
+	var req dto.WebhookDeliveryQueryRequest
+
+	// query params binding
+	if err = (&echo.DefaultBinder{}).BindQueryParams(c, &req); err != nil {
+		h.requestLogger(c).Error("failed to bind query params",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrParamResponse{
+			Message: "invalid query params",
+		})
+	}
+
+	// query params validation
+	if err = req.Validate(); err != nil {
+		h.requestLogger(c).Error("validation failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusBadRequest, ErrValidationResponse{
+			Message: err.Error(),
+		})
+	}
+
+	filter := req.ToFilter(id)
+
+	// get webhook deliveries service
+	deliveries, err := h.services.Webhook.GetDeliveries(c.Request().Context(), filter)
+	if errors.Is(err, repository.ErrWebhookEndpointNotFound) {
+		return c.JSON(http.StatusNotFound, ErrGetByIdResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to get webhook deliveries",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrGetByIdResponse{
+			Message: "internal server error",
+		})
+	}
+
+	resp := make([]dto.WebhookDeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		resp[i] = dto.FromEntityWebhookDelivery(d)
+	}
+
+	return c.JSON(http.StatusOK, GetWebhookDeliveriesResponse{
+		Deliveries: resp,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+		Message:    "here are your webhook deliveries",
+	})
+}
+func (h *Handler) redeliverWebhook(c echo.Context) error {
+	start := time.Now()
+
+	h.logRequestStart(c, "Redeliver webhook request started")
+
+	// get id params
+	id, err := h.parseIdParam(c, start)
+	if err != nil {
+		return err
+	}
+	deliveryId, err := h.parseIntParam(c, "deliveryId", start)
+	if err != nil {
+		return err
+	}
+
+	// redeliver webhook service
+	newId, err := h.services.Webhook.Redeliver(c.Request().Context(), id, int64(deliveryId))
+	if errors.Is(err, repository.ErrWebhookEndpointNotFound) || errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+		return c.JSON(http.StatusNotFound, ErrCreateResponse{
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrWebhookEndpointDisabled) {
+		return c.JSON(http.StatusConflict, ErrCreateResponse{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		h.requestLogger(c).Error("failed to redeliver webhook",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrCreateResponse{
+			Message: "internal server error",
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, RedeliverWebhookResponse{
+		Id:      newId,
+		Message: "webhook delivery queued",
+	})
+}
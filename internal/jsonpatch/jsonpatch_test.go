@@ -0,0 +1,212 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "add null value",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/b","value":null}]`,
+			want:  `{"a":1,"b":null}`,
+		},
+		{
+			name:  "replace with null value",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"/a","value":null}]`,
+			want:  `{"a":null}`,
+		},
+		{
+			name:  "test null value",
+			doc:   `{"a":null}`,
+			patch: `[{"op":"test","path":"/a","value":null}]`,
+			want:  `{"a":null}`,
+		},
+		{
+			name:  "missing value",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/b"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "escaped slash",
+			doc:   `{"a/b":1}`,
+			patch: `[{"op":"replace","path":"/a~1b","value":2}]`,
+			want:  `{"a/b":2}`,
+		},
+		{
+			name:  "escaped tilde",
+			doc:   `{"m~n":1}`,
+			patch: `[{"op":"test","path":"/m~0n","value":1},{"op":"remove","path":"/m~0n"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "tilde escape is not decoded twice",
+			doc:   `{"~1":1}`,
+			patch: `[{"op":"remove","path":"/~01"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "append with dash index",
+			doc:   `{"list":[1,2]}`,
+			patch: `[{"op":"add","path":"/list/-","value":3}]`,
+			want:  `{"list":[1,2,3]}`,
+		},
+		{
+			name:  "dash index is not an element",
+			doc:   `{"list":[1,2]}`,
+			patch: `[{"op":"replace","path":"/list/-","value":3}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "insert into array",
+			doc:   `{"list":[1,3]}`,
+			patch: `[{"op":"add","path":"/list/1","value":2}]`,
+			want:  `{"list":[1,2,3]}`,
+		},
+		{
+			name:  "index out of range",
+			doc:   `{"list":[1]}`,
+			patch: `[{"op":"add","path":"/list/2","value":2}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "index with leading zero",
+			doc:   `{"list":[1,2]}`,
+			patch: `[{"op":"remove","path":"/list/01"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move into a child path",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"move","from":"/a","path":"/a/c"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move to a sibling with a common prefix",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"move","from":"/a","path":"/ab"}]`,
+			want:  `{"ab":1}`,
+		},
+		{
+			name:  "move out of an object",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"move","from":"/a/b","path":"/c"}]`,
+			want:  `{"a":{},"c":1}`,
+		},
+		{
+			name:  "copy is independent",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			want:  `{"a":{"b":1},"c":{"b":2}}`,
+		},
+		{
+			name:  "failed test fails the patch",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/b","value":2},{"op":"test","path":"/a","value":2}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "failed operation fails the patch",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":"/a"},{"op":"remove","path":"/missing"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "unknown op",
+			doc:   `{}`,
+			patch: `[{"op":"merge","path":"/a","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				// a failed patch returns no partial document
+				if got != nil {
+					t.Fatalf("document = %s, want none", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "null removes a member",
+			doc:   `{"a":1,"b":2}`,
+			patch: `{"a":null}`,
+			want:  `{"b":2}`,
+		},
+		{
+			name:  "objects are merged",
+			doc:   `{"a":{"b":1,"c":2}}`,
+			patch: `{"a":{"c":3,"d":4}}`,
+			want:  `{"a":{"b":1,"c":3,"d":4}}`,
+		},
+		{
+			name:  "arrays are replaced",
+			doc:   `{"list":[1,2,3]}`,
+			patch: `{"list":[4]}`,
+			want:  `{"list":[4]}`,
+		},
+		{
+			name:  "non-object patch replaces the document",
+			doc:   `{"a":1}`,
+			patch: `[1]`,
+			want:  `[1]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid expectation %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Fatalf("document = %s, want %s", got, want)
+	}
+}
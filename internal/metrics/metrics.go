@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "bookstore"
+
+// RouteUnmatched labels requests that matched no route, so unknown paths do not add series.
+const RouteUnmatched = "unmatched"
+
+// MethodOther labels requests with a method outside the standard ones, so made up methods
+// do not add series.
+const MethodOther = "OTHER"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	repositoryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_operation_duration_seconds",
+		Help:      "Latency of successful repository operations.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"repository", "operation"})
+	repositoryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "repository_errors_total",
+		Help:      "Failed database operations by operation and reason (timeout or error).",
+	}, []string{"operation", "reason"})
+
+	ordersByStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "orders",
+		Help:      "Orders by status, excluding deleted ones.",
+	}, []string{"status"})
+	lowStockProducts = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "low_stock_products",
+		Help:      "Products with stock at or below the low stock threshold.",
+	})
+)
+
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	method = Method(method)
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// Method returns the method for a label, MethodOther if it is not a standard HTTP method.
+func Method(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return MethodOther
+}
+
+func ObserveRepositoryOperation(repository, operation string, start time.Time) {
+	repositoryDuration.WithLabelValues(repository, operation).Observe(time.Since(start).Seconds())
+}
+
+func RepositoryError(operation, reason string) {
+	repositoryErrors.WithLabelValues(operation, reason).Inc()
+}
+
+func SetOrdersByStatus(counts map[string]int) {
+	for status, count := range counts {
+		ordersByStatus.WithLabelValues(status).Set(float64(count))
+	}
+}
+
+func SetLowStockProducts(count int) {
+	lowStockProducts.Set(float64(count))
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the pgx pool stats on every scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	maxConns         *prometheus.Desc
+	totalConns       *prometheus.Desc
+	idleConns        *prometheus.Desc
+	acquiredConns    *prometheus.Desc
+	acquires         *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	acquireWait      *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+	idleDestroyed    *prometheus.Desc
+}
+
+// RegisterPool exposes the stats of the pool.
+func RegisterPool(pool *pgxpool.Pool) error {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return prometheus.Register(&poolCollector{
+		pool:             pool,
+		maxConns:         desc("max_conns", "Maximum size of the pool."),
+		totalConns:       desc("total_conns", "Connections currently in the pool."),
+		idleConns:        desc("idle_conns", "Idle connections in the pool."),
+		acquiredConns:    desc("acquired_conns", "Connections currently in use."),
+		acquires:         desc("acquires_total", "Successful acquires from the pool."),
+		emptyAcquires:    desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		acquireWait:      desc("acquire_wait_seconds_total", "Total time spent acquiring connections."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires canceled by their context."),
+		idleDestroyed:    desc("idle_destroyed_total", "Connections closed for being idle too long."),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxConns
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.acquiredConns
+	ch <- c.acquires
+	ch <- c.emptyAcquires
+	ch <- c.acquireWait
+	ch <- c.canceledAcquires
+	ch <- c.idleDestroyed
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.idleDestroyed, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()))
+}
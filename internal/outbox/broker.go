@@ -0,0 +1,165 @@
+package outbox
+
+import (
+	"BookStore_API/internal/entity"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// brokerConnect is sent after the server INFO, verbose is off so only errors are answered.
+const brokerConnect = `CONNECT {"verbose":false,"pedantic":false,"name":"bookstore-outbox","lang":"go","version":"1.0.0","protocol":0}` + "\r\n"
+
+var ErrBrokerProtocol = errors.New("unexpected broker response")
+
+// BrokerSink publishes events to a NATS compatible broker using the plain text protocol.
+// Every event goes to <subject>.<event type>; a PING is sent after the PUB and the PONG,
+// which the server sends only after processing the PUB, acknowledges the event.
+type BrokerSink struct {
+	addr    string
+	subject string
+	timeout time.Duration
+	logger  *zap.Logger
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+func NewBrokerSink(addr, subject string, timeout time.Duration, logger *zap.Logger) *BrokerSink {
+	return &BrokerSink{
+		addr:    addr,
+		subject: subject,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+func (s *BrokerSink) Name() string {
+	return SinkBroker
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, event entity.OutboxEvent) error {
+	body, err := Encode(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err = s.connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect to broker: %w", err)
+		}
+	}
+
+	if err = s.publish(ctx, s.subject+"."+event.EventType, body); err != nil {
+		// the connection state is unknown, the next event reconnects
+		s.closeConn()
+		return err
+	}
+	return nil
+}
+
+func (s *BrokerSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closeConn()
+}
+
+func (s *BrokerSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+	s.setDeadline(ctx)
+
+	// the server greets with INFO before anything else
+	line, err := s.readLine()
+	if err == nil && !strings.HasPrefix(line, "INFO") {
+		err = fmt.Errorf("%w: %q", ErrBrokerProtocol, line)
+	}
+	if err == nil {
+		_, err = conn.Write([]byte(brokerConnect))
+	}
+	if err != nil {
+		s.closeConn()
+		return err
+	}
+
+	s.logger.Info("Connected to outbox broker", zap.String("address", s.addr))
+	return nil
+}
+
+func (s *BrokerSink) publish(ctx context.Context, subject string, body []byte) error {
+	s.setDeadline(ctx)
+
+	msg := make([]byte, 0, len(body)+len(subject)+32)
+	msg = fmt.Appendf(msg, "PUB %s %d\r\n", subject, len(body))
+	msg = append(msg, body...)
+	msg = append(msg, "\r\nPING\r\n"...)
+
+	if _, err := s.conn.Write(msg); err != nil {
+		return err
+	}
+
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			// keepalive from the server
+			if _, err = s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("broker error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case line == "+OK", strings.HasPrefix(line, "INFO"):
+		default:
+			return fmt.Errorf("%w: %q", ErrBrokerProtocol, line)
+		}
+	}
+}
+
+func (s *BrokerSink) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setDeadline bounds the next reads and writes by the sink timeout or the context deadline.
+func (s *BrokerSink) setDeadline(ctx context.Context) {
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = s.conn.SetDeadline(deadline)
+}
+
+func (s *BrokerSink) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	s.reader = nil
+	return err
+}
@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"BookStore_API/internal/entity"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every event by the HTTP sink.
+const (
+	EventIdHeader   = "X-Event-Id"
+	EventTypeHeader = "X-Event-Type"
+)
+
+// HTTPSink posts every event as JSON to a single URL, any 2xx answer acknowledges it.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *HTTPSink) Name() string {
+	return SinkHTTP
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, event entity.OutboxEvent) error {
+	body, err := Encode(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIdHeader, strconv.FormatInt(event.Id, 10))
+	req.Header.Set(EventTypeHeader, event.EventType)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("event rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *HTTPSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
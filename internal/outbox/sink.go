@@ -0,0 +1,93 @@
+package outbox
+
+import (
+	"BookStore_API/internal/config"
+	"BookStore_API/internal/entity"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+// Sink names accepted in OUTBOX_SINKS.
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkHTTP   = "http"
+	SinkBroker = "broker"
+)
+
+// Sink delivers events to another system. Publish returns only after the event is accepted,
+// the dispatcher retries it otherwise, so a sink can see the same event more than once.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event entity.OutboxEvent) error
+	Close() error
+}
+
+// Message is the representation of an event sent to every sink. Id is unique
+// and lets consumers drop redelivered events.
+type Message struct {
+	Id            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregateType"`
+	AggregateId   int             `json:"aggregateId"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func Encode(event entity.OutboxEvent) ([]byte, error) {
+	return json.Marshal(Message{
+		Id:            event.Id,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateId:   event.AggregateId,
+		OccurredAt:    event.CreatedAt,
+		Payload:       event.Payload,
+	})
+}
+
+// NewSinks builds the sinks selected in config.
+func NewSinks(cfg config.OutboxConfig, logger *zap.Logger) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+	for _, name := range cfg.Sinks {
+		switch name {
+		case SinkStdout:
+			sinks = append(sinks, NewWriterSink(SinkStdout, os.Stdout))
+		case SinkFile:
+			f, err := os.OpenFile(cfg.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+			if err != nil {
+				CloseAll(sinks, logger)
+				return nil, fmt.Errorf("failed to open outbox file: %w", err)
+			}
+			sinks = append(sinks, NewWriterSink(SinkFile, f))
+		case SinkHTTP:
+			if cfg.HTTPURL == "" {
+				CloseAll(sinks, logger)
+				return nil, fmt.Errorf("outbox http sink requires OUTBOX_HTTP_URL")
+			}
+			sinks = append(sinks, NewHTTPSink(cfg.HTTPURL, cfg.Timeout))
+		case SinkBroker:
+			sinks = append(sinks, NewBrokerSink(cfg.BrokerAddr, cfg.Subject, cfg.Timeout, logger))
+		case "":
+		default:
+			CloseAll(sinks, logger)
+			return nil, fmt.Errorf("unknown outbox sink: %s", name)
+		}
+	}
+	return sinks, nil
+}
+
+// CloseAll closes the sinks, errors are only logged.
+func CloseAll(sinks []Sink, logger *zap.Logger) {
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			logger.Error("failed to close outbox sink",
+				zap.String("sink", sink.Name()),
+				zap.Error(err),
+			)
+		}
+	}
+}
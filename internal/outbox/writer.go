@@ -0,0 +1,53 @@
+package outbox
+
+import (
+	"BookStore_API/internal/entity"
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink writes every event as a line of JSON, to stdout or to a file.
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{
+		name: name,
+		w:    w,
+	}
+}
+
+func (s *WriterSink) Name() string {
+	return s.name
+}
+
+func (s *WriterSink) Publish(ctx context.Context, event entity.OutboxEvent) error {
+	line, err := Encode(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err = s.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	// a file is synced so an acknowledged event survives a crash
+	if f, ok := s.w.(*os.File); ok && f != os.Stdout {
+		return f.Sync()
+	}
+	return nil
+}
+
+func (s *WriterSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package payment
+
+import (
+	"BookStore_API/internal/entity"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const FakeProviderName = "fake"
+
+// Tokens understood by the fake provider. Any other token is authorized at once.
+const (
+	FakeTokenDecline      = "tok_decline"
+	FakeTokenDelay        = "tok_delay"
+	FakeTokenDelayDecline = "tok_delay_decline"
+)
+
+var ErrFakePaymentNotFound = errors.New("fake payment not found")
+
+type FakeOptions struct {
+	// CallbackURL receives signed webhooks for delayed payments.
+	CallbackURL string
+	Secret      string
+	// Delay is the time between a delayed authorization and its webhook.
+	Delay time.Duration
+	// Duplicates is the number of extra deliveries of every webhook.
+	Duplicates int
+}
+
+// FakeProvider is an in-memory gateway for tests and local development. It can
+// simulate declines, payments settled later by a webhook and duplicate callbacks.
+type FakeProvider struct {
+	opts     FakeOptions
+	client   *http.Client
+	logger   *zap.Logger
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	status   string
+	amount   float64
+	refunded float64
+}
+
+func NewFakeProvider(opts FakeOptions, logger *zap.Logger) *FakeProvider {
+	return &FakeProvider{
+		opts:     opts,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		logger:   logger,
+		payments: make(map[string]*fakePayment),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ref := p.nextId("pay")
+	approved := entity.PaymentStatusAuthorized
+	if req.Capture {
+		approved = entity.PaymentStatusCaptured
+	}
+
+	switch req.Token {
+	case FakeTokenDecline:
+		p.payments[ref] = &fakePayment{status: entity.PaymentStatusDeclined, amount: req.Amount}
+		return Result{ProviderRef: ref, Status: entity.PaymentStatusDeclined, FailureReason: "card declined"}, nil
+	case FakeTokenDelay, FakeTokenDelayDecline:
+		final := approved
+		if req.Token == FakeTokenDelayDecline {
+			final = entity.PaymentStatusDeclined
+		}
+		p.payments[ref] = &fakePayment{status: entity.PaymentStatusPending, amount: req.Amount}
+		go p.settleLater(ref, final)
+		return Result{ProviderRef: ref, Status: entity.PaymentStatusPending}, nil
+	default:
+		p.payments[ref] = &fakePayment{status: approved, amount: req.Amount}
+		return Result{ProviderRef: ref, Status: approved}, nil
+	}
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, providerRef string, amount float64) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fp, err := p.get(providerRef, entity.PaymentStatusAuthorized)
+	if err != nil {
+		return Result{}, err
+	}
+	if amount > fp.amount {
+		return Result{}, fmt.Errorf("capture amount %.2f exceeds authorized %.2f", amount, fp.amount)
+	}
+
+	fp.status = entity.PaymentStatusCaptured
+	fp.amount = amount
+	return Result{ProviderRef: providerRef, Status: fp.status}, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, providerRef string, amount float64) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fp, err := p.get(providerRef, entity.PaymentStatusCaptured, entity.PaymentStatusPartiallyRefunded)
+	if err != nil {
+		return Result{}, err
+	}
+	if fp.refunded+amount > fp.amount {
+		return Result{}, fmt.Errorf("refund amount %.2f exceeds captured %.2f", fp.refunded+amount, fp.amount)
+	}
+
+	fp.refunded += amount
+	fp.status = entity.PaymentStatusPartiallyRefunded
+	if fp.refunded >= fp.amount {
+		fp.status = entity.PaymentStatusRefunded
+	}
+	return Result{ProviderRef: providerRef, Status: fp.status}, nil
+}
+
+func (p *FakeProvider) Void(ctx context.Context, providerRef string) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fp, err := p.get(providerRef, entity.PaymentStatusPending, entity.PaymentStatusAuthorized)
+	if err != nil {
+		return Result{}, err
+	}
+
+	fp.status = entity.PaymentStatusVoided
+	return Result{ProviderRef: providerRef, Status: fp.status}, nil
+}
+
+// get returns the payment by reference if it is in one of the given statuses.
+func (p *FakeProvider) get(ref string, statuses ...string) (*fakePayment, error) {
+	fp, ok := p.payments[ref]
+	if !ok {
+		return nil, ErrFakePaymentNotFound
+	}
+	for _, status := range statuses {
+		if fp.status == status {
+			return fp, nil
+		}
+	}
+	return nil, fmt.Errorf("fake payment %s is %s", ref, fp.status)
+}
+
+func (p *FakeProvider) nextId(prefix string) string {
+	p.seq++
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().UnixNano(), p.seq)
+}
+
+// settleLater moves a pending payment to its final status and reports it with a webhook.
+func (p *FakeProvider) settleLater(ref, status string) {
+	time.Sleep(p.opts.Delay)
+
+	p.mu.Lock()
+	fp := p.payments[ref]
+	if fp.status != entity.PaymentStatusPending {
+		// voided while waiting
+		p.mu.Unlock()
+		return
+	}
+	fp.status = status
+	event := WebhookEvent{
+		EventId:     p.nextId("evt"),
+		ProviderRef: ref,
+		Status:      status,
+		Amount:      fp.amount,
+	}
+	p.mu.Unlock()
+
+	for i := 0; i <= p.opts.Duplicates; i++ {
+		if err := p.deliver(event); err != nil {
+			p.logger.Error("fake provider webhook delivery failed",
+				zap.String("event_id", event.EventId),
+				zap.String("provider_ref", ref),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func (p *FakeProvider) deliver(event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.opts.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(p.opts.Secret, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package payment
+
+import (
+	"BookStore_API/internal/config"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of a webhook body.
+const SignatureHeader = "X-Payment-Signature"
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+type AuthorizeRequest struct {
+	PaymentId int
+	OrderId   int
+	Amount    float64
+	Currency  string
+	// Token identifies the payment method collected by the client, e.g. a tokenized card.
+	Token string
+	// Capture requests the funds to be captured right after a successful authorization.
+	Capture bool
+}
+
+// Result is the provider answer to an operation. Status is one of entity.PaymentStatus*;
+// pending means the final status will arrive with a webhook.
+type Result struct {
+	ProviderRef   string
+	Status        string
+	FailureReason string
+}
+
+// WebhookEvent is the body of a provider callback.
+type WebhookEvent struct {
+	EventId     string  `json:"eventId"`
+	ProviderRef string  `json:"providerRef"`
+	Status      string  `json:"status"`
+	Amount      float64 `json:"amount"`
+}
+
+// PaymentProvider is a payment gateway integration.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, providerRef string, amount float64) (Result, error)
+	Refund(ctx context.Context, providerRef string, amount float64) (Result, error)
+	Void(ctx context.Context, providerRef string) (Result, error)
+}
+
+// NewProvider builds the payment provider selected in config.
+func NewProvider(cfg config.PaymentConfig, logger *zap.Logger) (PaymentProvider, error) {
+	switch cfg.Provider {
+	case FakeProviderName:
+		return NewFakeProvider(FakeOptions{
+			CallbackURL: cfg.FakeCallbackURL,
+			Secret:      cfg.WebhookSecret,
+			Delay:       cfg.FakeDelay,
+			Duplicates:  cfg.FakeDuplicates,
+		}, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider: %s", cfg.Provider)
+	}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of body in constant time.
+func Verify(secret string, body []byte, signature string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
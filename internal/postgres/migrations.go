@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"BookStore_API/migrations"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// MigrationStatus is the schema version of the database against the migrations of this build.
+type MigrationStatus struct {
+	// Version is the migration applied last, 0 if none.
+	Version uint
+	Dirty   bool
+	Latest  uint
+	// Pending lists the versions not applied yet, in order.
+	Pending []uint
+}
+
+// Migrator applies the embedded migrations. golang-migrate holds a Postgres advisory lock
+// while migrating, so replicas migrating on start take turns instead of racing.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// NewMigrator migrates through a connection of the pool, lockTimeout bounds the wait for
+// a migration run by another replica.
+func NewMigrator(pool *pgxpool.Pool, lockTimeout time.Duration, logger *zap.Logger) (*Migrator, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	driver, err := pgxmigrate.WithInstance(stdlib.OpenDBFromPool(pool), &pgxmigrate.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "pgx5", driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
+	}
+	m.LockTimeout = lockTimeout
+	m.Log = migrateLogger{logger: logger}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down reverts the given number of migrations.
+func (m *Migrator) Down(steps int) error {
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+// Goto migrates up or down to the given version.
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force sets the version without migrating, to recover from a dirty failed migration.
+func (m *Migrator) Force(version uint) error {
+	return m.m.Force(int(version))
+}
+
+func (m *Migrator) Status() (MigrationStatus, error) {
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return MigrationStatus{}, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	versions, err := MigrationVersions()
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	status := MigrationStatus{Version: version, Dirty: dirty}
+	for _, v := range versions {
+		status.Latest = max(status.Latest, v)
+		if v > version {
+			status.Pending = append(status.Pending, v)
+		}
+	}
+	return status, nil
+}
+
+// Check fails with ErrSchemaBehind if migrations of this build are not applied or the last
+// one failed; a newer schema, migrated by a newer build, is accepted.
+func (m *Migrator) Check() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w: migration %d failed and left the schema dirty", ErrSchemaBehind, status.Version)
+	}
+	if status.Version < status.Latest {
+		return fmt.Errorf("%w: version %d, expected %d", ErrSchemaBehind, status.Version, status.Latest)
+	}
+	return nil
+}
+
+// Close releases the connection of the migrator, the pool stays open.
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// LatestMigrationVersion returns the highest version among the embedded migrations.
+func LatestMigrationVersion() (uint, error) {
+	versions, err := MigrationVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, v := range versions {
+		latest = max(latest, v)
+	}
+	return latest, nil
+}
+
+// MigrationVersions lists the versions of the embedded up migrations in order.
+func MigrationVersions() ([]uint, error) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []uint
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, uint(version))
+	}
+	return versions, nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// migrateLogger writes the progress of golang-migrate to the application logger.
+type migrateLogger struct {
+	logger *zap.Logger
+}
+
+func (l migrateLogger) Printf(format string, v ...any) {
+	l.logger.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
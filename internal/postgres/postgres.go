@@ -2,30 +2,171 @@ package postgres
 
 import (
 	"BookStore_API/internal/config"
+	"BookStore_API/internal/tracing"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"time"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 // products table sql queries
 const (
-	InsertProductsSQL = `INSERT INTO products (type, name, price, stock, created_at)
-						 VALUES ($1, $2, $3, $4, $5)
+	InsertProductsSQL = `INSERT INTO products (type, name, price, stock, tax_class, weight_grams, created_at)
+						 VALUES ($1, $2, $3, $4, $5, $6, $7)
 						 RETURNING id`
-	GetByIdProductsSQL = `SELECT id, type, name, price, stock, created_at
+	GetByIdProductsSQL = `SELECT id, type, name, price, stock, tax_class, weight_grams, version, created_at
 						  FROM products
-						  WHERE id = $1`
-	UpdateProductsSQL = `UPDATE products
+						  WHERE id = $1 AND deleted_at IS NULL`
+	// UpdateProductsSQL updates the product only if it still has the expected version $7,
+	// returning the price and stock it had before.
+	UpdateProductsSQL = `UPDATE products p
 						 SET name = $2,
 						 	 price = $3,
-						 	 stock = $4
-						 WHERE id = $1`
-	DeleteByIdProductsSQL = `DELETE FROM products
-							 WHERE id = $1`
-	GetByIdsProductsSQL = `SELECT id, type, name, price, stock, created_at
+						 	 stock = $4,
+						 	 tax_class = $5,
+						 	 weight_grams = $6,
+						 	 version = p.version + 1
+						 FROM (
+						 	 SELECT id, price, stock
+						 	 FROM products
+						 	 WHERE id = $1
+						 	 FOR UPDATE
+						 ) prev
+						 WHERE p.id = prev.id AND p.version = $7
+						 RETURNING prev.price, prev.stock`
+	// DeleteByIdProductsSQL moves the product of type $4 to the trash if it has version $2,
+	// any version if $2 is 0.
+	DeleteByIdProductsSQL = `UPDATE products
+							 SET deleted_at = $3,
+							 	 version = version + 1
+							 WHERE id = $1 AND type = $4 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+	ExistsProductsSQL = `SELECT EXISTS (
+							 SELECT 1
+							 FROM products
+							 WHERE id = $1 AND type = $2 AND deleted_at IS NULL
+						 )`
+	// GetByIdsProductsSQL includes deleted products, orders keep referring to them.
+	GetByIdsProductsSQL = `SELECT id, type, name, price, stock, tax_class, weight_grams, version, created_at, deleted_at
 						  FROM products
 						  WHERE id = ANY($1)`
+	GetDeletedProductsSQL = `SELECT id, type, name, deleted_at
+							 FROM products
+							 WHERE deleted_at IS NOT NULL
+							 ORDER BY deleted_at DESC, id`
+	RestoreProductsSQL = `UPDATE products
+						  SET deleted_at = NULL,
+						  	  version = version + 1
+						  WHERE id = $1 AND deleted_at IS NOT NULL`
+	// PurgeProductsSQL deletes products in the trash since before $1 unless an order or return refers to them.
+	PurgeProductsSQL = `DELETE FROM products p
+						WHERE p.deleted_at < $1
+						  AND NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.product_id = p.id)
+						  AND NOT EXISTS (SELECT 1 FROM return_items ri WHERE ri.product_id = p.id)`
+	GetPriceForUpdateProductsSQL = `SELECT price
+									FROM products
+									WHERE id = $1
+									FOR UPDATE`
+	UpdatePriceProductsSQL = `UPDATE products
+							  SET price = $2,
+							  	  version = version + 1
+							  WHERE id = $1`
+	RestockProductsSQL = `UPDATE products
+						  SET stock = stock + $2,
+							  damaged_stock = damaged_stock + $3,
+							  version = version + 1
+						  WHERE id = $1
+						  RETURNING stock`
+	CountLowStockProductsSQL = `SELECT COUNT(*)
+								FROM products
+								WHERE deleted_at IS NULL AND stock <= $1`
+)
+
+// price_history table sql queries
+const (
+	// InsertPriceHistorySQL records a price only if it differs from the latest recorded one,
+	// so it is safe to call on every product write.
+	InsertPriceHistorySQL = `INSERT INTO price_history (product_id, price, source, changed_at)
+							 SELECT $1::int, $2::numeric, $3::varchar, $4::timestamptz
+							 WHERE $2::numeric IS DISTINCT FROM (
+								 SELECT price
+								 FROM price_history
+								 WHERE product_id = $1
+								 ORDER BY changed_at DESC, id DESC
+								 LIMIT 1
+							 )`
+	GetByProductIdPriceHistorySQL = `SELECT id, product_id, price, source, changed_at
+									 FROM price_history
+									 WHERE product_id = $1
+									 ORDER BY changed_at, id`
+	GetPriceAtPriceHistorySQL = `SELECT price
+								 FROM price_history
+								 WHERE product_id = $1 AND changed_at <= $2
+								 ORDER BY changed_at DESC, id DESC
+								 LIMIT 1`
+)
+
+// scheduled_prices table sql queries
+const (
+	InsertScheduledPricesSQL = `INSERT INTO scheduled_prices (product_id, price, starts_at, ends_at, status, created_at)
+								VALUES ($1, $2, $3, $4, $5, $6)
+								RETURNING id`
+	GetByProductIdScheduledPricesSQL = `SELECT id, product_id, price, previous_price, starts_at, ends_at, status, created_at
+										FROM scheduled_prices
+										WHERE product_id = $1
+										ORDER BY starts_at, id`
+	CancelScheduledPricesSQL = `UPDATE scheduled_prices
+								SET status = 'canceled'
+								WHERE id = $1 AND product_id = $2 AND status = 'pending'`
+	GetDueToStartScheduledPricesSQL = `SELECT id, product_id, price, ends_at
+									   FROM scheduled_prices
+									   WHERE status = 'pending' AND starts_at <= $1
+									   ORDER BY starts_at, id
+									   FOR UPDATE SKIP LOCKED`
+	GetDueToEndScheduledPricesSQL = `SELECT id, product_id, price, previous_price
+									 FROM scheduled_prices
+									 WHERE status = 'active' AND ends_at <= $1
+									 ORDER BY ends_at, id
+									 FOR UPDATE SKIP LOCKED`
+	UpdateStatusScheduledPricesSQL = `UPDATE scheduled_prices
+									  SET status = $2,
+									      previous_price = COALESCE($3, previous_price)
+									  WHERE id = $1`
+)
+
+// exchange_rates table sql queries
+const (
+	InsertExchangeRatesSQL = `INSERT INTO exchange_rates (currency, rate, effective_from, created_at)
+							  VALUES ($1, $2, $3, $4)
+							  RETURNING id`
+	GetExchangeRatesSQL = `SELECT id, currency, rate, effective_from, created_at
+						   FROM exchange_rates
+						   WHERE $1 = '' OR currency = $1
+						   ORDER BY currency, effective_from DESC`
+	GetRateAtExchangeRatesSQL = `SELECT rate
+								 FROM exchange_rates
+								 WHERE currency = $1 AND effective_from <= $2
+								 ORDER BY effective_from DESC, id DESC
+								 LIMIT 1`
+)
+
+// product_prices table sql queries
+const (
+	UpsertProductPricesSQL = `INSERT INTO product_prices (product_id, currency, price, updated_at)
+							  VALUES ($1, $2, $3, $4)
+							  ON CONFLICT (product_id, currency) DO UPDATE
+							  SET price = EXCLUDED.price, updated_at = EXCLUDED.updated_at`
+	DeleteProductPricesSQL = `DELETE FROM product_prices
+							  WHERE product_id = $1 AND currency = $2`
+	GetByProductIdProductPricesSQL = `SELECT product_id, currency, price, updated_at
+									  FROM product_prices
+									  WHERE product_id = $1
+									  ORDER BY currency`
+	GetByProductIdsProductPricesSQL = `SELECT product_id, currency, price, updated_at
+									   FROM product_prices
+									   WHERE product_id = ANY($1) AND currency = $2`
 )
 
 // books table sql queries
@@ -39,8 +180,6 @@ const (
 					  SET author = $2,
 					      isbn = $3
 					  WHERE product_id = $1`
-	DeleteByIdBooksSQL = `DELETE FROM books
-				  		  WHERE product_id = $1`
 	ExistsIsbnBooksSQL = `SELECT EXISTS (
 							  SELECT 1
 							  FROM books
@@ -60,8 +199,6 @@ const (
 						  SET issue_number = $2,
 						  	  publication_date = $3
 						  WHERE product_id = $1`
-	DeleteByIdMagazinesSQL = `DELETE FROM magazines
-							  WHERE product_id = $1`
 	ExistsIssueNumberMagazinesSQL = `SELECT EXISTS (
 										 SELECT 1
 										 FROM magazines
@@ -71,50 +208,494 @@ const (
 )
 
 const (
-	InsertOrdersSQL = `INSERT INTO orders (status, created_at)
-				 	   VALUES ($1, $2)
+	InsertOrdersSQL = `INSERT INTO orders (status, currency, exchange_rate, shipping_country, shipping_region, tax_mode,
+										   shipping_method, shipping_cost, created_at)
+				 	   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 				 	   RETURNING id`
-	GetByIdOrdersSQL = `SELECT status, created_at
+	GetByIdOrdersSQL = `SELECT status, currency, exchange_rate, shipping_country, shipping_region, tax_mode,
+							   shipping_method, shipping_cost, tracking_number, version, created_at
 						FROM orders
-						WHERE id = $1`
-	UpdateOrdersSQL = `UPDATE orders
-					   SET status = $2
-					   WHERE id = $1`
-	DeleteByIdOrdersSQL = `DELETE FROM orders
-						   WHERE id = $1`
+						WHERE id = $1 AND deleted_at IS NULL`
+	// UpdateOrdersSQL updates the order only if it still has the expected version $4,
+	// returning the status it had before.
+	UpdateOrdersSQL = `UPDATE orders o
+					   SET status = $2,
+					       tracking_number = $3,
+					       version = o.version + 1
+					   FROM (
+					       SELECT id, status
+					       FROM orders
+					       WHERE id = $1
+					       FOR UPDATE
+					   ) prev
+					   WHERE o.id = prev.id AND o.version = $4
+					   RETURNING prev.status`
+	// DeleteByIdOrdersSQL moves the order to the trash if it has version $2, any version if $2 is 0.
+	DeleteByIdOrdersSQL = `UPDATE orders
+						   SET deleted_at = $3,
+						   	   version = version + 1
+						   WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+	GetDeletedOrdersSQL = `SELECT id, status, deleted_at
+						   FROM orders
+						   WHERE deleted_at IS NOT NULL
+						   ORDER BY deleted_at DESC, id`
+	RestoreOrdersSQL = `UPDATE orders
+						SET deleted_at = NULL,
+							version = version + 1
+						WHERE id = $1 AND deleted_at IS NOT NULL`
+	PurgeOrdersSQL = `DELETE FROM orders
+					  WHERE deleted_at < $1`
+	// MarkPaidOrdersSQL moves an order to paid unless it is already further along,
+	// returning the status it had before.
+	MarkPaidOrdersSQL = `UPDATE orders o
+						 SET status = 'paid',
+						 	 version = o.version + 1
+						 FROM (
+						 	 SELECT id, status
+						 	 FROM orders
+						 	 WHERE id = $1
+						 	 FOR UPDATE
+						 ) prev
+						 WHERE o.id = prev.id AND prev.status IN ('created', 'accepted', 'pending')
+						 RETURNING prev.status`
+	ExistsOrdersSQL = `SELECT EXISTS (
+						   SELECT 1
+						   FROM orders
+						   WHERE id = $1 AND deleted_at IS NULL
+					   )`
+	LockOrdersSQL = `SELECT id
+					 FROM orders
+					 WHERE id = $1
+					 FOR UPDATE`
+	CountByStatusOrdersSQL = `SELECT status, COUNT(*)
+							  FROM orders
+							  WHERE deleted_at IS NULL
+							  GROUP BY status`
 )
 
+// order_history table sql queries
 const (
-	InsertOrderItemsSQL = `INSERT INTO order_items (order_id, product_id, quantity, price)
-						   VALUES ($1, $2, $3, $4)`
+	InsertOrderHistorySQL = `INSERT INTO order_history (order_id, event, details, created_at)
+							 VALUES ($1, $2, $3, $4)`
+	GetByOrderIdOrderHistorySQL = `SELECT id, order_id, event, details, created_at
+								   FROM order_history
+								   WHERE order_id = $1
+								   ORDER BY created_at, id`
+)
+
+const (
+	// InsertOrderItemsSQL snapshots the product name, type and ISBN or issue number with the item.
+	InsertOrderItemsSQL = `INSERT INTO order_items (order_id, product_id, quantity, price, tax_class, tax_rate, tax_amount,
+												   product_name, product_type, isbn, issue_number)
+						   SELECT $1, p.id, $3, $4, $5, $6, $7, p.name, p.type, b.isbn, m.issue_number
+						   FROM products p
+						   LEFT JOIN books b ON b.product_id = p.id
+						   LEFT JOIN magazines m ON m.product_id = p.id
+						   WHERE p.id = $2`
 
-	GetByOrderIdOrderItemsSQL = `SELECT product_id, quantity, price
+	GetByOrderIdOrderItemsSQL = `SELECT product_id, quantity, price, tax_class, tax_rate, tax_amount,
+										product_name, product_type, COALESCE(isbn, ''), COALESCE(issue_number, 0)
 								 FROM order_items
 								 WHERE order_id = $1`
-	UpsertOrderItemsSQL = `INSERT INTO order_items (order_id, product_id, quantity, price)
-						   VALUES ($1, $2, $3, $4)
-						   ON CONFLICT (order_id, product_id) DO UPDATE
-						   SET quantity = EXCLUDED.quantity, price = EXCLUDED.price;`
-	DeleteByOrderIdOrderItemsSQL = `DELETE FROM order_items
-								    WHERE order_id = $1 AND product_id <> ALL($2)`
-	ExistsOrderItemsWithOrderId = `SELECT COUNT(*)
-								   FROM order_items
-								   WHERE order_id = $1`
+	UpdateOrderItemsSQL = `UPDATE order_items
+						   SET quantity = $3, price = $4, tax_class = $5, tax_rate = $6, tax_amount = $7
+						   WHERE order_id = $1 AND product_id = $2`
+	DeleteByProductIdsOrderItemsSQL = `DELETE FROM order_items
+									   WHERE order_id = $1 AND product_id = ANY($2)`
+)
+
+// shipping_zones table sql queries
+const (
+	InsertShippingZonesSQL = `INSERT INTO shipping_zones (name, countries, created_at)
+							  VALUES ($1, $2, $3)
+							  RETURNING id`
+	GetShippingZonesSQL = `SELECT id, name, countries, created_at
+						   FROM shipping_zones
+						   ORDER BY id`
+	// GetForCountryShippingZonesSQL prefers a zone listing the country over a catch-all one.
+	GetForCountryShippingZonesSQL = `SELECT id, name, countries, created_at
+									 FROM shipping_zones
+									 WHERE $1 = ANY(countries) OR '*' = ANY(countries)
+									 ORDER BY ($1 = ANY(countries)) DESC, id
+									 LIMIT 1`
+	DeleteByIdShippingZonesSQL = `DELETE FROM shipping_zones
+								  WHERE id = $1`
+)
+
+// shipping_methods table sql queries
+const (
+	InsertShippingMethodsSQL = `INSERT INTO shipping_methods (zone_id, code, name, basis, free_threshold, created_at)
+								VALUES ($1, $2, $3, $4, $5, $6)
+								RETURNING id`
+	GetByZoneIdsShippingMethodsSQL = `SELECT id, zone_id, code, name, basis, free_threshold, created_at
+									  FROM shipping_methods
+									  WHERE zone_id = ANY($1)
+									  ORDER BY zone_id, id`
+	DeleteByIdShippingMethodsSQL = `DELETE FROM shipping_methods
+									WHERE id = $1`
+)
+
+// shipping_rates table sql queries
+const (
+	InsertShippingRatesSQL = `INSERT INTO shipping_rates (method_id, min_value, price)
+							  VALUES ($1, $2, $3)`
+	GetByMethodIdsShippingRatesSQL = `SELECT method_id, min_value, price
+									  FROM shipping_rates
+									  WHERE method_id = ANY($1)
+									  ORDER BY method_id, min_value`
+)
+
+// order_tax_lines table sql queries
+const (
+	InsertOrderTaxLinesSQL = `INSERT INTO order_tax_lines (order_id, tax_class, rate, taxable_amount, tax_amount)
+							  VALUES ($1, $2, $3, $4, $5)`
+	GetByOrderIdOrderTaxLinesSQL = `SELECT tax_class, rate, taxable_amount, tax_amount
+									FROM order_tax_lines
+									WHERE order_id = $1
+									ORDER BY tax_class, rate`
+	DeleteByOrderIdOrderTaxLinesSQL = `DELETE FROM order_tax_lines
+									   WHERE order_id = $1`
+)
+
+// tax_rules table sql queries
+const (
+	InsertTaxRulesSQL = `INSERT INTO tax_rules (country, region, tax_class, rate, created_at)
+						 VALUES ($1, $2, $3, $4, $5)
+						 ON CONFLICT (country, region, tax_class) DO UPDATE
+						 SET rate = EXCLUDED.rate
+						 RETURNING id`
+	GetTaxRulesSQL = `SELECT id, country, region, tax_class, rate, created_at
+					  FROM tax_rules
+					  WHERE $1 = '' OR country = $1
+					  ORDER BY country, region, tax_class`
+	GetForDestinationTaxRulesSQL = `SELECT id, country, region, tax_class, rate, created_at
+									FROM tax_rules
+									WHERE country = $1 AND (region = '' OR region = $2)`
+	DeleteByIdTaxRulesSQL = `DELETE FROM tax_rules
+							 WHERE id = $1`
+)
+
+// payments table sql queries
+const (
+	// HasOpenPaymentsSQL tells whether the order has a payment that is not declined, failed or voided.
+	HasOpenPaymentsSQL = `SELECT EXISTS (
+							  SELECT 1
+							  FROM payments
+							  WHERE order_id = $1 AND status NOT IN ('declined', 'failed', 'voided')
+						  )`
+	InsertPaymentsSQL = `INSERT INTO payments (order_id, provider, status, amount, currency, created_at, updated_at)
+						 VALUES ($1, $2, $3, $4, $5, $6, $6)
+						 RETURNING id`
+	GetByIdPaymentsSQL = `SELECT id, order_id, provider, COALESCE(provider_ref, ''), status, amount, currency,
+								 refunded_amount, failure_reason, created_at, updated_at
+						  FROM payments
+						  WHERE id = $1`
+	GetByOrderIdPaymentsSQL = `SELECT id, order_id, provider, COALESCE(provider_ref, ''), status, amount, currency,
+									  refunded_amount, failure_reason, created_at, updated_at
+							   FROM payments
+							   WHERE order_id = $1
+							   ORDER BY created_at, id`
+	GetByProviderRefForUpdatePaymentsSQL = `SELECT id, order_id, provider, COALESCE(provider_ref, ''), status, amount, currency,
+												   refunded_amount, failure_reason, created_at, updated_at
+											FROM payments
+											WHERE provider = $1 AND provider_ref = $2
+											FOR UPDATE`
+	// ReserveRefundPaymentsSQL adds a refund to the payment before the provider is called, only
+	// while it stays within the captured amount, so concurrent refunds can not exceed it.
+	ReserveRefundPaymentsSQL = `UPDATE payments
+								SET refunded_amount = refunded_amount + $2::numeric,
+									status = CASE WHEN refunded_amount + $2::numeric >= amount
+												  THEN 'refunded' ELSE 'partially_refunded' END,
+									updated_at = $3
+								WHERE id = $1 AND status IN ('captured', 'partially_refunded')
+									AND refunded_amount + $2::numeric <= amount
+								RETURNING id, order_id, provider, COALESCE(provider_ref, ''), status, amount, currency,
+										  refunded_amount, failure_reason, created_at, updated_at`
+	// ReleaseRefundPaymentsSQL takes back a reserved refund the provider did not make.
+	ReleaseRefundPaymentsSQL = `UPDATE payments
+								SET refunded_amount = refunded_amount - $2::numeric,
+									status = CASE WHEN refunded_amount - $2::numeric <= 0
+												  THEN 'captured' ELSE 'partially_refunded' END,
+									updated_at = $3
+								WHERE id = $1`
+	UpdatePaymentsSQL = `UPDATE payments
+						 SET provider_ref = NULLIF($2, ''),
+							 status = $3,
+							 refunded_amount = $4,
+							 failure_reason = $5,
+							 updated_at = $6
+						 WHERE id = $1`
+)
+
+// payment_events table sql queries
+const (
+	// InsertPaymentEventsSQL returns no rows for an already received event.
+	InsertPaymentEventsSQL = `INSERT INTO payment_events (provider, event_id, payment_id, status, payload, received_at)
+							  VALUES ($1, $2, $3, $4, $5, $6)
+							  ON CONFLICT (provider, event_id) DO NOTHING
+							  RETURNING id`
+)
+
+// returns table sql queries
+const (
+	InsertReturnsSQL = `INSERT INTO returns (order_id, status, reason, refund_amount, created_at, updated_at)
+						VALUES ($1, $2, $3, $4, $5, $5)
+						RETURNING id`
+	GetByIdReturnsSQL = `SELECT id, order_id, status, reason, note, refund_amount, refunded_amount, payment_id,
+								created_at, updated_at
+						 FROM returns
+						 WHERE id = $1`
+	GetByOrderIdReturnsSQL = `SELECT id, order_id, status, reason, note, refund_amount, refunded_amount, payment_id,
+									 created_at, updated_at
+							  FROM returns
+							  WHERE order_id = $1
+							  ORDER BY created_at, id`
+	// UpdateStatusReturnsSQL moves a return on only from the expected status, keeping the note if none is given.
+	UpdateStatusReturnsSQL = `UPDATE returns
+							  SET status = $3,
+								  note = CASE WHEN $4 = '' THEN note ELSE $4 END,
+								  updated_at = $5
+							  WHERE id = $1 AND status = $2
+							  RETURNING order_id`
+	RefundReturnsSQL = `UPDATE returns
+						SET status = 'refunded',
+							refunded_amount = $2,
+							payment_id = $3,
+							updated_at = $4
+						WHERE id = $1 AND status = 'refunding'
+						RETURNING order_id`
+)
+
+// return_items table sql queries
+const (
+	InsertReturnItemsSQL = `INSERT INTO return_items (return_id, product_id, quantity, price, tax_amount)
+							VALUES ($1, $2, $3, $4, $5)`
+	GetByReturnIdsReturnItemsSQL = `SELECT return_id, product_id, quantity, damaged, price, tax_amount
+									FROM return_items
+									WHERE return_id = ANY($1)
+									ORDER BY return_id, product_id`
+	UpdateDamagedReturnItemsSQL = `UPDATE return_items
+								   SET damaged = $3
+								   WHERE return_id = $1 AND product_id = $2`
+	// GetReturnedQuantitiesReturnItemsSQL sums quantities of all returns of the order that were not rejected.
+	GetReturnedQuantitiesReturnItemsSQL = `SELECT ri.product_id, SUM(ri.quantity)
+										   FROM return_items ri
+										   JOIN returns r ON r.id = ri.return_id
+										   WHERE r.order_id = $1 AND r.status <> 'rejected'
+										   GROUP BY ri.product_id`
+)
+
+// idempotency_keys table sql queries
+const (
+	// DeleteStaleIdempotencyKeysSQL frees a key that expired or whose request never finished.
+	DeleteStaleIdempotencyKeysSQL = `DELETE FROM idempotency_keys
+									 WHERE key = $1 AND route = $2
+									   AND (expires_at <= $3 OR (status = 'in_progress' AND created_at <= $4))`
+	// InsertIdempotencyKeysSQL returns no rows if the key is already taken.
+	InsertIdempotencyKeysSQL = `INSERT INTO idempotency_keys (key, route, request_hash, status, created_at, expires_at)
+								VALUES ($1, $2, $3, 'in_progress', $4, $5)
+								ON CONFLICT (key, route) DO NOTHING
+								RETURNING created_at`
+	GetIdempotencyKeysSQL = `SELECT key, route, request_hash, status, response_status, response_body, created_at, expires_at
+							 FROM idempotency_keys
+							 WHERE key = $1 AND route = $2`
+	CompleteIdempotencyKeysSQL = `UPDATE idempotency_keys
+								  SET status = 'completed',
+									  response_status = $3,
+									  response_body = $4
+								  WHERE key = $1 AND route = $2`
+	DeleteIdempotencyKeysSQL = `DELETE FROM idempotency_keys
+								WHERE key = $1 AND route = $2`
+	DeleteExpiredIdempotencyKeysSQL = `DELETE FROM idempotency_keys
+									   WHERE expires_at <= $1`
+)
+
+// audit_log table sql queries
+const (
+	InsertAuditLogSQL = `INSERT INTO audit_log (actor, request_id, entity_type, entity_id, action, changes, created_at)
+						 VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	// GetAuditLogSQL filters by entity type, entity id, actor, action and a time range, empty values match all.
+	GetAuditLogSQL = `SELECT id, actor, request_id, entity_type, entity_id, action, changes, created_at
+					  FROM audit_log
+					  WHERE ($1 = '' OR entity_type = $1)
+						AND ($2 = 0 OR entity_id = $2)
+						AND ($3 = '' OR actor = $3)
+						AND ($4 = '' OR action = $4)
+						AND ($5::timestamp IS NULL OR created_at >= $5)
+						AND ($6::timestamp IS NULL OR created_at < $6)
+					  ORDER BY created_at DESC, id DESC
+					  LIMIT $7 OFFSET $8`
+)
+
+// outbox table sql queries
+const (
+	InsertOutboxSQL = `INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, created_at, next_attempt_at)
+					   VALUES ($1, $2, $3, $4, $5, $5)`
+	// ClaimOutboxSQL leases up to $3 due events until $2. Only the oldest undelivered event
+	// of an aggregate can be taken, so events of one aggregate are delivered in order.
+	ClaimOutboxSQL = `UPDATE outbox
+					  SET attempts = attempts + 1,
+					  	  next_attempt_at = $2
+					  WHERE id IN (
+					  	  SELECT o.id
+					  	  FROM outbox o
+					  	  WHERE o.dispatched_at IS NULL AND o.next_attempt_at <= $1
+					  	    AND NOT EXISTS (
+					  	  	    SELECT 1
+					  	  	    FROM outbox e
+					  	  	    WHERE e.dispatched_at IS NULL
+					  	  	      AND e.aggregate_type = o.aggregate_type
+					  	  	      AND e.aggregate_id = o.aggregate_id
+					  	  	      AND e.id < o.id
+					  	    )
+					  	  ORDER BY o.id
+					  	  LIMIT $3
+					  	  FOR UPDATE SKIP LOCKED
+					  )
+					  RETURNING id, aggregate_type, aggregate_id, event_type, payload, attempts, created_at`
+	MarkDispatchedOutboxSQL = `UPDATE outbox
+							   SET dispatched_at = $2,
+							   	   last_error = NULL
+							   WHERE id = $1`
+	MarkFailedOutboxSQL = `UPDATE outbox
+						   SET next_attempt_at = $2,
+						   	   last_error = $3
+						   WHERE id = $1`
+	PurgeOutboxSQL = `DELETE FROM outbox
+					  WHERE dispatched_at < $1`
+	GetByIdOutboxSQL = `SELECT id, aggregate_type, aggregate_id, event_type, payload, attempts, created_at
+						FROM outbox
+						WHERE id = $1`
+	GetAfterOutboxSQL = `SELECT id, aggregate_type, aggregate_id, event_type, payload, attempts, created_at
+						 FROM outbox
+						 WHERE id > $1
+						 ORDER BY id
+						 LIMIT $2`
+	// ListenOutboxSQL subscribes to the ids of new events, sent by the outbox_notify trigger.
+	ListenOutboxSQL = `LISTEN outbox_events`
+)
+
+// webhook_endpoints table sql queries
+const (
+	InsertWebhookEndpointsSQL = `INSERT INTO webhook_endpoints (url, secret, events, created_at, updated_at)
+								 VALUES ($1, $2, $3, $4, $4)
+								 RETURNING id`
+	GetAllWebhookEndpointsSQL = `SELECT id, url, secret, events, enabled, consecutive_failures, disabled_at, created_at, updated_at
+								 FROM webhook_endpoints
+								 ORDER BY id`
+	GetByIdWebhookEndpointsSQL = `SELECT id, url, secret, events, enabled, consecutive_failures, disabled_at, created_at, updated_at
+								  FROM webhook_endpoints
+								  WHERE id = $1`
+	// UpdateWebhookEndpointsSQL sets url, events and enabled; enabling an endpoint clears its failures.
+	UpdateWebhookEndpointsSQL = `UPDATE webhook_endpoints
+								 SET url = $2,
+								 	 events = $3,
+								 	 enabled = $4,
+								 	 consecutive_failures = CASE WHEN $4 THEN 0 ELSE consecutive_failures END,
+								 	 disabled_at = CASE WHEN $4 THEN NULL ELSE COALESCE(disabled_at, $5) END,
+								 	 updated_at = $5
+								 WHERE id = $1`
+	DeleteWebhookEndpointsSQL = `DELETE FROM webhook_endpoints
+								 WHERE id = $1`
+	ResetFailuresWebhookEndpointsSQL = `UPDATE webhook_endpoints
+										SET consecutive_failures = 0
+										WHERE id = $1`
+	// AddFailureWebhookEndpointsSQL counts a failed attempt and disables the endpoint once
+	// $2 attempts in a row failed, returning true if it was disabled by this attempt.
+	AddFailureWebhookEndpointsSQL = `UPDATE webhook_endpoints
+									 SET consecutive_failures = consecutive_failures + 1,
+									 	 enabled = enabled AND consecutive_failures + 1 < $2,
+									 	 disabled_at = CASE WHEN enabled AND consecutive_failures + 1 >= $2 THEN $3 ELSE disabled_at END
+									 WHERE id = $1
+									 RETURNING NOT enabled AND disabled_at = $3`
+)
+
+// webhook_deliveries table sql queries
+const (
+	// InsertWebhookDeliveriesSQL creates a delivery of event $1 for every enabled endpoint
+	// subscribed to its type, an event handed over twice is not delivered twice.
+	InsertWebhookDeliveriesSQL = `INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, next_attempt_at, created_at)
+								  SELECT id, $1, $2, $3, $4, $4
+								  FROM webhook_endpoints
+								  WHERE enabled AND (cardinality(events) = 0 OR $2 = ANY(events))
+								  ON CONFLICT (endpoint_id, event_id) WHERE redelivery_of IS NULL DO NOTHING`
+	// ClaimWebhookDeliveriesSQL leases up to $3 due deliveries of enabled endpoints until $2.
+	ClaimWebhookDeliveriesSQL = `WITH claimed AS (
+									 UPDATE webhook_deliveries
+									 SET attempts = attempts + 1,
+									 	 next_attempt_at = $2
+									 WHERE id IN (
+									 	 SELECT d.id
+									 	 FROM webhook_deliveries d
+									 	 JOIN webhook_endpoints e ON e.id = d.endpoint_id
+									 	 WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND e.enabled
+									 	 ORDER BY d.next_attempt_at, d.id
+									 	 LIMIT $3
+									 	 FOR UPDATE OF d SKIP LOCKED
+									 )
+									 RETURNING id, endpoint_id, event_id, event_type, payload, attempts
+								 )
+								 SELECT c.id, c.endpoint_id, c.event_id, c.event_type, c.payload, c.attempts, e.url, e.secret
+								 FROM claimed c
+								 JOIN webhook_endpoints e ON e.id = c.endpoint_id
+								 ORDER BY c.id`
+	UpdateWebhookDeliveriesSQL = `UPDATE webhook_deliveries
+								  SET status = $2,
+								  	  next_attempt_at = $3,
+								  	  response_code = $4,
+								  	  response_body = $5,
+								  	  last_error = $6,
+								  	  delivered_at = $7
+								  WHERE id = $1`
+	GetByEndpointIdWebhookDeliveriesSQL = `SELECT id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+												  response_code, response_body, last_error, redelivery_of, created_at, delivered_at
+										   FROM webhook_deliveries
+										   WHERE endpoint_id = $1 AND ($2 = '' OR status = $2)
+										   ORDER BY created_at DESC, id DESC
+										   LIMIT $3 OFFSET $4`
+	// RedeliverWebhookDeliveriesSQL queues delivery $1 of endpoint $2 again as a new delivery.
+	RedeliverWebhookDeliveriesSQL = `INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, redelivery_of,
+																	 next_attempt_at, created_at)
+									 SELECT endpoint_id, event_id, event_type, payload, id, $3, $3
+									 FROM webhook_deliveries
+									 WHERE id = $1 AND endpoint_id = $2
+									 RETURNING id`
+)
+
+// schema_migrations table sql queries
+const (
+	// GetVersionMigrationsSQL reads the version golang-migrate applied last.
+	GetVersionMigrationsSQL = `SELECT version, dirty
+							   FROM schema_migrations
+							   LIMIT 1`
 )
 
 func NewPostgresDB(ctx context.Context, cfg *config.DBConfig) (*pgxpool.Pool, error) {
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode,
-	)
+	connStr, err := connString(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build connection string: %w", err)
+	}
 
 	cfgPool, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	cfgPool.MaxConns = 25
-	cfgPool.MaxConnLifetime = time.Hour
+	cfgPool.MinConns = cfg.MinConns
+	cfgPool.MaxConns = cfg.MaxConns
+	cfgPool.MaxConnLifetime = cfg.MaxConnLifetime
+	cfgPool.MaxConnIdleTime = cfg.MaxConnIdleTime
+	cfgPool.HealthCheckPeriod = cfg.HealthCheckPeriod
+	cfgPool.ConnConfig.Tracer = tracing.NewQueryTracer()
+
+	// runtime params given in DATABASE_URL take precedence
+	params := cfgPool.ConnConfig.RuntimeParams
+	if _, ok := params["application_name"]; !ok && cfg.ApplicationName != "" {
+		params["application_name"] = cfg.ApplicationName
+	}
+	if _, ok := params["statement_timeout"]; !ok && cfg.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfgPool)
 	if err != nil {
@@ -128,3 +709,62 @@ func NewPostgresDB(ctx context.Context, cfg *config.DBConfig) (*pgxpool.Pool, er
 
 	return pool, nil
 }
+
+// connString returns DATABASE_URL, or the keyword/value string of the separate settings,
+// with the TLS files added when set.
+func connString(cfg *config.DBConfig) (string, error) {
+	tls := [][2]string{
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+	}
+
+	if cfg.URL != "" {
+		// keyword/value strings are accepted too
+		if !strings.HasPrefix(cfg.URL, "postgres://") && !strings.HasPrefix(cfg.URL, "postgresql://") {
+			connStr := cfg.URL
+			for _, kv := range tls {
+				if kv[1] != "" {
+					connStr += " " + kv[0] + "=" + quoteConnValue(kv[1])
+				}
+			}
+			return connStr, nil
+		}
+
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			// the error may contain the password
+			return "", errors.New("invalid DATABASE_URL")
+		}
+		query := u.Query()
+		for _, kv := range tls {
+			if kv[1] != "" {
+				query.Set(kv[0], kv[1])
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	settings := [][2]string{
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+	}
+
+	parts := make([]string, 0, len(settings)+len(tls))
+	for _, kv := range append(settings, tls...) {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+quoteConnValue(kv[1]))
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// quoteConnValue quotes a keyword/value setting, so passwords may contain spaces and quotes.
+func quoteConnValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type AuditRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewAuditRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *AuditRepository {
+	return &AuditRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *AuditRepository) Get(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "AuditRepository.Get")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Audit.Get")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository audit operation...",
+		zap.String("operation", "get"),
+		zap.String("entity_type", filter.EntityType),
+		zap.Int("entity_id", filter.EntityId),
+		zap.Int("limit", filter.Limit),
+		zap.Int("offset", filter.Offset),
+	)
+
+	// get filtered audit entries, newest first
+	rows, err := r.db.Query(ctx, postgres.GetAuditLogSQL,
+		filter.EntityType, filter.EntityId, filter.Actor, filter.Action, filter.From, filter.To,
+		filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_audit_log", start, "failed to get audit entries")
+	}
+	defer rows.Close()
+
+	entries := make([]entity.AuditEntry, 0)
+	for rows.Next() {
+		var e entity.AuditEntry
+
+		err = rows.Scan(&e.Id, &e.Actor, &e.RequestId, &e.EntityType, &e.EntityId, &e.Action, &e.Changes, &e.CreatedAt)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_audit_entry", start, "failed to scan audit entry")
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoAuditOperation(ctx, "get", start)
+	return entries, nil
+}
+
+// insertAuditEntry stores the audit entry of a change within the caller's transaction, so
+// the change is not committed without it. A nil entry is skipped, entityId fills in the id
+// of a created entity.
+func insertAuditEntry(ctx context.Context, tx pgx.Tx, entry *entity.AuditEntry, entityId int) error {
+	if entry == nil {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, postgres.InsertAuditLogSQL,
+		entry.Actor, entry.RequestId, entry.EntityType, entityId, entry.Action, entry.Changes, entry.CreatedAt,
+	)
+	return err
+}
+
+func (r *AuditRepository) logInfoAuditOperation(ctx context.Context, operation string, start time.Time) {
+	metrics.ObserveRepositoryOperation("audit", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository audit operation",
+		zap.String("operation", operation),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
@@ -2,29 +2,44 @@ package repository
 
 import (
 	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
 	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
 	"BookStore_API/internal/zaplog"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 	"time"
 )
 
 type BookRepository struct {
-	db     *pgxpool.Pool
-	logger *zap.Logger
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	// lowStock is the stock level at which StockLow is raised
+	lowStock int
+	logger   *zap.Logger
 }
 
-func NewBookRepository(db *pgxpool.Pool, logger *zap.Logger) *BookRepository {
+func NewBookRepository(db *pgxpool.Pool, timeouts Timeouts, lowStock int, logger *zap.Logger) *BookRepository {
 	return &BookRepository{
-		db:     db,
-		logger: logger,
+		db:       db,
+		timeouts: timeouts,
+		lowStock: lowStock,
+		logger:   logger,
 	}
 }
 
-func (r *BookRepository) Create(ctx context.Context, book entity.Book) (int, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *BookRepository) Create(ctx context.Context, book entity.Book, audit *entity.AuditEntry) (int, error) {
+	ctx, span := tracing.Start(ctx, "BookRepository.Create")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Book.Create")
 	defer cancel()
 
 	start := time.Now()
@@ -34,18 +49,18 @@ func (r *BookRepository) Create(ctx context.Context, book entity.Book) (int, err
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logDebugBookOperation("insert", book)
+	r.logDebugBookOperation(ctx, "insert", book)
 
 	var id int
 
 	// product insert, returning 'id'
 	err = tx.QueryRow(ctx, postgres.InsertProductsSQL,
-		"book", book.Name, book.Price, book.Stock, start,
+		"book", book.Name, book.Price, book.Stock, book.TaxClass, book.WeightGrams, start,
 	).Scan(&id)
 	if err != nil {
-		return 0, handleDBError(r.logger, err, "insert_product", start, "failed to insert product")
+		return 0, handleDBError(logger, err, "insert_product", start, "failed to insert product")
 	}
 
 	// book insert
@@ -53,10 +68,21 @@ func (r *BookRepository) Create(ctx context.Context, book entity.Book) (int, err
 		id, book.Author, book.Isbn,
 	)
 	if err != nil {
-		return 0, handleDBError(r.logger, err, "insert_book", start, "failed to insert book")
+		return 0, handleDBError(logger, err, "insert_book", start, "failed to insert book")
+	}
+
+	// initial price history record
+	err = insertPriceHistory(ctx, tx, id, book.Price, entity.PriceSourceInitial, start)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_price_history", start, "failed to insert price history")
 	}
 
-	r.logger.Info("Book inserted successfully",
+	// audit entry, stored in the same transaction as the change
+	if err = insertAuditEntry(ctx, tx, audit, id); err != nil {
+		return 0, handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+	}
+
+	logger.Info("Book inserted successfully",
 		zap.String("operation", "insert"),
 		zap.Int("id", id),
 		zap.Duration("duration", time.Since(start)),
@@ -64,7 +90,12 @@ func (r *BookRepository) Create(ctx context.Context, book entity.Book) (int, err
 	return id, nil
 }
 func (r *BookRepository) GetById(ctx context.Context, id int) (entity.Book, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, span := tracing.Start(ctx, "BookRepository.GetById")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Book.GetById")
 	defer cancel()
 
 	start := time.Now()
@@ -74,9 +105,9 @@ func (r *BookRepository) GetById(ctx context.Context, id int) (entity.Book, erro
 	if err != nil {
 		return entity.Book{}, fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logger.Debug("Starting repository book operation...",
+	logger.Debug("Starting repository book operation...",
 		zap.String("operation", "get_by_id"),
 		zap.Int("id", id),
 	)
@@ -86,9 +117,13 @@ func (r *BookRepository) GetById(ctx context.Context, id int) (entity.Book, erro
 
 	// product get by id
 	err = tx.QueryRow(ctx, postgres.GetByIdProductsSQL, id).
-		Scan(&book.Id, &productType, &book.Name, &book.Price, &book.Stock, &book.CreatedAt)
+		Scan(&book.Id, &productType, &book.Name, &book.Price, &book.Stock, &book.TaxClass, &book.WeightGrams, &book.Version, &book.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		return entity.Book{}, ErrProductNotFound
+	}
 	if err != nil {
-		return entity.Book{}, handleDBError(r.logger, err, "get_by_id_product", start, "failed to get product by id")
+		return entity.Book{}, handleDBError(logger, err, "get_by_id_product", start, "failed to get product by id")
 	}
 
 	// product type check
@@ -100,14 +135,19 @@ func (r *BookRepository) GetById(ctx context.Context, id int) (entity.Book, erro
 	err = tx.QueryRow(ctx, postgres.GetByIdBooksSQL, id).
 		Scan(&book.Author, &book.Isbn)
 	if err != nil {
-		return entity.Book{}, handleDBError(r.logger, err, "get_by_id_book", start, "failed to get book by id")
+		return entity.Book{}, handleDBError(logger, err, "get_by_id_book", start, "failed to get book by id")
 	}
 
-	r.logInfoBookOperation("get_by_id", start, book)
+	r.logInfoBookOperation(ctx, "get_by_id", start, book)
 	return book, nil
 }
-func (r *BookRepository) Update(ctx context.Context, book entity.Book) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *BookRepository) Update(ctx context.Context, book entity.Book, audit *entity.AuditEntry) error {
+	ctx, span := tracing.Start(ctx, "BookRepository.Update")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Book.Update")
 	defer cancel()
 
 	start := time.Now()
@@ -117,41 +157,73 @@ func (r *BookRepository) Update(ctx context.Context, book entity.Book) error {
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	r.logDebugBookOperation(ctx, "update", book)
 
-	r.logDebugBookOperation("update", book)
+	var prevPrice float64
+	var prevStock int
 
-	// product update by id
-	tag, err := tx.Exec(ctx, postgres.UpdateProductsSQL, book.Id, book.Name, book.Price, book.Stock)
+	// product update by id, returning the previous price and stock
+	err = tx.QueryRow(ctx, postgres.UpdateProductsSQL, book.Id, book.Name, book.Price, book.Stock, book.TaxClass, book.WeightGrams, book.Version).
+		Scan(&prevPrice, &prevStock)
+	// product update result check, the product was read before so a miss means a newer version
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrVersionConflict
+		return err
+	}
 	if err != nil {
-		return handleDBError(r.logger, err, "update_product", start, "failed to update product by id")
+		return handleDBError(logger, err, "update_product", start, "failed to update product by id")
 	}
 
-	// product update result check
-	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no product affected - possibly it does not exist",
-			zap.Int("id", book.Id),
-		)
+	// price and stock events, stored in the same transaction as the change
+	err = insertPriceChangedEvent(ctx, tx, book.Id, prevPrice, book.Price, entity.PriceSourceManual, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_outbox", start, "failed to insert price changed event")
+	}
+	err = insertStockChangedEvent(ctx, tx, book.Id, prevStock, book.Stock, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_outbox", start, "failed to insert stock changed event")
+	}
+	err = insertStockLowEvent(ctx, tx, book.Id, prevStock, book.Stock, r.lowStock, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_outbox", start, "failed to insert stock low event")
+	}
+
+	// price history record, skipped if price did not change
+	err = insertPriceHistory(ctx, tx, book.Id, book.Price, entity.PriceSourceManual, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_price_history", start, "failed to insert price history")
 	}
 
 	// book update by id
-	tag, err = tx.Exec(ctx, postgres.UpdateBooksSQL, book.Id, book.Author, book.Isbn)
+	tag, err := tx.Exec(ctx, postgres.UpdateBooksSQL, book.Id, book.Author, book.Isbn)
 	if err != nil {
-		return handleDBError(r.logger, err, "update_book", start, "failed to update book by id")
+		return handleDBError(logger, err, "update_book", start, "failed to update book by id")
 	}
 
 	// book update result check
 	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no book affected - possibly it does not exist",
+		logger.Warn("no book affected - possibly it does not exist",
 			zap.Int("id", book.Id),
 		)
 	}
 
-	r.logInfoBookOperation("update", start, book)
+	// audit entry, stored in the same transaction as the change
+	if err = insertAuditEntry(ctx, tx, audit, book.Id); err != nil {
+		return handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+	}
+
+	r.logInfoBookOperation(ctx, "update", start, book)
 	return nil
 }
-func (r *BookRepository) Delete(ctx context.Context, id int) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *BookRepository) Delete(ctx context.Context, id, version int, audit *entity.AuditEntry) error {
+	ctx, span := tracing.Start(ctx, "BookRepository.Delete")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Book.Delete")
 	defer cancel()
 
 	start := time.Now()
@@ -161,40 +233,38 @@ func (r *BookRepository) Delete(ctx context.Context, id int) error {
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logger.Debug("Starting repository book operation...",
+	logger.Debug("Starting repository book operation...",
 		zap.String("operation", "delete_by_id"),
 		zap.Int("id", id),
 	)
 
-	// delete book by id
-	tag, err := tx.Exec(ctx, postgres.DeleteByIdBooksSQL, id)
+	// move product to the trash, the book row is kept until the product is purged
+	tag, err := tx.Exec(ctx, postgres.DeleteByIdProductsSQL, id, version, start, "book")
 	if err != nil {
-		return handleDBError(r.logger, err, "delete_book", start, "failed to delete book by id")
+		return handleDBError(logger, err, "delete_product", start, "failed to delete product by id")
 	}
 
-	// book delete result check
+	// product delete result check, a missing book is not a version conflict
+	if tag.RowsAffected() == 0 && version != 0 {
+		err = deleteConflict(ctx, tx, logger, id, "book", start)
+		return err
+	}
 	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no book affected - possibly it does not exist",
+		logger.Warn("no book affected - possibly it does not exist",
 			zap.Int("id", id),
 		)
 	}
 
-	// delete product by id
-	tag, err = tx.Exec(ctx, postgres.DeleteByIdProductsSQL, id)
-	if err != nil {
-		return handleDBError(r.logger, err, "delete_product", start, "failed to delete product by id")
+	// audit entry, stored in the same transaction when the book was moved to the trash
+	if tag.RowsAffected() > 0 {
+		if err = insertAuditEntry(ctx, tx, audit, id); err != nil {
+			return handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+		}
 	}
 
-	// product delete result check
-	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no product affected - possibly it does not exist",
-			zap.Int("id", id),
-		)
-	}
-
-	r.logger.Info("Finished repository book operation",
+	logger.Info("Finished repository book operation",
 		zap.String("operation", "delete"),
 		zap.Int("id", id),
 		zap.Duration("duration", time.Since(start)),
@@ -203,12 +273,17 @@ func (r *BookRepository) Delete(ctx context.Context, id int) error {
 }
 
 func (r *BookRepository) IsbnExists(ctx context.Context, isbn string) (bool, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, span := tracing.Start(ctx, "BookRepository.IsbnExists")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Book.IsbnExists")
 	defer cancel()
 
 	start := time.Now()
 
-	r.logger.Debug("Starting repository book operation...",
+	logger.Debug("Starting repository book operation...",
 		zap.String("operation", "exists_isbn"),
 		zap.String("isbn", isbn),
 	)
@@ -219,10 +294,10 @@ func (r *BookRepository) IsbnExists(ctx context.Context, isbn string) (bool, err
 	err := r.db.QueryRow(ctx, postgres.ExistsIsbnBooksSQL, isbn).
 		Scan(&exists)
 	if err != nil {
-		return false, handleDBError(r.logger, err, "exists_isbn", start, "failed to check isbn existence")
+		return false, handleDBError(logger, err, "exists_isbn", start, "failed to check isbn existence")
 	}
 
-	r.logger.Info("Finished repository book operation",
+	logger.Info("Finished repository book operation",
 		zap.String("operation", "exists_isbn"),
 		zap.String("isbn", isbn),
 		zap.Duration("duration", time.Since(start)),
@@ -230,14 +305,16 @@ func (r *BookRepository) IsbnExists(ctx context.Context, isbn string) (bool, err
 	return exists, nil
 }
 
-func (r *BookRepository) logDebugBookOperation(operation string, book entity.Book) {
+func (r *BookRepository) logDebugBookOperation(ctx context.Context, operation string, book entity.Book) {
 	fields := append(
 		[]zap.Field{zap.String("operation", operation)},
 		zaplog.BookFields(book)...,
 	)
-	r.logger.Debug("Starting repository book operation...", fields...)
+	reqctx.Logger(ctx, r.logger).Debug("Starting repository book operation...", fields...)
 }
-func (r *BookRepository) logInfoBookOperation(operation string, start time.Time, book entity.Book) {
+func (r *BookRepository) logInfoBookOperation(ctx context.Context, operation string, start time.Time, book entity.Book) {
+	metrics.ObserveRepositoryOperation("book", operation, start)
+
 	fields := append(
 		[]zap.Field{
 			zap.String("operation", operation),
@@ -245,5 +322,5 @@ func (r *BookRepository) logInfoBookOperation(operation string, start time.Time,
 		},
 		zaplog.BookFields(book)...,
 	)
-	r.logger.Info("Finished repository book operation", fields...)
+	reqctx.Logger(ctx, r.logger).Info("Finished repository book operation", fields...)
 }
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type CurrencyRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewCurrencyRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *CurrencyRepository {
+	return &CurrencyRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *CurrencyRepository) CreateRate(ctx context.Context, rate entity.ExchangeRate) (int, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyRepository.CreateRate")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Currency.CreateRate")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository currency operation...",
+		zap.String("operation", "insert_rate"),
+		zap.String("currency", rate.Currency),
+		zap.Float64("rate", rate.Rate),
+		zap.Time("effective_from", rate.EffectiveFrom),
+	)
+
+	var id int
+
+	// exchange rate insert, returning 'id'
+	err := r.db.QueryRow(ctx, postgres.InsertExchangeRatesSQL,
+		rate.Currency, rate.Rate, rate.EffectiveFrom, start,
+	).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_exchange_rate", start, "failed to insert exchange rate")
+	}
+
+	r.logInfoCurrencyOperation(ctx, "insert_rate", start, rate.Currency)
+	return id, nil
+}
+func (r *CurrencyRepository) GetRates(ctx context.Context, currency string) ([]entity.ExchangeRate, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyRepository.GetRates")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Currency.GetRates")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository currency operation...",
+		zap.String("operation", "get_rates"),
+		zap.String("currency", currency),
+	)
+
+	// get exchange rates, all currencies if currency is empty
+	rows, err := r.db.Query(ctx, postgres.GetExchangeRatesSQL, currency)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_exchange_rates", start, "failed to get exchange rates")
+	}
+	defer rows.Close()
+
+	rates := make([]entity.ExchangeRate, 0)
+
+	// rows parsing
+	for rows.Next() {
+		var rate entity.ExchangeRate
+
+		err = rows.Scan(&rate.Id, &rate.Currency, &rate.Rate, &rate.EffectiveFrom, &rate.CreatedAt)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_exchange_rate", start, "failed to scan exchange rate")
+		}
+
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoCurrencyOperation(ctx, "get_rates", start, currency)
+	return rates, nil
+}
+func (r *CurrencyRepository) GetRateAt(ctx context.Context, currency string, at time.Time) (float64, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyRepository.GetRateAt")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Currency.GetRateAt")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository currency operation...",
+		zap.String("operation", "get_rate_at"),
+		zap.String("currency", currency),
+		zap.Time("at", at),
+	)
+
+	var rate float64
+
+	// get the latest effective rate at the given moment
+	err := r.db.QueryRow(ctx, postgres.GetRateAtExchangeRatesSQL, currency, at).Scan(&rate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrRateNotFound
+	}
+	if err != nil {
+		return 0, handleDBError(logger, err, "get_rate_at_exchange_rates", start, "failed to get exchange rate")
+	}
+
+	r.logInfoCurrencyOperation(ctx, "get_rate_at", start, currency)
+	return rate, nil
+}
+
+func (r *CurrencyRepository) SetPriceOverride(ctx context.Context, po entity.PriceOverride) error {
+	ctx, span := tracing.Start(ctx, "CurrencyRepository.SetPriceOverride")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Currency.SetPriceOverride")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository currency operation...",
+		zap.String("operation", "set_price_override"),
+		zap.Int("product_id", po.ProductId),
+		zap.String("currency", po.Currency),
+		zap.Float64("price", po.Price),
+	)
+
+	// product price override upsert
+	_, err := r.db.Exec(ctx, postgres.UpsertProductPricesSQL, po.ProductId, po.Currency, po.Price, start)
+	if err != nil {
+		return handleDBError(logger, err, "upsert_product_price", start, "failed to set price override")
+	}
+
+	r.logInfoCurrencyOperation(ctx, "set_price_override", start, po.Currency)
+	return nil
+}
+func (r *CurrencyRepository) DeletePriceOverride(ctx context.Context, productId int, currency string) error {
+	ctx, span := tracing.Start(ctx, "CurrencyRepository.DeletePriceOverride")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Currency.DeletePriceOverride")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository currency operation...",
+		zap.String("operation", "delete_price_override"),
+		zap.Int("product_id", productId),
+		zap.String("currency", currency),
+	)
+
+	// product price override delete
+	tag, err := r.db.Exec(ctx, postgres.DeleteProductPricesSQL, productId, currency)
+	if err != nil {
+		return handleDBError(logger, err, "delete_product_price", start, "failed to delete price override")
+	}
+
+	// delete result check
+	if tag.RowsAffected() == 0 {
+		logger.Warn("no price override affected - possibly it does not exist",
+			zap.Int("product_id", productId),
+			zap.String("currency", currency),
+		)
+	}
+
+	r.logInfoCurrencyOperation(ctx, "delete_price_override", start, currency)
+	return nil
+}
+func (r *CurrencyRepository) GetPriceOverrides(ctx context.Context, productId int) ([]entity.PriceOverride, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyRepository.GetPriceOverrides")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Currency.GetPriceOverrides")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository currency operation...",
+		zap.String("operation", "get_price_overrides"),
+		zap.Int("product_id", productId),
+	)
+
+	// get price overrides by product id
+	rows, err := r.db.Query(ctx, postgres.GetByProductIdProductPricesSQL, productId)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_by_product_id_product_prices", start, "failed to get price overrides")
+	}
+	defer rows.Close()
+
+	overrides, err := scanPriceOverrides(rows)
+	if err != nil {
+		return nil, handleDBError(logger, err, "scan_product_price", start, "failed to scan price override")
+	}
+
+	r.logInfoCurrencyOperation(ctx, "get_price_overrides", start, "")
+	return overrides, nil
+}
+func (r *CurrencyRepository) GetPriceOverridesByIds(ctx context.Context, productIds []int, currency string) ([]entity.PriceOverride, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyRepository.GetPriceOverridesByIds")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Currency.GetPriceOverridesByIds")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository currency operation...",
+		zap.String("operation", "get_price_overrides_by_ids"),
+		zap.Ints("product_ids", productIds),
+		zap.String("currency", currency),
+	)
+
+	// get price overrides by product ids in one currency
+	rows, err := r.db.Query(ctx, postgres.GetByProductIdsProductPricesSQL, productIds, currency)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_by_product_ids_product_prices", start, "failed to get price overrides")
+	}
+	defer rows.Close()
+
+	overrides, err := scanPriceOverrides(rows)
+	if err != nil {
+		return nil, handleDBError(logger, err, "scan_product_price", start, "failed to scan price override")
+	}
+
+	r.logInfoCurrencyOperation(ctx, "get_price_overrides_by_ids", start, currency)
+	return overrides, nil
+}
+
+func scanPriceOverrides(rows pgx.Rows) ([]entity.PriceOverride, error) {
+	overrides := make([]entity.PriceOverride, 0)
+	for rows.Next() {
+		var po entity.PriceOverride
+		if err := rows.Scan(&po.ProductId, &po.Currency, &po.Price, &po.UpdatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, po)
+	}
+	return overrides, rows.Err()
+}
+
+func (r *CurrencyRepository) logInfoCurrencyOperation(ctx context.Context, operation string, start time.Time, currency string) {
+	metrics.ObserveRepositoryOperation("currency", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository currency operation",
+		zap.String("operation", operation),
+		zap.String("currency", currency),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
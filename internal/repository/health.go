@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type HealthRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewHealthRepository(db *pgxpool.Pool, logger *zap.Logger) *HealthRepository {
+	return &HealthRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "HealthRepository.Ping")
+	defer span.End()
+
+	return r.db.Ping(ctx)
+}
+
+// MigrationVersion returns the applied schema version and whether the last migration failed half way.
+func (r *HealthRepository) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	ctx, span := tracing.Start(ctx, "HealthRepository.MigrationVersion")
+	defer span.End()
+
+	var version int64
+	var dirty bool
+
+	err := r.db.QueryRow(ctx, postgres.GetVersionMigrationsSQL).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
+
+func (r *HealthRepository) PoolStats() entity.PoolStats {
+	stat := r.db.Stat()
+	return entity.PoolStats{
+		MaxConns:          stat.MaxConns(),
+		TotalConns:        stat.TotalConns(),
+		IdleConns:         stat.IdleConns(),
+		AcquiredConns:     stat.AcquiredConns(),
+		AcquireCount:      stat.AcquireCount(),
+		EmptyAcquireCount: stat.EmptyAcquireCount(),
+		AcquireDuration:   stat.AcquireDuration(),
+	}
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewIdempotencyRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// Acquire takes the key for a new request. If the key is already taken it returns the
+// stored record and false. Expired keys and keys in progress since before staleBefore are
+// taken over.
+func (r *IdempotencyRepository) Acquire(ctx context.Context, rec entity.IdempotencyRecord, staleBefore time.Time) (entity.IdempotencyRecord, bool, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.Acquire")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Idempotency.Acquire")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return entity.IdempotencyRecord{}, false, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository idempotency operation...",
+		zap.String("operation", "acquire"),
+		zap.String("route", rec.Route),
+	)
+
+	_, err = tx.Exec(ctx, postgres.DeleteStaleIdempotencyKeysSQL, rec.Key, rec.Route, rec.CreatedAt, staleBefore)
+	if err != nil {
+		return entity.IdempotencyRecord{}, false, handleDBError(logger, err, "delete_stale_idempotency_key", start, "failed to delete stale idempotency key")
+	}
+
+	// key insert, no rows means somebody holds it
+	err = tx.QueryRow(ctx, postgres.InsertIdempotencyKeysSQL,
+		rec.Key, rec.Route, rec.RequestHash, rec.CreatedAt, rec.ExpiresAt,
+	).Scan(&rec.CreatedAt)
+	if err == nil {
+		rec.Status = entity.IdempotencyStatusInProgress
+		r.logInfoIdempotencyOperation(ctx, "acquire", start, rec.Route)
+		return rec, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return entity.IdempotencyRecord{}, false, handleDBError(logger, err, "insert_idempotency_key", start, "failed to insert idempotency key")
+	}
+
+	var stored entity.IdempotencyRecord
+	err = tx.QueryRow(ctx, postgres.GetIdempotencyKeysSQL, rec.Key, rec.Route).Scan(
+		&stored.Key,
+		&stored.Route,
+		&stored.RequestHash,
+		&stored.Status,
+		&stored.ResponseStatus,
+		&stored.ResponseBody,
+		&stored.CreatedAt,
+		&stored.ExpiresAt,
+	)
+	if err != nil {
+		return entity.IdempotencyRecord{}, false, handleDBError(logger, err, "get_idempotency_key", start, "failed to get idempotency key")
+	}
+
+	r.logInfoIdempotencyOperation(ctx, "acquire", start, rec.Route)
+	return stored, false, nil
+}
+func (r *IdempotencyRepository) Complete(ctx context.Context, key, route string, status int, body []byte) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.Complete")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Idempotency.Complete")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository idempotency operation...",
+		zap.String("operation", "complete"),
+		zap.String("route", route),
+		zap.Int("status", status),
+	)
+
+	// stored response update
+	_, err := r.db.Exec(ctx, postgres.CompleteIdempotencyKeysSQL, key, route, status, body)
+	if err != nil {
+		return handleDBError(logger, err, "complete_idempotency_key", start, "failed to store idempotent response")
+	}
+
+	r.logInfoIdempotencyOperation(ctx, "complete", start, route)
+	return nil
+}
+func (r *IdempotencyRepository) Release(ctx context.Context, key, route string) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.Release")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Idempotency.Release")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository idempotency operation...",
+		zap.String("operation", "release"),
+		zap.String("route", route),
+	)
+
+	// key delete, so the request can be retried
+	_, err := r.db.Exec(ctx, postgres.DeleteIdempotencyKeysSQL, key, route)
+	if err != nil {
+		return handleDBError(logger, err, "delete_idempotency_key", start, "failed to release idempotency key")
+	}
+
+	r.logInfoIdempotencyOperation(ctx, "release", start, route)
+	return nil
+}
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.DeleteExpired")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Idempotency.DeleteExpired")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository idempotency operation...",
+		zap.String("operation", "delete_expired"),
+		zap.Time("now", now),
+	)
+
+	// expired keys delete
+	tag, err := r.db.Exec(ctx, postgres.DeleteExpiredIdempotencyKeysSQL, now)
+	if err != nil {
+		return 0, handleDBError(logger, err, "delete_expired_idempotency_keys", start, "failed to delete expired idempotency keys")
+	}
+
+	r.logInfoIdempotencyOperation(ctx, "delete_expired", start, "")
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *IdempotencyRepository) logInfoIdempotencyOperation(ctx context.Context, operation string, start time.Time, route string) {
+	metrics.ObserveRepositoryOperation("idempotency", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository idempotency operation",
+		zap.String("operation", operation),
+		zap.String("route", route),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
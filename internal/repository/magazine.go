@@ -2,29 +2,44 @@ package repository
 
 import (
 	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
 	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
 	"BookStore_API/internal/zaplog"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 	"time"
 )
 
 type MagazineRepository struct {
-	db     *pgxpool.Pool
-	logger *zap.Logger
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	// lowStock is the stock level at which StockLow is raised
+	lowStock int
+	logger   *zap.Logger
 }
 
-func NewMagazineRepository(db *pgxpool.Pool, logger *zap.Logger) *MagazineRepository {
+func NewMagazineRepository(db *pgxpool.Pool, timeouts Timeouts, lowStock int, logger *zap.Logger) *MagazineRepository {
 	return &MagazineRepository{
-		db:     db,
-		logger: logger,
+		db:       db,
+		timeouts: timeouts,
+		lowStock: lowStock,
+		logger:   logger,
 	}
 }
 
-func (r *MagazineRepository) Create(ctx context.Context, mag entity.Magazine) (int, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *MagazineRepository) Create(ctx context.Context, mag entity.Magazine, audit *entity.AuditEntry) (int, error) {
+	ctx, span := tracing.Start(ctx, "MagazineRepository.Create")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Magazine.Create")
 	defer cancel()
 
 	start := time.Now()
@@ -34,18 +49,18 @@ func (r *MagazineRepository) Create(ctx context.Context, mag entity.Magazine) (i
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logDebugMagazineOperation("insert", mag)
+	r.logDebugMagazineOperation(ctx, "insert", mag)
 
 	var id int
 
 	// product insert, returning 'id'
 	err = tx.QueryRow(ctx, postgres.InsertProductsSQL,
-		"magazine", mag.Name, mag.Price, mag.Stock, start,
+		"magazine", mag.Name, mag.Price, mag.Stock, mag.TaxClass, mag.WeightGrams, start,
 	).Scan(&id)
 	if err != nil {
-		return 0, handleDBError(r.logger, err, "insert_product", start, "failed to insert product")
+		return 0, handleDBError(logger, err, "insert_product", start, "failed to insert product")
 	}
 
 	// magazine insert
@@ -53,10 +68,21 @@ func (r *MagazineRepository) Create(ctx context.Context, mag entity.Magazine) (i
 		id, mag.IssueNumber, mag.PublicationDate,
 	)
 	if err != nil {
-		return 0, handleDBError(r.logger, err, "insert_magazine", start, "failed to insert magazine")
+		return 0, handleDBError(logger, err, "insert_magazine", start, "failed to insert magazine")
+	}
+
+	// initial price history record
+	err = insertPriceHistory(ctx, tx, id, mag.Price, entity.PriceSourceInitial, start)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_price_history", start, "failed to insert price history")
 	}
 
-	r.logger.Info("Magazine inserted successfully",
+	// audit entry, stored in the same transaction as the change
+	if err = insertAuditEntry(ctx, tx, audit, id); err != nil {
+		return 0, handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+	}
+
+	logger.Info("Magazine inserted successfully",
 		zap.String("operation", "insert"),
 		zap.Int("id", id),
 		zap.Duration("duration", time.Since(start)),
@@ -64,7 +90,12 @@ func (r *MagazineRepository) Create(ctx context.Context, mag entity.Magazine) (i
 	return id, nil
 }
 func (r *MagazineRepository) GetById(ctx context.Context, id int) (entity.Magazine, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, span := tracing.Start(ctx, "MagazineRepository.GetById")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Magazine.GetById")
 	defer cancel()
 
 	start := time.Now()
@@ -74,9 +105,9 @@ func (r *MagazineRepository) GetById(ctx context.Context, id int) (entity.Magazi
 	if err != nil {
 		return entity.Magazine{}, fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logger.Debug("Starting repository magazine operation...",
+	logger.Debug("Starting repository magazine operation...",
 		zap.String("operation", "get_by_id"),
 		zap.Int("id", id),
 	)
@@ -86,9 +117,13 @@ func (r *MagazineRepository) GetById(ctx context.Context, id int) (entity.Magazi
 
 	// product get by id
 	err = tx.QueryRow(ctx, postgres.GetByIdProductsSQL, id).
-		Scan(&mag.Id, &productType, &mag.Name, &mag.Price, &mag.Stock, &mag.CreatedAt)
+		Scan(&mag.Id, &productType, &mag.Name, &mag.Price, &mag.Stock, &mag.TaxClass, &mag.WeightGrams, &mag.Version, &mag.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		return entity.Magazine{}, ErrProductNotFound
+	}
 	if err != nil {
-		return entity.Magazine{}, handleDBError(r.logger, err, "get_by_id_product", start, "failed to get product by id")
+		return entity.Magazine{}, handleDBError(logger, err, "get_by_id_product", start, "failed to get product by id")
 	}
 
 	// product type check
@@ -100,14 +135,19 @@ func (r *MagazineRepository) GetById(ctx context.Context, id int) (entity.Magazi
 	err = tx.QueryRow(ctx, postgres.GetByIdMagazinesSQL, id).
 		Scan(&mag.IssueNumber, &mag.PublicationDate)
 	if err != nil {
-		return entity.Magazine{}, handleDBError(r.logger, err, "get_by_id_magazine", start, "failed to get magazine by id")
+		return entity.Magazine{}, handleDBError(logger, err, "get_by_id_magazine", start, "failed to get magazine by id")
 	}
 
-	r.logInfoMagazineOperation("get_by_id", start, mag)
+	r.logInfoMagazineOperation(ctx, "get_by_id", start, mag)
 	return mag, nil
 }
-func (r *MagazineRepository) Update(ctx context.Context, mag entity.Magazine) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *MagazineRepository) Update(ctx context.Context, mag entity.Magazine, audit *entity.AuditEntry) error {
+	ctx, span := tracing.Start(ctx, "MagazineRepository.Update")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Magazine.Update")
 	defer cancel()
 
 	start := time.Now()
@@ -117,42 +157,74 @@ func (r *MagazineRepository) Update(ctx context.Context, mag entity.Magazine) er
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	r.logDebugMagazineOperation(ctx, "update", mag)
 
-	r.logDebugMagazineOperation("update", mag)
+	var prevPrice float64
+	var prevStock int
 
-	// product update by id
-	tag, err := tx.Exec(ctx, postgres.UpdateProductsSQL, mag.Id, mag.Name, mag.Price, mag.Stock)
+	// product update by id, returning the previous price and stock
+	err = tx.QueryRow(ctx, postgres.UpdateProductsSQL, mag.Id, mag.Name, mag.Price, mag.Stock, mag.TaxClass, mag.WeightGrams, mag.Version).
+		Scan(&prevPrice, &prevStock)
+	// product update result check, the product was read before so a miss means a newer version
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrVersionConflict
+		return err
+	}
 	if err != nil {
-		return handleDBError(r.logger, err, "update_product", start, "failed to update product by id")
+		return handleDBError(logger, err, "update_product", start, "failed to update product by id")
 	}
 
-	// product update result check
-	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no product affected - possibly it does not exist",
-			zap.Int("id", mag.Id),
-		)
+	// price and stock events, stored in the same transaction as the change
+	err = insertPriceChangedEvent(ctx, tx, mag.Id, prevPrice, mag.Price, entity.PriceSourceManual, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_outbox", start, "failed to insert price changed event")
+	}
+	err = insertStockChangedEvent(ctx, tx, mag.Id, prevStock, mag.Stock, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_outbox", start, "failed to insert stock changed event")
+	}
+	err = insertStockLowEvent(ctx, tx, mag.Id, prevStock, mag.Stock, r.lowStock, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_outbox", start, "failed to insert stock low event")
+	}
+
+	// price history record, skipped if price did not change
+	err = insertPriceHistory(ctx, tx, mag.Id, mag.Price, entity.PriceSourceManual, start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_price_history", start, "failed to insert price history")
 	}
 
 	// magazine update by id
-	tag, err = tx.Exec(ctx, postgres.UpdateMagazinesSQL,
+	tag, err := tx.Exec(ctx, postgres.UpdateMagazinesSQL,
 		mag.Id, mag.IssueNumber, mag.PublicationDate)
 	if err != nil {
-		return handleDBError(r.logger, err, "update_magazine", start, "failed to update magazine by id")
+		return handleDBError(logger, err, "update_magazine", start, "failed to update magazine by id")
 	}
 
 	// magazine update result check
 	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no magazine affected - possibly it does not exist",
+		logger.Warn("no magazine affected - possibly it does not exist",
 			zap.Int("id", mag.Id),
 		)
 	}
 
-	r.logInfoMagazineOperation("update", start, mag)
+	// audit entry, stored in the same transaction as the change
+	if err = insertAuditEntry(ctx, tx, audit, mag.Id); err != nil {
+		return handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+	}
+
+	r.logInfoMagazineOperation(ctx, "update", start, mag)
 	return nil
 }
-func (r *MagazineRepository) Delete(ctx context.Context, id int) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *MagazineRepository) Delete(ctx context.Context, id, version int, audit *entity.AuditEntry) error {
+	ctx, span := tracing.Start(ctx, "MagazineRepository.Delete")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Magazine.Delete")
 	defer cancel()
 
 	start := time.Now()
@@ -162,40 +234,38 @@ func (r *MagazineRepository) Delete(ctx context.Context, id int) error {
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logger.Debug("Starting repository magazine operation...",
+	logger.Debug("Starting repository magazine operation...",
 		zap.String("operation", "delete_by_id"),
 		zap.Int("id", id),
 	)
 
-	// delete magazine by id
-	tag, err := tx.Exec(ctx, postgres.DeleteByIdMagazinesSQL, id)
+	// move product to the trash, the magazine row is kept until the product is purged
+	tag, err := tx.Exec(ctx, postgres.DeleteByIdProductsSQL, id, version, start, "magazine")
 	if err != nil {
-		return handleDBError(r.logger, err, "delete_magazine", start, "failed to delete magazine by id")
+		return handleDBError(logger, err, "delete_product", start, "failed to delete product by id")
 	}
 
-	// magazine delete result check
+	// product delete result check, a missing magazine is not a version conflict
+	if tag.RowsAffected() == 0 && version != 0 {
+		err = deleteConflict(ctx, tx, logger, id, "magazine", start)
+		return err
+	}
 	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no magazine affected - possibly it does not exist",
+		logger.Warn("no magazine affected - possibly it does not exist",
 			zap.Int("id", id),
 		)
 	}
 
-	// delete product by id
-	tag, err = tx.Exec(ctx, postgres.DeleteByIdProductsSQL, id)
-	if err != nil {
-		return handleDBError(r.logger, err, "delete_product", start, "failed to delete product by id")
+	// audit entry, stored in the same transaction when the magazine was moved to the trash
+	if tag.RowsAffected() > 0 {
+		if err = insertAuditEntry(ctx, tx, audit, id); err != nil {
+			return handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+		}
 	}
 
-	// product delete result check
-	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no product affected - possibly it does not exist",
-			zap.Int("id", id),
-		)
-	}
-
-	r.logger.Info("Finished repository magazine operation",
+	logger.Info("Finished repository magazine operation",
 		zap.String("operation", "delete"),
 		zap.Int("id", id),
 		zap.Duration("duration", time.Since(start)),
@@ -203,12 +273,17 @@ func (r *MagazineRepository) Delete(ctx context.Context, id int) error {
 	return nil
 }
 func (r *MagazineRepository) ExistsIssueNumber(ctx context.Context, issueNumber int) (bool, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, span := tracing.Start(ctx, "MagazineRepository.ExistsIssueNumber")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Magazine.ExistsIssueNumber")
 	defer cancel()
 
 	start := time.Now()
 
-	r.logger.Debug("Starting repository book operation...",
+	logger.Debug("Starting repository book operation...",
 		zap.String("operation", "exists_isbn"),
 		zap.Int("issue_number", issueNumber),
 	)
@@ -219,10 +294,10 @@ func (r *MagazineRepository) ExistsIssueNumber(ctx context.Context, issueNumber
 	err := r.db.QueryRow(ctx, postgres.ExistsIssueNumberMagazinesSQL, issueNumber).
 		Scan(&exists)
 	if err != nil {
-		return false, handleDBError(r.logger, err, "exists_issue_number", start, "failed to check isbn existence")
+		return false, handleDBError(logger, err, "exists_issue_number", start, "failed to check isbn existence")
 	}
 
-	r.logger.Info("Finished repository magazine operation",
+	logger.Info("Finished repository magazine operation",
 		zap.String("operation", "exists_issue_number"),
 		zap.Int("issue_number", issueNumber),
 		zap.Duration("duration", time.Since(start)),
@@ -230,14 +305,16 @@ func (r *MagazineRepository) ExistsIssueNumber(ctx context.Context, issueNumber
 	return exists, nil
 }
 
-func (r *MagazineRepository) logDebugMagazineOperation(operation string, mag entity.Magazine) {
+func (r *MagazineRepository) logDebugMagazineOperation(ctx context.Context, operation string, mag entity.Magazine) {
 	fields := append(
 		[]zap.Field{zap.String("operation", operation)},
 		zaplog.MagazineFields(mag)...,
 	)
-	r.logger.Debug("Starting repository magazine operation...", fields...)
+	reqctx.Logger(ctx, r.logger).Debug("Starting repository magazine operation...", fields...)
 }
-func (r *MagazineRepository) logInfoMagazineOperation(operation string, start time.Time, mag entity.Magazine) {
+func (r *MagazineRepository) logInfoMagazineOperation(ctx context.Context, operation string, start time.Time, mag entity.Magazine) {
+	metrics.ObserveRepositoryOperation("magazine", operation, start)
+
 	fields := append(
 		[]zap.Field{
 			zap.String("operation", operation),
@@ -245,5 +322,5 @@ func (r *MagazineRepository) logInfoMagazineOperation(operation string, start ti
 		},
 		zaplog.MagazineFields(mag)...,
 	)
-	r.logger.Info("Finished repository magazine operation", fields...)
+	reqctx.Logger(ctx, r.logger).Info("Finished repository magazine operation", fields...)
 }
@@ -2,29 +2,42 @@ package repository
 
 import (
 	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
 	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
 	"BookStore_API/internal/zaplog"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
+	"math"
 	"time"
 )
 
 type OrderRepository struct {
-	db     *pgxpool.Pool
-	logger *zap.Logger
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
 }
 
-func NewOrderRepository(db *pgxpool.Pool, logger *zap.Logger) *OrderRepository {
+func NewOrderRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *OrderRepository {
 	return &OrderRepository{
-		db:     db,
-		logger: logger,
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
 	}
 }
 
-func (r *OrderRepository) Create(ctx context.Context, order entity.Order) (int, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *OrderRepository) Create(ctx context.Context, order entity.Order, audit *entity.AuditEntry) (int, error) {
+	ctx, span := tracing.Start(ctx, "OrderRepository.Create")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Order.Create")
 	defer cancel()
 
 	start := time.Now()
@@ -34,30 +47,49 @@ func (r *OrderRepository) Create(ctx context.Context, order entity.Order) (int,
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logDebugOrderOperation("insert", order)
+	r.logDebugOrderOperation(ctx, "insert", order)
 
 	var orderId int
 
 	// order insert, returning 'orderId'
 	err = tx.QueryRow(ctx, postgres.InsertOrdersSQL,
-		order.Status, start,
+		order.Status, order.Currency, order.ExchangeRate,
+		order.Destination.Country, order.Destination.Region, order.TaxMode,
+		order.Shipping.Method, order.Shipping.Cost, start,
 	).Scan(&orderId)
 	if err != nil {
-		return 0, handleDBError(r.logger, err, "insert_order", start, "failed to insert order")
+		return 0, handleDBError(logger, err, "insert_order", start, "failed to insert order")
 	}
 
 	// order item insert
 	for _, item := range order.Items {
 		_, err = tx.Exec(ctx, postgres.InsertOrderItemsSQL,
-			orderId, item.Product.Id, item.Quantity, item.Product.Price)
+			orderId, item.Product.Id, item.Quantity, item.Product.Price,
+			item.Product.TaxClass, item.TaxRate, item.TaxAmount)
 		if err != nil {
-			return 0, handleDBError(r.logger, err, "insert_order_item", start, "failed to insert order item")
+			return 0, handleDBError(logger, err, "insert_order_item", start, "failed to insert order item")
 		}
 	}
 
-	r.logger.Info("Order inserted successfully",
+	// order tax breakdown insert
+	if err = insertTaxLines(ctx, tx, orderId, order.TaxLines); err != nil {
+		return 0, handleDBError(logger, err, "insert_order_tax_lines", start, "failed to insert order tax lines")
+	}
+
+	// order created event
+	err = insertOutboxEvent(ctx, tx, entity.AggregateOrder, orderId, entity.EventOrderCreated, orderCreatedPayload(orderId, order), start)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_outbox", start, "failed to insert order created event")
+	}
+
+	// audit entry, stored in the same transaction as the change
+	if err = insertAuditEntry(ctx, tx, audit, orderId); err != nil {
+		return 0, handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+	}
+
+	logger.Info("Order inserted successfully",
 		zap.String("operation", "insert"),
 		zap.Int("orderId", orderId),
 		zap.Duration("duration", time.Since(start)),
@@ -65,7 +97,12 @@ func (r *OrderRepository) Create(ctx context.Context, order entity.Order) (int,
 	return orderId, nil
 }
 func (r *OrderRepository) GetById(ctx context.Context, id int) (entity.Order, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, span := tracing.Start(ctx, "OrderRepository.GetById")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Order.GetById")
 	defer cancel()
 
 	start := time.Now()
@@ -75,9 +112,9 @@ func (r *OrderRepository) GetById(ctx context.Context, id int) (entity.Order, er
 	if err != nil {
 		return entity.Order{}, fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logger.Debug("Starting repository order operation...",
+	logger.Debug("Starting repository order operation...",
 		zap.String("operation", "get_by_id"),
 		zap.Int("id", id),
 	)
@@ -86,43 +123,83 @@ func (r *OrderRepository) GetById(ctx context.Context, id int) (entity.Order, er
 
 	// order get by id
 	err = tx.QueryRow(ctx, postgres.GetByIdOrdersSQL, id).
-		Scan(&order.Status, &order.CreatedAt)
+		Scan(
+			&order.Status,
+			&order.Currency,
+			&order.ExchangeRate,
+			&order.Destination.Country,
+			&order.Destination.Region,
+			&order.TaxMode,
+			&order.Shipping.Method,
+			&order.Shipping.Cost,
+			&order.Shipping.TrackingNumber,
+			&order.Version,
+			&order.CreatedAt,
+		)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		return entity.Order{}, ErrOrderNotFound
+	}
 	if err != nil {
-		return entity.Order{}, handleDBError(r.logger, err, "get_by_id_order", start, "failed to get order by id")
+		return entity.Order{}, handleDBError(logger, err, "get_by_id_order", start, "failed to get order by id")
 	}
 
 	order.Id = id
 
 	rows, err := tx.Query(ctx, postgres.GetByOrderIdOrderItemsSQL, id)
 	if err != nil {
-		return entity.Order{}, handleDBError(r.logger, err, "get_by_order_id_order_items", start, "failed to get order items by order id")
+		return entity.Order{}, handleDBError(logger, err, "get_by_order_id_order_items", start, "failed to get order items by order id")
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var item entity.OrderItem
 
-		err = rows.Scan(
-			&item.Product.Id,
-			&item.Quantity,
-			&item.Product.Price,
-		)
+		err = scanOrderItem(rows, &item)
 		if err != nil {
-			return entity.Order{}, handleDBError(r.logger, err, "scan_order_item", start, "failed to scan order item")
+			return entity.Order{}, handleDBError(logger, err, "scan_order_item", start, "failed to scan order item")
 		}
 
 		order.Items = append(order.Items, item)
 	}
 
 	if err = rows.Err(); err != nil {
-		return entity.Order{}, handleDBError(r.logger, err, "rows_err", start, "failed during rows iteration")
+		return entity.Order{}, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
 	}
+	rows.Close()
 
-	r.logInfoOrderOperation("get_by_id", start, order)
+	// order tax breakdown get
+	taxRows, err := tx.Query(ctx, postgres.GetByOrderIdOrderTaxLinesSQL, id)
+	if err != nil {
+		return entity.Order{}, handleDBError(logger, err, "get_by_order_id_order_tax_lines", start, "failed to get order tax lines by order id")
+	}
+	defer taxRows.Close()
+
+	for taxRows.Next() {
+		var line entity.TaxLine
+
+		err = taxRows.Scan(&line.TaxClass, &line.Rate, &line.TaxableAmount, &line.TaxAmount)
+		if err != nil {
+			return entity.Order{}, handleDBError(logger, err, "scan_order_tax_line", start, "failed to scan order tax line")
+		}
+
+		order.TaxLines = append(order.TaxLines, line)
+	}
+
+	if err = taxRows.Err(); err != nil {
+		return entity.Order{}, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoOrderOperation(ctx, "get_by_id", start, order)
 	return order, nil
 }
-func (r *OrderRepository) Update(ctx context.Context, order entity.Order) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *OrderRepository) Update(ctx context.Context, order entity.Order, audit *entity.AuditEntry) error {
+	ctx, span := tracing.Start(ctx, "OrderRepository.Update")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Order.Update")
 	defer cancel()
 
 	start := time.Now()
@@ -132,49 +209,95 @@ func (r *OrderRepository) Update(ctx context.Context, order entity.Order) error
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logDebugOrderOperation("update", order)
+	r.logDebugOrderOperation(ctx, "update", order)
 
-	// order update by id
-	tag, err := tx.Exec(ctx, postgres.UpdateOrdersSQL, order.Id, order.Status)
+	var prevStatus string
+
+	// order update by id, returning the previous status
+	err = tx.QueryRow(ctx, postgres.UpdateOrdersSQL, order.Id, order.Status, order.Shipping.TrackingNumber, order.Version).
+		Scan(&prevStatus)
+	// order update result check, the order was read before so a miss means a newer version
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrVersionConflict
+		return err
+	}
 	if err != nil {
-		return handleDBError(r.logger, err, "update_order", start, "failed to update order by id")
+		return handleDBError(logger, err, "update_order", start, "failed to update order by id")
 	}
 
-	// order update result check
-	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no order affected - possibly it does not exist",
-			zap.Int("id", order.Id),
-		)
+	// order status changed event
+	if err = insertStatusChangedEvent(ctx, tx, order.Id, prevStatus, order.Status, start); err != nil {
+		return handleDBError(logger, err, "insert_outbox", start, "failed to insert order status changed event")
+	}
+
+	// stored order items, the order row is locked by the update above
+	stored, err := storedOrderItems(ctx, tx, order.Id)
+	if err != nil {
+		return handleDBError(logger, err, "get_by_order_id_order_items", start, "failed to get order items by order id")
 	}
 
-	// order items update
+	// only added, changed and removed lines are written
 	for _, item := range order.Items {
-		_, err = tx.Exec(ctx, postgres.UpsertOrderItemsSQL,
-			order.Id, item.Product.Id, item.Quantity, item.Product.Price)
-		if err != nil {
-			return handleDBError(r.logger, err, "update_order_item", start, "failed to update order item")
+		prev, ok := stored[item.Product.Id]
+		delete(stored, item.Product.Id)
+
+		switch {
+		case !ok:
+			_, err = tx.Exec(ctx, postgres.InsertOrderItemsSQL,
+				order.Id, item.Product.Id, item.Quantity, item.Product.Price,
+				item.Product.TaxClass, item.TaxRate, item.TaxAmount)
+			if err != nil {
+				return handleDBError(logger, err, "insert_order_item", start, "failed to insert order item")
+			}
+		case !sameOrderItem(prev, item):
+			_, err = tx.Exec(ctx, postgres.UpdateOrderItemsSQL,
+				order.Id, item.Product.Id, item.Quantity, item.Product.Price,
+				item.Product.TaxClass, item.TaxRate, item.TaxAmount)
+			if err != nil {
+				return handleDBError(logger, err, "update_order_item", start, "failed to update order item")
+			}
 		}
 	}
 
-	// preparing items id array
-	ids := make([]int, len(order.Items))
-	for i, item := range order.Items {
-		ids[i] = item.Product.Id
+	// lines left in stored are no longer in the order
+	if len(stored) > 0 {
+		removed := make([]int, 0, len(stored))
+		for productId := range stored {
+			removed = append(removed, productId)
+		}
+
+		_, err = tx.Exec(ctx, postgres.DeleteByProductIdsOrderItemsSQL, order.Id, removed)
+		if err != nil {
+			return handleDBError(logger, err, "delete_order_items", start, "failed to delete removed order items")
+		}
 	}
 
-	// delete unnecessary order items
-	_, err = tx.Exec(ctx, postgres.DeleteByOrderIdOrderItemsSQL, order.Id, ids)
+	// order tax breakdown replace
+	_, err = tx.Exec(ctx, postgres.DeleteByOrderIdOrderTaxLinesSQL, order.Id)
 	if err != nil {
-		return handleDBError(r.logger, err, "delete_order_items", start, "failed to delete unnecessary order items")
+		return handleDBError(logger, err, "delete_order_tax_lines", start, "failed to delete order tax lines")
+	}
+	if err = insertTaxLines(ctx, tx, order.Id, order.TaxLines); err != nil {
+		return handleDBError(logger, err, "insert_order_tax_lines", start, "failed to insert order tax lines")
 	}
 
-	r.logInfoOrderOperation("update", start, order)
+	// audit entry, stored in the same transaction as the change
+	if err = insertAuditEntry(ctx, tx, audit, order.Id); err != nil {
+		return handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+	}
+
+	r.logInfoOrderOperation(ctx, "update", start, order)
 	return nil
 }
-func (r *OrderRepository) Delete(ctx context.Context, id int) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+func (r *OrderRepository) Delete(ctx context.Context, id, version int, audit *entity.AuditEntry) error {
+	ctx, span := tracing.Start(ctx, "OrderRepository.Delete")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Order.Delete")
 	defer cancel()
 
 	start := time.Now()
@@ -184,54 +307,194 @@ func (r *OrderRepository) Delete(ctx context.Context, id int) error {
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
-	defer finalizeTx(r.logger, ctx, tx, &err)
+	defer finalizeTx(logger, ctx, tx, &err)
 
-	r.logger.Debug("Starting repository order operation...",
+	logger.Debug("Starting repository order operation...",
 		zap.String("operation", "delete_by_id"),
 		zap.Int("id", id),
 	)
 
-	// delete order by id
-	tag, err := tx.Exec(ctx, postgres.DeleteByIdOrdersSQL, id)
+	// move order to the trash, items and history are kept until the order is purged
+	tag, err := tx.Exec(ctx, postgres.DeleteByIdOrdersSQL, id, version, start)
 	if err != nil {
-		return handleDBError(r.logger, err, "delete_by_id_order", start, "failed to delete order by id")
+		return handleDBError(logger, err, "delete_by_id_order", start, "failed to delete order by id")
 	}
 
-	// order delete result check
+	// order delete result check, a missing order is not a version conflict
+	if tag.RowsAffected() == 0 && version != 0 {
+		var exists bool
+		if err = tx.QueryRow(ctx, postgres.ExistsOrdersSQL, id).Scan(&exists); err != nil {
+			return handleDBError(logger, err, "exists_order", start, "failed to check order existence")
+		}
+		err = ErrVersionConflict
+		if !exists {
+			err = ErrOrderNotFound
+		}
+		return err
+	}
 	if tag.RowsAffected() == 0 {
-		r.logger.Warn("no order affected - possibly it does not exist",
+		logger.Warn("no order affected - possibly it does not exist",
 			zap.Int("id", id),
 		)
 	}
 
-	var count int
+	// audit entry, stored in the same transaction when the order was moved to the trash
+	if tag.RowsAffected() > 0 {
+		if err = insertAuditEntry(ctx, tx, audit, id); err != nil {
+			return handleDBError(logger, err, "insert_audit_log", start, "failed to insert audit entry")
+		}
+	}
 
-	// order items delete result check
-	err = tx.QueryRow(ctx, postgres.ExistsOrderItemsWithOrderId, id).Scan(&count)
+	logger.Info("Finished repository order operation",
+		zap.String("operation", "delete"),
+		zap.Int("id", id),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return nil
+}
+
+func (r *OrderRepository) GetHistory(ctx context.Context, orderId int) ([]entity.OrderHistoryEntry, error) {
+	ctx, span := tracing.Start(ctx, "OrderRepository.GetHistory")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Order.GetHistory")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository order operation...",
+		zap.String("operation", "get_history"),
+		zap.Int("id", orderId),
+	)
+
+	// get order history by order id
+	rows, err := r.db.Query(ctx, postgres.GetByOrderIdOrderHistorySQL, orderId)
 	if err != nil {
-		return handleDBError(r.logger, err, "exists_oder_items", start, "failed to check order items existence")
+		return nil, handleDBError(logger, err, "get_by_order_id_order_history", start, "failed to get order history")
 	}
-	if count > 0 {
-		r.logger.Warn("order_items were not deleted as expected",
-			zap.Int("remaining_items", count))
+	defer rows.Close()
+
+	history := make([]entity.OrderHistoryEntry, 0)
+
+	// rows parsing
+	for rows.Next() {
+		var entry entity.OrderHistoryEntry
+
+		err = rows.Scan(&entry.Id, &entry.OrderId, &entry.Event, &entry.Details, &entry.CreatedAt)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_order_history", start, "failed to scan order history")
+		}
+
+		history = append(history, entry)
 	}
 
-	r.logger.Info("Finished repository order operation",
-		zap.String("operation", "delete"),
-		zap.Int("id", id),
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	logger.Info("Finished repository order operation",
+		zap.String("operation", "get_history"),
+		zap.Int("id", orderId),
 		zap.Duration("duration", time.Since(start)),
 	)
+	return history, nil
+}
+
+// insertOrderHistory records an event in the order history within the caller's transaction.
+func insertOrderHistory(ctx context.Context, tx pgx.Tx, orderId int, event, details string, at time.Time) error {
+	_, err := tx.Exec(ctx, postgres.InsertOrderHistorySQL, orderId, event, details, at)
+	return err
+}
+
+// storedOrderItems returns the order lines currently stored, by product id.
+func storedOrderItems(ctx context.Context, tx pgx.Tx, orderId int) (map[int]entity.OrderItem, error) {
+	rows, err := tx.Query(ctx, postgres.GetByOrderIdOrderItemsSQL, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[int]entity.OrderItem)
+	for rows.Next() {
+		var item entity.OrderItem
+		if err = scanOrderItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items[item.Product.Id] = item
+	}
+	return items, rows.Err()
+}
+
+// scanOrderItem scans a row of GetByOrderIdOrderItemsSQL, the product name comes from the snapshot.
+func scanOrderItem(row pgx.Row, item *entity.OrderItem) error {
+	return row.Scan(
+		&item.Product.Id,
+		&item.Quantity,
+		&item.Product.Price,
+		&item.Product.TaxClass,
+		&item.TaxRate,
+		&item.TaxAmount,
+		&item.Product.Name,
+		&item.ProductType,
+		&item.Isbn,
+		&item.IssueNumber,
+	)
+}
+
+// sameOrderItem reports whether a line is unchanged, compared with the precision of the columns.
+func sameOrderItem(a, b entity.OrderItem) bool {
+	cents := func(v float64) int64 { return int64(math.Round(v * 100)) }
+	return a.Quantity == b.Quantity &&
+		a.Product.TaxClass == b.Product.TaxClass &&
+		cents(a.Product.Price) == cents(b.Product.Price) &&
+		cents(a.TaxAmount) == cents(b.TaxAmount) &&
+		math.Round(a.TaxRate*10000) == math.Round(b.TaxRate*10000)
+}
+
+func orderCreatedPayload(orderId int, order entity.Order) entity.OrderCreatedPayload {
+	_, _, total := order.Totals()
+
+	items := make([]entity.OrderCreatedItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, entity.OrderCreatedItem{
+			ProductId: item.Product.Id,
+			Quantity:  item.Quantity,
+			Price:     item.Product.Price,
+		})
+	}
+
+	return entity.OrderCreatedPayload{
+		OrderId:  orderId,
+		Status:   order.Status,
+		Currency: order.Currency,
+		Total:    math.Round(total*100) / 100,
+		Items:    items,
+	}
+}
+
+func insertTaxLines(ctx context.Context, tx pgx.Tx, orderId int, lines []entity.TaxLine) error {
+	for _, line := range lines {
+		_, err := tx.Exec(ctx, postgres.InsertOrderTaxLinesSQL,
+			orderId, line.TaxClass, line.Rate, line.TaxableAmount, line.TaxAmount)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (r *OrderRepository) logDebugOrderOperation(operation string, order entity.Order) {
+func (r *OrderRepository) logDebugOrderOperation(ctx context.Context, operation string, order entity.Order) {
 	fields := append(
 		[]zap.Field{zap.String("operation", operation)},
 		zaplog.OrderFields(order)...,
 	)
-	r.logger.Debug("Starting repository order operation...", fields...)
+	reqctx.Logger(ctx, r.logger).Debug("Starting repository order operation...", fields...)
 }
-func (r *OrderRepository) logInfoOrderOperation(operation string, start time.Time, order entity.Order) {
+func (r *OrderRepository) logInfoOrderOperation(ctx context.Context, operation string, start time.Time, order entity.Order) {
+	metrics.ObserveRepositoryOperation("order", operation, start)
+
 	fields := append(
 		[]zap.Field{
 			zap.String("operation", operation),
@@ -239,5 +502,5 @@ func (r *OrderRepository) logInfoOrderOperation(operation string, start time.Tim
 		},
 		zaplog.OrderFields(order)...,
 	)
-	r.logger.Info("Finished repository order operation", fields...)
+	reqctx.Logger(ctx, r.logger).Info("Finished repository order operation", fields...)
 }
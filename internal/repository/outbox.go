@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type OutboxRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewOutboxRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *OutboxRepository {
+	return &OutboxRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// Claim leases up to limit due events until leaseUntil, an event that is not marked as
+// dispatched or failed by then is claimed again.
+func (r *OutboxRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entity.OutboxEvent, error) {
+	ctx, span := tracing.Start(ctx, "OutboxRepository.Claim")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Outbox.Claim")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository outbox operation...",
+		zap.String("operation", "claim"),
+		zap.Int("limit", limit),
+	)
+
+	// claim due events, the oldest pending one of every aggregate
+	rows, err := r.db.Query(ctx, postgres.ClaimOutboxSQL, now, leaseUntil, limit)
+	if err != nil {
+		return nil, handleDBError(logger, err, "claim_outbox", start, "failed to claim outbox events")
+	}
+	defer rows.Close()
+
+	events := make([]entity.OutboxEvent, 0)
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_outbox_event", start, "failed to scan outbox event")
+		}
+
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(events, func(i, j int) bool { return events[i].Id < events[j].Id })
+
+	// the dispatcher polls often, empty claims are not worth an info line
+	if len(events) > 0 {
+		r.logInfoOutboxOperation(ctx, "claim", start, len(events))
+	}
+	return events, nil
+}
+func (r *OutboxRepository) MarkDispatched(ctx context.Context, id int64, at time.Time) error {
+	ctx, span := tracing.Start(ctx, "OutboxRepository.MarkDispatched")
+	defer span.End()
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Outbox.MarkDispatched")
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := r.db.Exec(ctx, postgres.MarkDispatchedOutboxSQL, id, at)
+	if err != nil {
+		return handleDBError(reqctx.Logger(ctx, r.logger), err, "mark_dispatched_outbox", start, "failed to mark outbox event as dispatched")
+	}
+	return nil
+}
+
+// MarkFailed records a failed delivery, the event is retried at nextAttempt.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, nextAttempt time.Time, reason string) error {
+	ctx, span := tracing.Start(ctx, "OutboxRepository.MarkFailed")
+	defer span.End()
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Outbox.MarkFailed")
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := r.db.Exec(ctx, postgres.MarkFailedOutboxSQL, id, nextAttempt, reason)
+	if err != nil {
+		return handleDBError(reqctx.Logger(ctx, r.logger), err, "mark_failed_outbox", start, "failed to mark outbox event as failed")
+	}
+	return nil
+}
+
+// Purge deletes events dispatched before the given time.
+func (r *OutboxRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "OutboxRepository.Purge")
+	defer span.End()
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Outbox.Purge")
+	defer cancel()
+
+	start := time.Now()
+
+	tag, err := r.db.Exec(ctx, postgres.PurgeOutboxSQL, before)
+	if err != nil {
+		return 0, handleDBError(reqctx.Logger(ctx, r.logger), err, "purge_outbox", start, "failed to purge outbox")
+	}
+
+	deleted := int(tag.RowsAffected())
+
+	r.logInfoOutboxOperation(ctx, "purge", start, deleted)
+	return deleted, nil
+}
+
+func (r *OutboxRepository) GetById(ctx context.Context, id int64) (entity.OutboxEvent, error) {
+	ctx, span := tracing.Start(ctx, "OutboxRepository.GetById")
+	defer span.End()
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Outbox.GetById")
+	defer cancel()
+
+	start := time.Now()
+
+	// get event by id
+	e, err := scanOutboxEvent(r.db.QueryRow(ctx, postgres.GetByIdOutboxSQL, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.OutboxEvent{}, ErrEventNotFound
+	}
+	if err != nil {
+		return entity.OutboxEvent{}, handleDBError(reqctx.Logger(ctx, r.logger), err, "get_by_id_outbox", start, "failed to get outbox event")
+	}
+	return e, nil
+}
+
+// GetAfter returns up to limit events with an id greater than afterId, oldest first.
+func (r *OutboxRepository) GetAfter(ctx context.Context, afterId int64, limit int) ([]entity.OutboxEvent, error) {
+	ctx, span := tracing.Start(ctx, "OutboxRepository.GetAfter")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Outbox.GetAfter")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository outbox operation...",
+		zap.String("operation", "get_after"),
+		zap.Int64("after_id", afterId),
+		zap.Int("limit", limit),
+	)
+
+	// get events after id
+	rows, err := r.db.Query(ctx, postgres.GetAfterOutboxSQL, afterId, limit)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_after_outbox", start, "failed to get outbox events")
+	}
+	defer rows.Close()
+
+	events := make([]entity.OutboxEvent, 0)
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_outbox_event", start, "failed to scan outbox event")
+		}
+
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoOutboxOperation(ctx, "get_after", start, len(events))
+	return events, nil
+}
+
+// Listen calls fn with the id of every new event until ctx is done or the connection fails,
+// ready is called once notifications are received. It holds a connection of its own, which
+// is closed on return rather than put back in the pool.
+func (r *OutboxRepository) Listen(ctx context.Context, ready func(), fn func(id int64)) error {
+	pooled, err := r.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err = conn.Exec(ctx, postgres.ListenOutboxSQL); err != nil {
+		return fmt.Errorf("failed to listen for outbox events: %w", err)
+	}
+
+	r.logger.Info("Listening for outbox events")
+	ready()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		id, err := strconv.ParseInt(n.Payload, 10, 64)
+		if err != nil {
+			r.logger.Warn("invalid outbox notification", zap.String("payload", n.Payload))
+			continue
+		}
+		fn(id)
+	}
+}
+
+func scanOutboxEvent(row pgx.Row) (entity.OutboxEvent, error) {
+	var e entity.OutboxEvent
+	err := row.Scan(&e.Id, &e.AggregateType, &e.AggregateId, &e.EventType, &e.Payload, &e.Attempts, &e.CreatedAt)
+	return e, err
+}
+
+// insertOutboxEvent stores a domain event within the caller's transaction.
+func insertOutboxEvent(ctx context.Context, tx pgx.Tx, aggregateType string, aggregateId int, eventType string, payload any, at time.Time) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, postgres.InsertOutboxSQL, aggregateType, aggregateId, eventType, body, at)
+	return err
+}
+
+// insertPriceChangedEvent raises ProductPriceChanged if the price changed at the precision of the column.
+func insertPriceChangedEvent(ctx context.Context, tx pgx.Tx, productId int, from, to float64, source string, at time.Time) error {
+	if math.Round(from*100) == math.Round(to*100) {
+		return nil
+	}
+	return insertOutboxEvent(ctx, tx, entity.AggregateProduct, productId, entity.EventProductPriceChanged,
+		entity.ProductPriceChangedPayload{ProductId: productId, OldPrice: from, NewPrice: to, Source: source}, at)
+}
+
+// insertStockChangedEvent raises ProductStockChanged if the stock changed.
+func insertStockChangedEvent(ctx context.Context, tx pgx.Tx, productId, from, to int, at time.Time) error {
+	if from == to {
+		return nil
+	}
+	return insertOutboxEvent(ctx, tx, entity.AggregateProduct, productId, entity.EventProductStockChanged,
+		entity.ProductStockChangedPayload{ProductId: productId, OldStock: from, NewStock: to}, at)
+}
+
+// insertStockLowEvent raises StockLow when the stock drops from above the threshold to it or below.
+func insertStockLowEvent(ctx context.Context, tx pgx.Tx, productId, from, to, threshold int, at time.Time) error {
+	if from <= threshold || to > threshold {
+		return nil
+	}
+	return insertOutboxEvent(ctx, tx, entity.AggregateProduct, productId, entity.EventStockLow,
+		entity.StockLowPayload{ProductId: productId, Stock: to, Threshold: threshold}, at)
+}
+
+// insertStatusChangedEvent raises OrderStatusChanged if the status changed.
+func insertStatusChangedEvent(ctx context.Context, tx pgx.Tx, orderId int, from, to string, at time.Time) error {
+	if from == to {
+		return nil
+	}
+	return insertOutboxEvent(ctx, tx, entity.AggregateOrder, orderId, entity.EventOrderStatusChanged,
+		entity.OrderStatusChangedPayload{OrderId: orderId, From: from, To: to}, at)
+}
+
+func (r *OutboxRepository) logInfoOutboxOperation(ctx context.Context, operation string, start time.Time, count int) {
+	metrics.ObserveRepositoryOperation("outbox", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository outbox operation",
+		zap.String("operation", operation),
+		zap.Int("count", count),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
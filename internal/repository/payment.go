@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"math"
+	"time"
+)
+
+type PaymentRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewPaymentRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *PaymentRepository {
+	return &PaymentRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// Create stores a new payment attempt of an order. The order is locked while it is checked
+// for other payments, so only one attempt at a time can be authorized and captured.
+func (r *PaymentRepository) Create(ctx context.Context, p entity.Payment) (int, error) {
+	ctx, span := tracing.Start(ctx, "PaymentRepository.Create")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Payment.Create")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository payment operation...",
+		zap.String("operation", "insert"),
+		zap.Int("order_id", p.OrderId),
+		zap.String("provider", p.Provider),
+		zap.Float64("amount", p.Amount),
+		zap.String("currency", p.Currency),
+	)
+
+	// lock the order so concurrent attempts are checked one by one
+	var orderId int
+	err = tx.QueryRow(ctx, postgres.LockOrdersSQL, p.OrderId).Scan(&orderId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		return 0, ErrOrderNotFound
+	}
+	if err != nil {
+		return 0, handleDBError(logger, err, "lock_order", start, "failed to lock order")
+	}
+
+	// a declined, failed or voided attempt may be retried, any other blocks a new one
+	var open bool
+	if err = tx.QueryRow(ctx, postgres.HasOpenPaymentsSQL, p.OrderId).Scan(&open); err != nil {
+		return 0, handleDBError(logger, err, "has_open_payments", start, "failed to check payments")
+	}
+	if open {
+		return 0, ErrPaymentInProgress
+	}
+
+	var id int
+
+	// payment insert, returning 'id'
+	err = tx.QueryRow(ctx, postgres.InsertPaymentsSQL,
+		p.OrderId, p.Provider, p.Status, p.Amount, p.Currency, start,
+	).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_payment", start, "failed to insert payment")
+	}
+
+	r.logInfoPaymentOperation(ctx, "insert", start, id)
+	return id, nil
+}
+func (r *PaymentRepository) GetById(ctx context.Context, id int) (entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentRepository.GetById")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Payment.GetById")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository payment operation...",
+		zap.String("operation", "get_by_id"),
+		zap.Int("id", id),
+	)
+
+	// get payment by id
+	p, err := scanPayment(r.db.QueryRow(ctx, postgres.GetByIdPaymentsSQL, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Payment{}, ErrPaymentNotFound
+	}
+	if err != nil {
+		return entity.Payment{}, handleDBError(logger, err, "get_by_id_payment", start, "failed to get payment by id")
+	}
+
+	r.logInfoPaymentOperation(ctx, "get_by_id", start, id)
+	return p, nil
+}
+func (r *PaymentRepository) GetByOrderId(ctx context.Context, orderId int) ([]entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentRepository.GetByOrderId")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Payment.GetByOrderId")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository payment operation...",
+		zap.String("operation", "get_by_order_id"),
+		zap.Int("order_id", orderId),
+	)
+
+	// get payments by order id
+	rows, err := r.db.Query(ctx, postgres.GetByOrderIdPaymentsSQL, orderId)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_by_order_id_payments", start, "failed to get payments")
+	}
+	defer rows.Close()
+
+	payments := make([]entity.Payment, 0)
+
+	// rows parsing
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_payment", start, "failed to scan payment")
+		}
+
+		payments = append(payments, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoPaymentOperation(ctx, "get_by_order_id", start, 0)
+	return payments, nil
+}
+
+// Update stores the payment state; a captured payment also marks its order as paid.
+func (r *PaymentRepository) Update(ctx context.Context, p entity.Payment) error {
+	ctx, span := tracing.Start(ctx, "PaymentRepository.Update")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Payment.Update")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository payment operation...",
+		zap.String("operation", "update"),
+		zap.Int("id", p.Id),
+		zap.String("status", p.Status),
+	)
+
+	if err = savePayment(ctx, tx, p, start); err != nil {
+		return handleDBError(logger, err, "update_payment", start, "failed to update payment")
+	}
+
+	r.logInfoPaymentOperation(ctx, "update", start, p.Id)
+	return nil
+}
+
+// ReserveRefund adds amount to the refunded amount of a captured payment and moves it to
+// partially refunded or refunded. It returns ErrRefundNotAllowed if the payment is not
+// refundable or the amount exceeds what is left.
+func (r *PaymentRepository) ReserveRefund(ctx context.Context, id int, amount float64) (entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentRepository.ReserveRefund")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Payment.ReserveRefund")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository payment operation...",
+		zap.String("operation", "reserve_refund"),
+		zap.Int("id", id),
+		zap.Float64("amount", amount),
+	)
+
+	p, err := scanPayment(r.db.QueryRow(ctx, postgres.ReserveRefundPaymentsSQL, id, amount, start))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Payment{}, ErrRefundNotAllowed
+	}
+	if err != nil {
+		return entity.Payment{}, handleDBError(logger, err, "reserve_refund_payment", start, "failed to reserve refund")
+	}
+
+	r.logInfoPaymentOperation(ctx, "reserve_refund", start, id)
+	return p, nil
+}
+
+// ReleaseRefund takes back a refund reserved with ReserveRefund that was not made.
+func (r *PaymentRepository) ReleaseRefund(ctx context.Context, id int, amount float64) error {
+	ctx, span := tracing.Start(ctx, "PaymentRepository.ReleaseRefund")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Payment.ReleaseRefund")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository payment operation...",
+		zap.String("operation", "release_refund"),
+		zap.Int("id", id),
+		zap.Float64("amount", amount),
+	)
+
+	if _, err := r.db.Exec(ctx, postgres.ReleaseRefundPaymentsSQL, id, amount, start); err != nil {
+		return handleDBError(logger, err, "release_refund_payment", start, "failed to release refund")
+	}
+
+	r.logInfoPaymentOperation(ctx, "release_refund", start, id)
+	return nil
+}
+
+// ApplyEvent records a provider callback and moves the payment to the reported status.
+// It returns false without changes if the event was already received.
+func (r *PaymentRepository) ApplyEvent(ctx context.Context, event entity.PaymentEvent) (entity.Payment, bool, error) {
+	ctx, span := tracing.Start(ctx, "PaymentRepository.ApplyEvent")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Payment.ApplyEvent")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return entity.Payment{}, false, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository payment operation...",
+		zap.String("operation", "apply_event"),
+		zap.String("provider", event.Provider),
+		zap.String("event_id", event.EventId),
+		zap.String("status", event.Status),
+	)
+
+	// lock the payment so concurrent callbacks are applied one by one
+	p, err := scanPayment(tx.QueryRow(ctx, postgres.GetByProviderRefForUpdatePaymentsSQL, event.Provider, event.ProviderRef))
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		return entity.Payment{}, false, ErrPaymentNotFound
+	}
+	if err != nil {
+		return entity.Payment{}, false, handleDBError(logger, err, "get_by_provider_ref_payment", start, "failed to get payment")
+	}
+
+	// event insert, a conflict means a duplicate delivery
+	var eventId int
+	err = tx.QueryRow(ctx, postgres.InsertPaymentEventsSQL,
+		event.Provider, event.EventId, p.Id, event.Status, event.Payload, start,
+	).Scan(&eventId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		logger.Info("duplicate payment event skipped",
+			zap.String("provider", event.Provider),
+			zap.String("event_id", event.EventId),
+		)
+		return p, false, nil
+	}
+	if err != nil {
+		return entity.Payment{}, false, handleDBError(logger, err, "insert_payment_event", start, "failed to insert payment event")
+	}
+
+	// a capture of another amount than authorized is recorded but not applied
+	if event.Status == entity.PaymentStatusCaptured && math.Abs(event.Amount-p.Amount) >= 0.005 {
+		logger.Warn("payment event amount differs from the payment amount",
+			zap.Int("id", p.Id),
+			zap.Float64("amount", p.Amount),
+			zap.Float64("event_amount", event.Amount),
+		)
+		return p, false, ErrPaymentAmountMismatch
+	}
+
+	// out of order events are recorded but do not move the payment back
+	if !entity.CanTransitionPayment(p.Status, event.Status) {
+		logger.Warn("payment event does not change payment status",
+			zap.Int("id", p.Id),
+			zap.String("from", p.Status),
+			zap.String("to", event.Status),
+		)
+		return p, false, nil
+	}
+
+	p.Status = event.Status
+	if p.Status == entity.PaymentStatusRefunded {
+		p.RefundedAmount = p.Amount
+	}
+	if err = savePayment(ctx, tx, p, start); err != nil {
+		return entity.Payment{}, false, handleDBError(logger, err, "update_payment", start, "failed to update payment")
+	}
+
+	r.logInfoPaymentOperation(ctx, "apply_event", start, p.Id)
+	return p, true, nil
+}
+
+// savePayment updates the payment and marks the order as paid once the payment is captured.
+func savePayment(ctx context.Context, tx pgx.Tx, p entity.Payment, at time.Time) error {
+	_, err := tx.Exec(ctx, postgres.UpdatePaymentsSQL,
+		p.Id, p.ProviderRef, p.Status, p.RefundedAmount, p.FailureReason, at,
+	)
+	if err != nil || p.Status != entity.PaymentStatusCaptured {
+		return err
+	}
+
+	var prevStatus string
+	err = tx.QueryRow(ctx, postgres.MarkPaidOrdersSQL, p.OrderId).Scan(&prevStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// the order is already paid or further along
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return insertStatusChangedEvent(ctx, tx, p.OrderId, prevStatus, entity.OrderStatusPaid, at)
+}
+
+func scanPayment(row pgx.Row) (entity.Payment, error) {
+	var p entity.Payment
+	err := row.Scan(
+		&p.Id,
+		&p.OrderId,
+		&p.Provider,
+		&p.ProviderRef,
+		&p.Status,
+		&p.Amount,
+		&p.Currency,
+		&p.RefundedAmount,
+		&p.FailureReason,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	return p, err
+}
+
+func (r *PaymentRepository) logInfoPaymentOperation(ctx context.Context, operation string, start time.Time, id int) {
+	metrics.ObserveRepositoryOperation("payment", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository payment operation",
+		zap.String("operation", operation),
+		zap.Int("id", id),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
@@ -0,0 +1,375 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type PriceRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewPriceRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *PriceRepository {
+	return &PriceRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *PriceRepository) GetHistory(ctx context.Context, productId int) ([]entity.PriceChange, error) {
+	ctx, span := tracing.Start(ctx, "PriceRepository.GetHistory")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Price.GetHistory")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository price operation...",
+		zap.String("operation", "get_history"),
+		zap.Int("product_id", productId),
+	)
+
+	// get price history by product id
+	rows, err := r.db.Query(ctx, postgres.GetByProductIdPriceHistorySQL, productId)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_by_product_id_price_history", start, "failed to get price history")
+	}
+	defer rows.Close()
+
+	history := make([]entity.PriceChange, 0)
+
+	// rows parsing
+	for rows.Next() {
+		var change entity.PriceChange
+
+		err = rows.Scan(&change.Id, &change.ProductId, &change.Price, &change.Source, &change.ChangedAt)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_price_history", start, "failed to scan price history")
+		}
+
+		history = append(history, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoPriceOperation(ctx, "get_history", start, productId)
+	return history, nil
+}
+func (r *PriceRepository) GetPriceAt(ctx context.Context, productId int, at time.Time) (float64, error) {
+	ctx, span := tracing.Start(ctx, "PriceRepository.GetPriceAt")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Price.GetPriceAt")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository price operation...",
+		zap.String("operation", "get_price_at"),
+		zap.Int("product_id", productId),
+		zap.Time("at", at),
+	)
+
+	var price float64
+
+	// get the latest recorded price at the given moment
+	err := r.db.QueryRow(ctx, postgres.GetPriceAtPriceHistorySQL, productId, at).Scan(&price)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrPriceNotFound
+	}
+	if err != nil {
+		return 0, handleDBError(logger, err, "get_price_at_price_history", start, "failed to get price at date")
+	}
+
+	r.logInfoPriceOperation(ctx, "get_price_at", start, productId)
+	return price, nil
+}
+
+func (r *PriceRepository) CreateSchedule(ctx context.Context, sp entity.ScheduledPrice) (int, error) {
+	ctx, span := tracing.Start(ctx, "PriceRepository.CreateSchedule")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Price.CreateSchedule")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository price operation...",
+		zap.String("operation", "create_schedule"),
+		zap.Int("product_id", sp.ProductId),
+		zap.Float64("price", sp.Price),
+		zap.Time("starts_at", sp.StartsAt),
+	)
+
+	var id int
+
+	// scheduled price insert, returning 'id'
+	err := r.db.QueryRow(ctx, postgres.InsertScheduledPricesSQL,
+		sp.ProductId, sp.Price, sp.StartsAt, sp.EndsAt, entity.ScheduledPriceStatusPending, start,
+	).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_scheduled_price", start, "failed to insert scheduled price")
+	}
+
+	r.logInfoPriceOperation(ctx, "create_schedule", start, sp.ProductId)
+	return id, nil
+}
+func (r *PriceRepository) GetSchedules(ctx context.Context, productId int) ([]entity.ScheduledPrice, error) {
+	ctx, span := tracing.Start(ctx, "PriceRepository.GetSchedules")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Price.GetSchedules")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository price operation...",
+		zap.String("operation", "get_schedules"),
+		zap.Int("product_id", productId),
+	)
+
+	// get scheduled prices by product id
+	rows, err := r.db.Query(ctx, postgres.GetByProductIdScheduledPricesSQL, productId)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_by_product_id_scheduled_prices", start, "failed to get scheduled prices")
+	}
+	defer rows.Close()
+
+	schedules := make([]entity.ScheduledPrice, 0)
+
+	// rows parsing
+	for rows.Next() {
+		var sp entity.ScheduledPrice
+
+		err = rows.Scan(
+			&sp.Id,
+			&sp.ProductId,
+			&sp.Price,
+			&sp.PreviousPrice,
+			&sp.StartsAt,
+			&sp.EndsAt,
+			&sp.Status,
+			&sp.CreatedAt,
+		)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_scheduled_price", start, "failed to scan scheduled price")
+		}
+
+		schedules = append(schedules, sp)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoPriceOperation(ctx, "get_schedules", start, productId)
+	return schedules, nil
+}
+func (r *PriceRepository) CancelSchedule(ctx context.Context, productId, id int) error {
+	ctx, span := tracing.Start(ctx, "PriceRepository.CancelSchedule")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Price.CancelSchedule")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository price operation...",
+		zap.String("operation", "cancel_schedule"),
+		zap.Int("product_id", productId),
+		zap.Int("id", id),
+	)
+
+	// cancel pending scheduled price
+	tag, err := r.db.Exec(ctx, postgres.CancelScheduledPricesSQL, id, productId)
+	if err != nil {
+		return handleDBError(logger, err, "cancel_scheduled_price", start, "failed to cancel scheduled price")
+	}
+
+	// cancel result check
+	if tag.RowsAffected() == 0 {
+		return ErrScheduleNotPending
+	}
+
+	r.logInfoPriceOperation(ctx, "cancel_schedule", start, productId)
+	return nil
+}
+
+// ApplyDue activates pending scheduled prices whose start has passed and reverts
+// active ones whose end has passed. It returns the number of applied transitions.
+func (r *PriceRepository) ApplyDue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "PriceRepository.ApplyDue")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Price.ApplyDue")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository price operation...",
+		zap.String("operation", "apply_due"),
+		zap.Time("now", now),
+	)
+
+	// collect due to start schedules
+	toStart, err := collectSchedules(ctx, tx, postgres.GetDueToStartScheduledPricesSQL, now, false)
+	if err != nil {
+		return 0, handleDBError(logger, err, "get_due_to_start_scheduled_prices", start, "failed to get due scheduled prices")
+	}
+
+	for _, sp := range toStart {
+		var current float64
+
+		// lock product and remember its current price
+		err = tx.QueryRow(ctx, postgres.GetPriceForUpdateProductsSQL, sp.ProductId).Scan(&current)
+		if err != nil {
+			return 0, handleDBError(logger, err, "get_price_for_update_product", start, "failed to get product price")
+		}
+
+		if err = setProductPrice(ctx, tx, sp.ProductId, current, sp.Price, entity.PriceSourceScheduled, now); err != nil {
+			return 0, handleDBError(logger, err, "update_price_product", start, "failed to apply scheduled price")
+		}
+
+		// one-off price changes are done at once, sales stay active until their end
+		status := entity.ScheduledPriceStatusActive
+		if sp.EndsAt == nil {
+			status = entity.ScheduledPriceStatusCompleted
+		}
+
+		_, err = tx.Exec(ctx, postgres.UpdateStatusScheduledPricesSQL, sp.Id, status, current)
+		if err != nil {
+			return 0, handleDBError(logger, err, "update_status_scheduled_price", start, "failed to update scheduled price status")
+		}
+	}
+
+	// collect due to end schedules, including the ones activated above
+	toEnd, err := collectSchedules(ctx, tx, postgres.GetDueToEndScheduledPricesSQL, now, true)
+	if err != nil {
+		return 0, handleDBError(logger, err, "get_due_to_end_scheduled_prices", start, "failed to get ended scheduled prices")
+	}
+
+	for _, sp := range toEnd {
+		var current float64
+
+		// lock product and check its current price
+		err = tx.QueryRow(ctx, postgres.GetPriceForUpdateProductsSQL, sp.ProductId).Scan(&current)
+		if err != nil {
+			return 0, handleDBError(logger, err, "get_price_for_update_product", start, "failed to get product price")
+		}
+
+		// price is reverted only if nobody changed it manually during the sale
+		if sp.PreviousPrice != nil && current == sp.Price {
+			err = setProductPrice(ctx, tx, sp.ProductId, current, *sp.PreviousPrice, entity.PriceSourceScheduleEnd, now)
+			if err != nil {
+				return 0, handleDBError(logger, err, "update_price_product", start, "failed to revert scheduled price")
+			}
+		}
+
+		_, err = tx.Exec(ctx, postgres.UpdateStatusScheduledPricesSQL, sp.Id, entity.ScheduledPriceStatusCompleted, nil)
+		if err != nil {
+			return 0, handleDBError(logger, err, "update_status_scheduled_price", start, "failed to update scheduled price status")
+		}
+	}
+
+	applied := len(toStart) + len(toEnd)
+
+	logger.Info("Finished repository price operation",
+		zap.String("operation", "apply_due"),
+		zap.Int("started", len(toStart)),
+		zap.Int("ended", len(toEnd)),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+	return applied, nil
+}
+
+// collectSchedules reads all due schedules before any of them is processed,
+// since a connection can not run other statements while rows are open.
+func collectSchedules(ctx context.Context, tx pgx.Tx, sql string, now time.Time, withPrevious bool) ([]entity.ScheduledPrice, error) {
+	rows, err := tx.Query(ctx, sql, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]entity.ScheduledPrice, 0)
+	for rows.Next() {
+		var sp entity.ScheduledPrice
+
+		dest := []any{&sp.Id, &sp.ProductId, &sp.Price}
+		if withPrevious {
+			dest = append(dest, &sp.PreviousPrice)
+		} else {
+			dest = append(dest, &sp.EndsAt)
+		}
+
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, sp)
+	}
+
+	return schedules, rows.Err()
+}
+
+// setProductPrice updates product price from the current one, records the change in price
+// history and raises ProductPriceChanged.
+func setProductPrice(ctx context.Context, tx pgx.Tx, productId int, current, price float64, source string, at time.Time) error {
+	if _, err := tx.Exec(ctx, postgres.UpdatePriceProductsSQL, productId, price); err != nil {
+		return err
+	}
+	if err := insertPriceHistory(ctx, tx, productId, price, source, at); err != nil {
+		return err
+	}
+	return insertPriceChangedEvent(ctx, tx, productId, current, price, source, at)
+}
+
+// insertPriceHistory records a product price, skipping it if the price did not change.
+func insertPriceHistory(ctx context.Context, tx pgx.Tx, productId int, price float64, source string, at time.Time) error {
+	_, err := tx.Exec(ctx, postgres.InsertPriceHistorySQL, productId, price, source, at)
+	return err
+}
+
+func (r *PriceRepository) logInfoPriceOperation(ctx context.Context, operation string, start time.Time, productId int) {
+	metrics.ObserveRepositoryOperation("price", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository price operation",
+		zap.String("operation", operation),
+		zap.Int("product_id", productId),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
@@ -2,28 +2,39 @@ package repository
 
 import (
 	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
 	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
 	"context"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 	"time"
 )
 
 type ProductRepository struct {
-	db     *pgxpool.Pool
-	logger *zap.Logger
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
 }
 
-func NewProductRepository(db *pgxpool.Pool, logger *zap.Logger) *ProductRepository {
+func NewProductRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *ProductRepository {
 	return &ProductRepository{
-		db:     db,
-		logger: logger,
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
 	}
 }
 
 func (r *ProductRepository) GetByIds(ctx context.Context, ids []int) ([]entity.BaseProduct, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, span := tracing.Start(ctx, "ProductRepository.GetByIds")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Product.GetByIds")
 	defer cancel()
 
 	start := time.Now()
@@ -35,12 +46,12 @@ func (r *ProductRepository) GetByIds(ctx context.Context, ids []int) ([]entity.B
 		logFields = append(logFields, zap.Int(fmt.Sprintf("id_%d", id), id))
 	}
 
-	r.logger.Debug("Starting repository products operation...", logFields...)
+	logger.Debug("Starting repository products operation...", logFields...)
 
 	// get products by ids
 	rows, err := r.db.Query(ctx, postgres.GetByIdsProductsSQL, ids)
 	if err != nil {
-		return nil, handleDBError(r.logger, err, "get_by_ids_products", start, "failed to get products by ids")
+		return nil, handleDBError(logger, err, "get_by_ids_products", start, "failed to get products by ids")
 	}
 	defer rows.Close()
 
@@ -57,30 +68,47 @@ func (r *ProductRepository) GetByIds(ctx context.Context, ids []int) ([]entity.B
 			&product.Name,
 			&product.Price,
 			&product.Stock,
+			&product.TaxClass,
+			&product.WeightGrams,
+			&product.Version,
 			&product.CreatedAt,
+			&product.DeletedAt,
 		)
 
 		if err != nil {
-			return nil, handleDBError(r.logger, err, "scan_product", start, "failed to scan product")
+			return nil, handleDBError(logger, err, "scan_product", start, "failed to scan product")
 		}
 
 		products = append(products, product)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, handleDBError(r.logger, err, "rows_err", start, "failed during rows iteration")
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
 	}
 
-	r.logInfoProductOperation("get_by_ids", start)
+	r.logInfoProductOperation(ctx, "get_by_ids", start)
 	return products, nil
 }
 
-func (r *ProductRepository) logInfoProductOperation(operation string, start time.Time) {
-	fields := append(
-		[]zap.Field{
-			zap.String("operation", operation),
-			zap.Duration("elapsed", time.Since(start)),
-		},
+// deleteConflict explains why a product of type kind with an expected version was not
+// deleted: ErrProductNotFound if it does not exist or is in the trash, ErrVersionConflict
+// otherwise.
+func deleteConflict(ctx context.Context, tx pgx.Tx, logger *zap.Logger, id int, kind string, start time.Time) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, postgres.ExistsProductsSQL, id, kind).Scan(&exists); err != nil {
+		return handleDBError(logger, err, "exists_product", start, "failed to check product existence")
+	}
+	if !exists {
+		return ErrProductNotFound
+	}
+	return ErrVersionConflict
+}
+
+func (r *ProductRepository) logInfoProductOperation(ctx context.Context, operation string, start time.Time) {
+	metrics.ObserveRepositoryOperation("product", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository product operation",
+		zap.String("operation", operation),
+		zap.Duration("elapsed", time.Since(start)),
 	)
-	r.logger.Info("Finished repository product operation", fields...)
 }
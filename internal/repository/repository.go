@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"BookStore_API/internal/config"
 	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
 	"context"
 	"errors"
 	"fmt"
@@ -12,51 +14,227 @@ import (
 )
 
 var (
-	ErrInvalidProductType = errors.New("invalid product type")
-	ErrDBOperation        = errors.New("database operation failed")
+	ErrInvalidProductType      = errors.New("invalid product type")
+	ErrDBOperation             = errors.New("database operation failed")
+	ErrScheduleNotPending      = errors.New("scheduled price not found or not pending")
+	ErrPriceNotFound           = errors.New("no price recorded at the given time")
+	ErrRateNotFound            = errors.New("exchange rate not found")
+	ErrShippingZoneNotFound    = errors.New("no shipping zone for destination")
+	ErrPaymentNotFound         = errors.New("payment not found")
+	ErrPaymentInProgress       = errors.New("order already has a payment in progress or completed")
+	ErrPaymentAmountMismatch   = errors.New("captured amount differs from the payment amount")
+	ErrRefundNotAllowed        = errors.New("payment can not be refunded by the amount")
+	ErrVersionConflict         = errors.New("resource was modified by another request")
+	ErrProductNotFound         = errors.New("product not found")
+	ErrOrderNotFound           = errors.New("order not found")
+	ErrNotInTrash              = errors.New("resource not found in trash")
+	ErrReturnNotFound          = errors.New("return not found")
+	ErrReturnStatus            = errors.New("operation is not allowed in the current return status")
+	ErrReturnQuantity          = errors.New("returned quantity exceeds the ordered quantity")
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrEventNotFound           = errors.New("event not found")
 )
 
+type Health interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (uint, bool, error)
+	PoolStats() entity.PoolStats
+}
+
 type Product interface {
 	GetByIds(ctx context.Context, ids []int) ([]entity.BaseProduct, error)
 }
 
+type Price interface {
+	GetHistory(ctx context.Context, productId int) ([]entity.PriceChange, error)
+	GetPriceAt(ctx context.Context, productId int, at time.Time) (float64, error)
+	CreateSchedule(ctx context.Context, sp entity.ScheduledPrice) (int, error)
+	GetSchedules(ctx context.Context, productId int) ([]entity.ScheduledPrice, error)
+	CancelSchedule(ctx context.Context, productId, id int) error
+	ApplyDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type Currency interface {
+	CreateRate(ctx context.Context, rate entity.ExchangeRate) (int, error)
+	GetRates(ctx context.Context, currency string) ([]entity.ExchangeRate, error)
+	GetRateAt(ctx context.Context, currency string, at time.Time) (float64, error)
+	SetPriceOverride(ctx context.Context, po entity.PriceOverride) error
+	DeletePriceOverride(ctx context.Context, productId int, currency string) error
+	GetPriceOverrides(ctx context.Context, productId int) ([]entity.PriceOverride, error)
+	GetPriceOverridesByIds(ctx context.Context, productIds []int, currency string) ([]entity.PriceOverride, error)
+}
+
+type Tax interface {
+	CreateRule(ctx context.Context, rule entity.TaxRule) (int, error)
+	GetRules(ctx context.Context, country string) ([]entity.TaxRule, error)
+	GetRulesForDestination(ctx context.Context, dest entity.Address) ([]entity.TaxRule, error)
+	DeleteRule(ctx context.Context, id int) error
+}
+
+type Shipping interface {
+	CreateZone(ctx context.Context, zone entity.ShippingZone) (int, error)
+	GetZones(ctx context.Context) ([]entity.ShippingZone, error)
+	GetZoneForCountry(ctx context.Context, country string) (entity.ShippingZone, error)
+	DeleteZone(ctx context.Context, id int) error
+	CreateMethod(ctx context.Context, method entity.ShippingMethod) (int, error)
+	DeleteMethod(ctx context.Context, id int) error
+}
+
+type Payment interface {
+	Create(ctx context.Context, p entity.Payment) (int, error)
+	GetById(ctx context.Context, id int) (entity.Payment, error)
+	GetByOrderId(ctx context.Context, orderId int) ([]entity.Payment, error)
+	Update(ctx context.Context, p entity.Payment) error
+	ReserveRefund(ctx context.Context, id int, amount float64) (entity.Payment, error)
+	ReleaseRefund(ctx context.Context, id int, amount float64) error
+	ApplyEvent(ctx context.Context, event entity.PaymentEvent) (entity.Payment, bool, error)
+}
+
+type Return interface {
+	Create(ctx context.Context, ret entity.OrderReturn, ordered map[int]int) (int, error)
+	GetById(ctx context.Context, id int) (entity.OrderReturn, error)
+	GetByOrderId(ctx context.Context, orderId int) ([]entity.OrderReturn, error)
+	UpdateStatus(ctx context.Context, id int, from, to, note string) error
+	Receive(ctx context.Context, ret entity.OrderReturn) error
+	ClaimRefund(ctx context.Context, id int) error
+	ReleaseRefund(ctx context.Context, id int) error
+	MarkRefunded(ctx context.Context, id, paymentId int, amount float64) error
+}
+
+type Idempotency interface {
+	Acquire(ctx context.Context, rec entity.IdempotencyRecord, staleBefore time.Time) (entity.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, key, route string, status int, body []byte) error
+	Release(ctx context.Context, key, route string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type Trash interface {
+	GetAll(ctx context.Context) ([]entity.TrashItem, error)
+	RestoreProduct(ctx context.Context, id int) error
+	RestoreOrder(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+type Audit interface {
+	Get(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error)
+}
+
+type Outbox interface {
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entity.OutboxEvent, error)
+	MarkDispatched(ctx context.Context, id int64, at time.Time) error
+	MarkFailed(ctx context.Context, id int64, nextAttempt time.Time, reason string) error
+	Purge(ctx context.Context, before time.Time) (int, error)
+	GetById(ctx context.Context, id int64) (entity.OutboxEvent, error)
+	GetAfter(ctx context.Context, afterId int64, limit int) ([]entity.OutboxEvent, error)
+	Listen(ctx context.Context, ready func(), fn func(id int64)) error
+}
+
+type Webhook interface {
+	CreateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) (int, error)
+	GetEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error)
+	GetEndpointById(ctx context.Context, id int) (entity.WebhookEndpoint, error)
+	UpdateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) error
+	DeleteEndpoint(ctx context.Context, id int) error
+	Enqueue(ctx context.Context, event entity.OutboxEvent, payload []byte, at time.Time) (int, error)
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entity.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, attempt entity.WebhookAttempt, disableAfter int) (bool, error)
+	GetDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error)
+	Redeliver(ctx context.Context, endpointId int, deliveryId int64, at time.Time) (int64, error)
+}
+
 type Book interface {
-	Create(ctx context.Context, book entity.Book) (int, error)
+	Create(ctx context.Context, book entity.Book, audit *entity.AuditEntry) (int, error)
 	GetById(ctx context.Context, id int) (entity.Book, error)
-	Update(ctx context.Context, book entity.Book) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, book entity.Book, audit *entity.AuditEntry) error
+	Delete(ctx context.Context, id, version int, audit *entity.AuditEntry) error
 	IsbnExists(ctx context.Context, isbn string) (bool, error)
 }
 
 type Magazine interface {
-	Create(ctx context.Context, mag entity.Magazine) (int, error)
+	Create(ctx context.Context, mag entity.Magazine, audit *entity.AuditEntry) (int, error)
 	GetById(ctx context.Context, id int) (entity.Magazine, error)
-	Update(ctx context.Context, mag entity.Magazine) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, mag entity.Magazine, audit *entity.AuditEntry) error
+	Delete(ctx context.Context, id, version int, audit *entity.AuditEntry) error
 	ExistsIssueNumber(ctx context.Context, issueNumber int) (bool, error)
 }
 
 type Order interface {
-	Create(ctx context.Context, order entity.Order) (int, error)
+	Create(ctx context.Context, order entity.Order, audit *entity.AuditEntry) (int, error)
 	GetById(ctx context.Context, id int) (entity.Order, error)
-	Update(ctx context.Context, order entity.Order) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, order entity.Order, audit *entity.AuditEntry) error
+	Delete(ctx context.Context, id, version int, audit *entity.AuditEntry) error
+	GetHistory(ctx context.Context, orderId int) ([]entity.OrderHistoryEntry, error)
+}
+
+type Stats interface {
+	Get(ctx context.Context) (entity.BusinessStats, error)
 }
 
 type Repository struct {
+	Health
+	Stats
 	Product
+	Price
+	Currency
+	Tax
+	Shipping
+	Payment
+	Return
+	Idempotency
+	Trash
+	Audit
+	Outbox
+	Webhook
 	Book
 	Magazine
 	Order
 }
 
-func NewRepository(db *pgxpool.Pool, logger *zap.Logger) *Repository {
+func NewRepository(db *pgxpool.Pool, cfg *config.Config, logger *zap.Logger) *Repository {
+	timeouts := Timeouts{
+		Default:    cfg.DBCfg.OperationTimeout,
+		Operations: cfg.DBCfg.OperationTimeouts,
+	}
+
 	return &Repository{
-		Product:  NewProductRepository(db, logger),
-		Book:     NewBookRepository(db, logger),
-		Magazine: NewMagazineRepository(db, logger),
-		Order:    NewOrderRepository(db, logger),
+		Health:      NewHealthRepository(db, logger),
+		Stats:       NewStatsRepository(db, timeouts, cfg.LowStockThreshold, logger),
+		Product:     NewProductRepository(db, timeouts, logger),
+		Price:       NewPriceRepository(db, timeouts, logger),
+		Currency:    NewCurrencyRepository(db, timeouts, logger),
+		Tax:         NewTaxRepository(db, timeouts, logger),
+		Shipping:    NewShippingRepository(db, timeouts, logger),
+		Payment:     NewPaymentRepository(db, timeouts, logger),
+		Return:      NewReturnRepository(db, timeouts, logger),
+		Idempotency: NewIdempotencyRepository(db, timeouts, logger),
+		Trash:       NewTrashRepository(db, timeouts, logger),
+		Audit:       NewAuditRepository(db, timeouts, logger),
+		Outbox:      NewOutboxRepository(db, timeouts, logger),
+		Webhook:     NewWebhookRepository(db, timeouts, logger),
+		Book:        NewBookRepository(db, timeouts, cfg.LowStockThreshold, logger),
+		Magazine:    NewMagazineRepository(db, timeouts, cfg.LowStockThreshold, logger),
+		Order:       NewOrderRepository(db, timeouts, logger),
+	}
+}
+
+// Timeouts bound repository operations. Every operation takes at most Default unless
+// Operations, keyed by repository and method as "Trash.Purge", has a timeout of its own.
+type Timeouts struct {
+	Default    time.Duration
+	Operations map[string]time.Duration
+}
+
+// withTimeout derives the context of an operation, a timeout of zero or less means none.
+func (t Timeouts) withTimeout(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	timeout, ok := t.Operations[operation]
+	if !ok {
+		timeout = t.Default
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 func finalizeTx(logger *zap.Logger, ctx context.Context, tx pgx.Tx, err *error) {
@@ -74,6 +252,7 @@ func finalizeTx(logger *zap.Logger, ctx context.Context, tx pgx.Tx, err *error)
 
 func handleDBError(logger *zap.Logger, err error, operation string, start time.Time, msg string) error {
 	if errors.Is(err, context.DeadlineExceeded) {
+		metrics.RepositoryError(operation, "timeout")
 		logger.Error(msg,
 			zap.String("operation", operation),
 			zap.Duration("elapsed", time.Since(start)),
@@ -83,6 +262,7 @@ func handleDBError(logger *zap.Logger, err error, operation string, start time.T
 		return fmt.Errorf("%w(%s): timeout: %w", ErrDBOperation, operation, err)
 	}
 
+	metrics.RepositoryError(operation, "error")
 	logger.Error(msg,
 		zap.String("operation", operation),
 		zap.Duration("elapsed", time.Since(start)),
@@ -0,0 +1,475 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+// statusEvents maps a return status to the order history event recorded when it is reached.
+var statusEvents = map[string]string{
+	entity.ReturnStatusRequested: entity.OrderEventReturnRequested,
+	entity.ReturnStatusApproved:  entity.OrderEventReturnApproved,
+	entity.ReturnStatusRejected:  entity.OrderEventReturnRejected,
+	entity.ReturnStatusReceived:  entity.OrderEventReturnReceived,
+	entity.ReturnStatusRefunded:  entity.OrderEventReturnRefunded,
+}
+
+type ReturnRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewReturnRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *ReturnRepository {
+	return &ReturnRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// Create stores a return after checking, under the order lock, that together with earlier
+// returns no more than the ordered quantity of each product is sent back.
+func (r *ReturnRepository) Create(ctx context.Context, ret entity.OrderReturn, ordered map[int]int) (int, error) {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.Create")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Return.Create")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository return operation...",
+		zap.String("operation", "insert"),
+		zap.Int("order_id", ret.OrderId),
+		zap.Int("item_count", len(ret.Items)),
+	)
+
+	// lock the order so concurrent returns see each other
+	var orderId int
+	if err = tx.QueryRow(ctx, postgres.LockOrdersSQL, ret.OrderId).Scan(&orderId); err != nil {
+		return 0, handleDBError(logger, err, "lock_order", start, "failed to lock order")
+	}
+
+	returned, err := returnedQuantities(ctx, tx, ret.OrderId)
+	if err != nil {
+		return 0, handleDBError(logger, err, "get_returned_quantities", start, "failed to get returned quantities")
+	}
+
+	for _, item := range ret.Items {
+		if returned[item.ProductId]+item.Quantity > ordered[item.ProductId] {
+			err = nil
+			return 0, ErrReturnQuantity
+		}
+	}
+
+	var id int
+
+	// return insert, returning 'id'
+	err = tx.QueryRow(ctx, postgres.InsertReturnsSQL,
+		ret.OrderId, entity.ReturnStatusRequested, ret.Reason, ret.RefundAmount, start,
+	).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_return", start, "failed to insert return")
+	}
+
+	// return items insert
+	for _, item := range ret.Items {
+		_, err = tx.Exec(ctx, postgres.InsertReturnItemsSQL, id, item.ProductId, item.Quantity, item.Price, item.TaxAmount)
+		if err != nil {
+			return 0, handleDBError(logger, err, "insert_return_item", start, "failed to insert return item")
+		}
+	}
+
+	err = insertOrderHistory(ctx, tx, ret.OrderId, entity.OrderEventReturnRequested,
+		fmt.Sprintf("return #%d: %s", id, ret.Reason), start)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_order_history", start, "failed to insert order history")
+	}
+
+	r.logInfoReturnOperation(ctx, "insert", start, id)
+	return id, nil
+}
+func (r *ReturnRepository) GetById(ctx context.Context, id int) (entity.OrderReturn, error) {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.GetById")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Return.GetById")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository return operation...",
+		zap.String("operation", "get_by_id"),
+		zap.Int("id", id),
+	)
+
+	// get return by id
+	ret, err := scanReturn(r.db.QueryRow(ctx, postgres.GetByIdReturnsSQL, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.OrderReturn{}, ErrReturnNotFound
+	}
+	if err != nil {
+		return entity.OrderReturn{}, handleDBError(logger, err, "get_by_id_return", start, "failed to get return by id")
+	}
+
+	// return items get
+	returns := []entity.OrderReturn{ret}
+	if err = r.loadReturnItems(ctx, returns); err != nil {
+		return entity.OrderReturn{}, handleDBError(logger, err, "get_return_items", start, "failed to get return items")
+	}
+
+	r.logInfoReturnOperation(ctx, "get_by_id", start, id)
+	return returns[0], nil
+}
+func (r *ReturnRepository) GetByOrderId(ctx context.Context, orderId int) ([]entity.OrderReturn, error) {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.GetByOrderId")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Return.GetByOrderId")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository return operation...",
+		zap.String("operation", "get_by_order_id"),
+		zap.Int("order_id", orderId),
+	)
+
+	// get returns by order id
+	rows, err := r.db.Query(ctx, postgres.GetByOrderIdReturnsSQL, orderId)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_by_order_id_returns", start, "failed to get returns")
+	}
+
+	returns := make([]entity.OrderReturn, 0)
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			rows.Close()
+			return nil, handleDBError(logger, err, "scan_return", start, "failed to scan return")
+		}
+
+		returns = append(returns, ret)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	// return items get
+	if err = r.loadReturnItems(ctx, returns); err != nil {
+		return nil, handleDBError(logger, err, "get_return_items", start, "failed to get return items")
+	}
+
+	r.logInfoReturnOperation(ctx, "get_by_order_id", start, 0)
+	return returns, nil
+}
+
+// UpdateStatus moves the return from one status to another and records it in the order history.
+func (r *ReturnRepository) UpdateStatus(ctx context.Context, id int, from, to, note string) error {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.UpdateStatus")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Return.UpdateStatus")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository return operation...",
+		zap.String("operation", "update_status"),
+		zap.Int("id", id),
+		zap.String("from", from),
+		zap.String("to", to),
+	)
+
+	if err = r.setStatus(ctx, tx, id, from, to, note, start); err != nil {
+		return err
+	}
+
+	r.logInfoReturnOperation(ctx, "update_status", start, id)
+	return nil
+}
+
+// Receive marks an approved return as received and puts the goods back in stock,
+// the damaged part of each item into the damaged stock.
+func (r *ReturnRepository) Receive(ctx context.Context, ret entity.OrderReturn) error {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.Receive")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Return.Receive")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository return operation...",
+		zap.String("operation", "receive"),
+		zap.Int("id", ret.Id),
+	)
+
+	err = r.setStatus(ctx, tx, ret.Id, entity.ReturnStatusApproved, entity.ReturnStatusReceived, ret.Note, start)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range ret.Items {
+		_, err = tx.Exec(ctx, postgres.UpdateDamagedReturnItemsSQL, ret.Id, item.ProductId, item.Damaged)
+		if err != nil {
+			return handleDBError(logger, err, "update_damaged_return_item", start, "failed to update return item")
+		}
+
+		restocked := item.Quantity - item.Damaged
+
+		var stock int
+		err = tx.QueryRow(ctx, postgres.RestockProductsSQL, item.ProductId, restocked, item.Damaged).Scan(&stock)
+		if err != nil {
+			return handleDBError(logger, err, "restock_product", start, "failed to restock product")
+		}
+
+		err = insertStockChangedEvent(ctx, tx, item.ProductId, stock-restocked, stock, start)
+		if err != nil {
+			return handleDBError(logger, err, "insert_outbox", start, "failed to insert stock changed event")
+		}
+	}
+
+	r.logInfoReturnOperation(ctx, "receive", start, ret.Id)
+	return nil
+}
+
+// ClaimRefund moves a received return to refunding, so only one refund of it is made. It
+// returns ErrReturnStatus if the return is not received.
+func (r *ReturnRepository) ClaimRefund(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.ClaimRefund")
+	defer span.End()
+
+	return r.swapStatus(ctx, "claim_refund", "Return.ClaimRefund", id, entity.ReturnStatusReceived, entity.ReturnStatusRefunding)
+}
+
+// ReleaseRefund moves a return claimed for a refund that was not made back to received.
+func (r *ReturnRepository) ReleaseRefund(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.ReleaseRefund")
+	defer span.End()
+
+	return r.swapStatus(ctx, "release_refund", "Return.ReleaseRefund", id, entity.ReturnStatusRefunding, entity.ReturnStatusReceived)
+}
+
+// swapStatus moves the return from one status to another without an order history entry,
+// for the intermediate refunding status.
+func (r *ReturnRepository) swapStatus(ctx context.Context, operation, name string, id int, from, to string) error {
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, name)
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository return operation...",
+		zap.String("operation", operation),
+		zap.Int("id", id),
+	)
+
+	var orderId int
+	err := r.db.QueryRow(ctx, postgres.UpdateStatusReturnsSQL, id, from, to, "", start).Scan(&orderId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrReturnStatus
+	}
+	if err != nil {
+		return handleDBError(logger, err, operation+"_return", start, "failed to update return status")
+	}
+
+	r.logInfoReturnOperation(ctx, operation, start, id)
+	return nil
+}
+
+// MarkRefunded finishes a refund claimed with ClaimRefund and records it in the order history.
+func (r *ReturnRepository) MarkRefunded(ctx context.Context, id, paymentId int, amount float64) error {
+	ctx, span := tracing.Start(ctx, "ReturnRepository.MarkRefunded")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Return.MarkRefunded")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository return operation...",
+		zap.String("operation", "mark_refunded"),
+		zap.Int("id", id),
+		zap.Int("payment_id", paymentId),
+		zap.Float64("amount", amount),
+	)
+
+	var orderId int
+
+	// refunded return update, returning 'orderId'
+	err = tx.QueryRow(ctx, postgres.RefundReturnsSQL, id, amount, paymentId, start).Scan(&orderId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		return ErrReturnStatus
+	}
+	if err != nil {
+		return handleDBError(logger, err, "refund_return", start, "failed to mark return refunded")
+	}
+
+	err = insertOrderHistory(ctx, tx, orderId, entity.OrderEventReturnRefunded,
+		fmt.Sprintf("return #%d: refunded %.2f with payment #%d", id, amount, paymentId), start)
+	if err != nil {
+		return handleDBError(logger, err, "insert_order_history", start, "failed to insert order history")
+	}
+
+	r.logInfoReturnOperation(ctx, "mark_refunded", start, id)
+	return nil
+}
+
+// setStatus updates the return status within tx, returning ErrReturnStatus if the return
+// is not in the from status.
+func (r *ReturnRepository) setStatus(ctx context.Context, tx pgx.Tx, id int, from, to, note string, start time.Time) error {
+	logger := reqctx.Logger(ctx, r.logger)
+
+	var orderId int
+
+	err := tx.QueryRow(ctx, postgres.UpdateStatusReturnsSQL, id, from, to, note, start).Scan(&orderId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrReturnStatus
+	}
+	if err != nil {
+		return handleDBError(logger, err, "update_status_return", start, "failed to update return status")
+	}
+
+	details := fmt.Sprintf("return #%d", id)
+	if note != "" {
+		details += ": " + note
+	}
+	if err = insertOrderHistory(ctx, tx, orderId, statusEvents[to], details, start); err != nil {
+		return handleDBError(logger, err, "insert_order_history", start, "failed to insert order history")
+	}
+	return nil
+}
+
+// loadReturnItems fills items into the given returns.
+func (r *ReturnRepository) loadReturnItems(ctx context.Context, returns []entity.OrderReturn) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(returns))
+	index := make(map[int]int, len(returns))
+	for i, ret := range returns {
+		ids[i] = ret.Id
+		index[ret.Id] = i
+	}
+
+	rows, err := r.db.Query(ctx, postgres.GetByReturnIdsReturnItemsSQL, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var returnId int
+		var item entity.ReturnItem
+		err = rows.Scan(&returnId, &item.ProductId, &item.Quantity, &item.Damaged, &item.Price, &item.TaxAmount)
+		if err != nil {
+			return err
+		}
+		i := index[returnId]
+		returns[i].Items = append(returns[i].Items, item)
+	}
+	return rows.Err()
+}
+
+// returnedQuantities sums, per product, the quantities of the order returns that were not rejected.
+func returnedQuantities(ctx context.Context, tx pgx.Tx, orderId int) (map[int]int, error) {
+	rows, err := tx.Query(ctx, postgres.GetReturnedQuantitiesReturnItemsSQL, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	returned := make(map[int]int)
+	for rows.Next() {
+		var productId, quantity int
+		if err = rows.Scan(&productId, &quantity); err != nil {
+			return nil, err
+		}
+		returned[productId] = quantity
+	}
+	return returned, rows.Err()
+}
+
+func scanReturn(row pgx.Row) (entity.OrderReturn, error) {
+	var ret entity.OrderReturn
+	err := row.Scan(
+		&ret.Id,
+		&ret.OrderId,
+		&ret.Status,
+		&ret.Reason,
+		&ret.Note,
+		&ret.RefundAmount,
+		&ret.RefundedAmount,
+		&ret.PaymentId,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+	)
+	return ret, err
+}
+
+func (r *ReturnRepository) logInfoReturnOperation(ctx context.Context, operation string, start time.Time, id int) {
+	metrics.ObserveRepositoryOperation("return", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository return operation",
+		zap.String("operation", operation),
+		zap.Int("id", id),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
@@ -0,0 +1,346 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type ShippingRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewShippingRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *ShippingRepository {
+	return &ShippingRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *ShippingRepository) CreateZone(ctx context.Context, zone entity.ShippingZone) (int, error) {
+	ctx, span := tracing.Start(ctx, "ShippingRepository.CreateZone")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Shipping.CreateZone")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository shipping operation...",
+		zap.String("operation", "insert_zone"),
+		zap.String("name", zone.Name),
+		zap.Strings("countries", zone.Countries),
+	)
+
+	var id int
+
+	// shipping zone insert, returning 'id'
+	err := r.db.QueryRow(ctx, postgres.InsertShippingZonesSQL, zone.Name, zone.Countries, start).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_shipping_zone", start, "failed to insert shipping zone")
+	}
+
+	r.logInfoShippingOperation(ctx, "insert_zone", start)
+	return id, nil
+}
+func (r *ShippingRepository) GetZones(ctx context.Context) ([]entity.ShippingZone, error) {
+	ctx, span := tracing.Start(ctx, "ShippingRepository.GetZones")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Shipping.GetZones")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository shipping operation...",
+		zap.String("operation", "get_zones"),
+	)
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	// get all shipping zones
+	rows, err := tx.Query(ctx, postgres.GetShippingZonesSQL)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_shipping_zones", start, "failed to get shipping zones")
+	}
+
+	zones := make([]entity.ShippingZone, 0)
+	for rows.Next() {
+		var zone entity.ShippingZone
+
+		err = rows.Scan(&zone.Id, &zone.Name, &zone.Countries, &zone.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, handleDBError(logger, err, "scan_shipping_zone", start, "failed to scan shipping zone")
+		}
+
+		zones = append(zones, zone)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	// shipping methods and rates of the zones
+	if err = loadShippingMethods(ctx, tx, zones); err != nil {
+		return nil, handleDBError(logger, err, "get_shipping_methods", start, "failed to get shipping methods")
+	}
+
+	r.logInfoShippingOperation(ctx, "get_zones", start)
+	return zones, nil
+}
+func (r *ShippingRepository) GetZoneForCountry(ctx context.Context, country string) (entity.ShippingZone, error) {
+	ctx, span := tracing.Start(ctx, "ShippingRepository.GetZoneForCountry")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Shipping.GetZoneForCountry")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository shipping operation...",
+		zap.String("operation", "get_zone_for_country"),
+		zap.String("country", country),
+	)
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return entity.ShippingZone{}, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	var zone entity.ShippingZone
+
+	// get the most specific zone for the country
+	err = tx.QueryRow(ctx, postgres.GetForCountryShippingZonesSQL, country).
+		Scan(&zone.Id, &zone.Name, &zone.Countries, &zone.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+		return entity.ShippingZone{}, ErrShippingZoneNotFound
+	}
+	if err != nil {
+		return entity.ShippingZone{}, handleDBError(logger, err, "get_for_country_shipping_zone", start, "failed to get shipping zone")
+	}
+
+	// shipping methods and rates of the zone
+	zones := []entity.ShippingZone{zone}
+	if err = loadShippingMethods(ctx, tx, zones); err != nil {
+		return entity.ShippingZone{}, handleDBError(logger, err, "get_shipping_methods", start, "failed to get shipping methods")
+	}
+
+	r.logInfoShippingOperation(ctx, "get_zone_for_country", start)
+	return zones[0], nil
+}
+func (r *ShippingRepository) DeleteZone(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ShippingRepository.DeleteZone")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Shipping.DeleteZone")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository shipping operation...",
+		zap.String("operation", "delete_zone"),
+		zap.Int("id", id),
+	)
+
+	// delete shipping zone by id, methods and rates are cascaded
+	tag, err := r.db.Exec(ctx, postgres.DeleteByIdShippingZonesSQL, id)
+	if err != nil {
+		return handleDBError(logger, err, "delete_shipping_zone", start, "failed to delete shipping zone by id")
+	}
+
+	// shipping zone delete result check
+	if tag.RowsAffected() == 0 {
+		logger.Warn("no shipping zone affected - possibly it does not exist",
+			zap.Int("id", id),
+		)
+	}
+
+	r.logInfoShippingOperation(ctx, "delete_zone", start)
+	return nil
+}
+
+func (r *ShippingRepository) CreateMethod(ctx context.Context, method entity.ShippingMethod) (int, error) {
+	ctx, span := tracing.Start(ctx, "ShippingRepository.CreateMethod")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Shipping.CreateMethod")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository shipping operation...",
+		zap.String("operation", "insert_method"),
+		zap.Int("zone_id", method.ZoneId),
+		zap.String("code", method.Code),
+		zap.String("basis", method.Basis),
+		zap.Int("rate_count", len(method.Rates)),
+	)
+
+	var id int
+
+	// shipping method insert, returning 'id'
+	err = tx.QueryRow(ctx, postgres.InsertShippingMethodsSQL,
+		method.ZoneId, method.Code, method.Name, method.Basis, method.FreeThreshold, start,
+	).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_shipping_method", start, "failed to insert shipping method")
+	}
+
+	// shipping rates insert
+	for _, rate := range method.Rates {
+		_, err = tx.Exec(ctx, postgres.InsertShippingRatesSQL, id, rate.MinValue, rate.Price)
+		if err != nil {
+			return 0, handleDBError(logger, err, "insert_shipping_rate", start, "failed to insert shipping rate")
+		}
+	}
+
+	r.logInfoShippingOperation(ctx, "insert_method", start)
+	return id, nil
+}
+func (r *ShippingRepository) DeleteMethod(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ShippingRepository.DeleteMethod")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Shipping.DeleteMethod")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository shipping operation...",
+		zap.String("operation", "delete_method"),
+		zap.Int("id", id),
+	)
+
+	// delete shipping method by id, rates are cascaded
+	tag, err := r.db.Exec(ctx, postgres.DeleteByIdShippingMethodsSQL, id)
+	if err != nil {
+		return handleDBError(logger, err, "delete_shipping_method", start, "failed to delete shipping method by id")
+	}
+
+	// shipping method delete result check
+	if tag.RowsAffected() == 0 {
+		logger.Warn("no shipping method affected - possibly it does not exist",
+			zap.Int("id", id),
+		)
+	}
+
+	r.logInfoShippingOperation(ctx, "delete_method", start)
+	return nil
+}
+
+// loadShippingMethods fills methods with their rates into the given zones.
+func loadShippingMethods(ctx context.Context, tx pgx.Tx, zones []entity.ShippingZone) error {
+	if len(zones) == 0 {
+		return nil
+	}
+
+	zoneIds := make([]int, len(zones))
+	zoneIndex := make(map[int]int, len(zones))
+	for i, zone := range zones {
+		zoneIds[i] = zone.Id
+		zoneIndex[zone.Id] = i
+	}
+
+	rows, err := tx.Query(ctx, postgres.GetByZoneIdsShippingMethodsSQL, zoneIds)
+	if err != nil {
+		return err
+	}
+
+	methods := make([]entity.ShippingMethod, 0)
+	for rows.Next() {
+		var m entity.ShippingMethod
+		err = rows.Scan(&m.Id, &m.ZoneId, &m.Code, &m.Name, &m.Basis, &m.FreeThreshold, &m.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		methods = append(methods, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	methodIds := make([]int, len(methods))
+	methodIndex := make(map[int]int, len(methods))
+	for i, m := range methods {
+		methodIds[i] = m.Id
+		methodIndex[m.Id] = i
+	}
+
+	rows, err = tx.Query(ctx, postgres.GetByMethodIdsShippingRatesSQL, methodIds)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var methodId int
+		var rate entity.ShippingRate
+		if err = rows.Scan(&methodId, &rate.MinValue, &rate.Price); err != nil {
+			return err
+		}
+		i := methodIndex[methodId]
+		methods[i].Rates = append(methods[i].Rates, rate)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range methods {
+		i := zoneIndex[m.ZoneId]
+		zones[i].Methods = append(zones[i].Methods, m)
+	}
+
+	return nil
+}
+
+func (r *ShippingRepository) logInfoShippingOperation(ctx context.Context, operation string, start time.Time) {
+	metrics.ObserveRepositoryOperation("shipping", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository shipping operation",
+		zap.String("operation", operation),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type StatsRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	lowStock int
+	logger   *zap.Logger
+}
+
+func NewStatsRepository(db *pgxpool.Pool, timeouts Timeouts, lowStock int, logger *zap.Logger) *StatsRepository {
+	return &StatsRepository{
+		db:       db,
+		timeouts: timeouts,
+		lowStock: lowStock,
+		logger:   logger,
+	}
+}
+
+func (r *StatsRepository) Get(ctx context.Context) (entity.BusinessStats, error) {
+	ctx, span := tracing.Start(ctx, "StatsRepository.Get")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Stats.Get")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository stats operation...",
+		zap.String("operation", "get"),
+	)
+
+	stats := entity.BusinessStats{OrdersByStatus: make(map[string]int)}
+
+	// count orders by status
+	rows, err := r.db.Query(ctx, postgres.CountByStatusOrdersSQL)
+	if err != nil {
+		return entity.BusinessStats{}, handleDBError(logger, err, "count_by_status_orders", start, "failed to count orders")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		if err = rows.Scan(&status, &count); err != nil {
+			return entity.BusinessStats{}, handleDBError(logger, err, "scan_order_count", start, "failed to scan order count")
+		}
+		stats.OrdersByStatus[status] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return entity.BusinessStats{}, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	// count low stock products
+	err = r.db.QueryRow(ctx, postgres.CountLowStockProductsSQL, r.lowStock).Scan(&stats.LowStockProducts)
+	if err != nil {
+		return entity.BusinessStats{}, handleDBError(logger, err, "count_low_stock_products", start, "failed to count low stock products")
+	}
+
+	metrics.ObserveRepositoryOperation("stats", "get", start)
+
+	// stats are refreshed periodically, not worth an info line
+	logger.Debug("Finished repository stats operation",
+		zap.String("operation", "get"),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+	return stats, nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type TaxRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewTaxRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *TaxRepository {
+	return &TaxRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *TaxRepository) CreateRule(ctx context.Context, rule entity.TaxRule) (int, error) {
+	ctx, span := tracing.Start(ctx, "TaxRepository.CreateRule")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Tax.CreateRule")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository tax operation...",
+		zap.String("operation", "insert_rule"),
+		zap.String("country", rule.Country),
+		zap.String("region", rule.Region),
+		zap.String("tax_class", rule.TaxClass),
+		zap.Float64("rate", rule.Rate),
+	)
+
+	var id int
+
+	// tax rule upsert, returning 'id'
+	err := r.db.QueryRow(ctx, postgres.InsertTaxRulesSQL,
+		rule.Country, rule.Region, rule.TaxClass, rule.Rate, start,
+	).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_tax_rule", start, "failed to insert tax rule")
+	}
+
+	r.logInfoTaxOperation(ctx, "insert_rule", start)
+	return id, nil
+}
+func (r *TaxRepository) GetRules(ctx context.Context, country string) ([]entity.TaxRule, error) {
+	ctx, span := tracing.Start(ctx, "TaxRepository.GetRules")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Tax.GetRules")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository tax operation...",
+		zap.String("operation", "get_rules"),
+		zap.String("country", country),
+	)
+
+	// get tax rules, all countries if country is empty
+	rows, err := r.db.Query(ctx, postgres.GetTaxRulesSQL, country)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_tax_rules", start, "failed to get tax rules")
+	}
+	defer rows.Close()
+
+	rules, err := scanTaxRules(rows)
+	if err != nil {
+		return nil, handleDBError(logger, err, "scan_tax_rule", start, "failed to scan tax rule")
+	}
+
+	r.logInfoTaxOperation(ctx, "get_rules", start)
+	return rules, nil
+}
+func (r *TaxRepository) GetRulesForDestination(ctx context.Context, dest entity.Address) ([]entity.TaxRule, error) {
+	ctx, span := tracing.Start(ctx, "TaxRepository.GetRulesForDestination")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Tax.GetRulesForDestination")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository tax operation...",
+		zap.String("operation", "get_rules_for_destination"),
+		zap.String("country", dest.Country),
+		zap.String("region", dest.Region),
+	)
+
+	// get country-wide and region specific tax rules
+	rows, err := r.db.Query(ctx, postgres.GetForDestinationTaxRulesSQL, dest.Country, dest.Region)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_for_destination_tax_rules", start, "failed to get tax rules")
+	}
+	defer rows.Close()
+
+	rules, err := scanTaxRules(rows)
+	if err != nil {
+		return nil, handleDBError(logger, err, "scan_tax_rule", start, "failed to scan tax rule")
+	}
+
+	r.logInfoTaxOperation(ctx, "get_rules_for_destination", start)
+	return rules, nil
+}
+func (r *TaxRepository) DeleteRule(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "TaxRepository.DeleteRule")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Tax.DeleteRule")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository tax operation...",
+		zap.String("operation", "delete_rule"),
+		zap.Int("id", id),
+	)
+
+	// delete tax rule by id
+	tag, err := r.db.Exec(ctx, postgres.DeleteByIdTaxRulesSQL, id)
+	if err != nil {
+		return handleDBError(logger, err, "delete_tax_rule", start, "failed to delete tax rule by id")
+	}
+
+	// tax rule delete result check
+	if tag.RowsAffected() == 0 {
+		logger.Warn("no tax rule affected - possibly it does not exist",
+			zap.Int("id", id),
+		)
+	}
+
+	r.logInfoTaxOperation(ctx, "delete_rule", start)
+	return nil
+}
+
+func scanTaxRules(rows pgx.Rows) ([]entity.TaxRule, error) {
+	rules := make([]entity.TaxRule, 0)
+	for rows.Next() {
+		var rule entity.TaxRule
+		err := rows.Scan(&rule.Id, &rule.Country, &rule.Region, &rule.TaxClass, &rule.Rate, &rule.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *TaxRepository) logInfoTaxOperation(ctx context.Context, operation string, start time.Time) {
+	metrics.ObserveRepositoryOperation("tax", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository tax operation",
+		zap.String("operation", operation),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
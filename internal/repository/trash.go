@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type TrashRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewTrashRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *TrashRepository {
+	return &TrashRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *TrashRepository) GetAll(ctx context.Context) ([]entity.TrashItem, error) {
+	ctx, span := tracing.Start(ctx, "TrashRepository.GetAll")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Trash.GetAll")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository trash operation...",
+		zap.String("operation", "get_all"),
+	)
+
+	// deleted products
+	rows, err := tx.Query(ctx, postgres.GetDeletedProductsSQL)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_deleted_products", start, "failed to get deleted products")
+	}
+
+	items := make([]entity.TrashItem, 0)
+	for rows.Next() {
+		var item entity.TrashItem
+
+		err = rows.Scan(&item.Id, &item.Kind, &item.Name, &item.DeletedAt)
+		if err != nil {
+			rows.Close()
+			return nil, handleDBError(logger, err, "scan_deleted_product", start, "failed to scan deleted product")
+		}
+
+		items = append(items, item)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	// deleted orders
+	rows, err = tx.Query(ctx, postgres.GetDeletedOrdersSQL)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_deleted_orders", start, "failed to get deleted orders")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := entity.TrashItem{Kind: entity.TrashKindOrder}
+
+		err = rows.Scan(&item.Id, &item.Status, &item.DeletedAt)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_deleted_order", start, "failed to scan deleted order")
+		}
+
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoTrashOperation(ctx, "get_all", start)
+	return items, nil
+}
+func (r *TrashRepository) RestoreProduct(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "TrashRepository.RestoreProduct")
+	defer span.End()
+
+	return r.restore(ctx, "Trash.RestoreProduct", "restore_product", postgres.RestoreProductsSQL, id)
+}
+func (r *TrashRepository) RestoreOrder(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "TrashRepository.RestoreOrder")
+	defer span.End()
+
+	return r.restore(ctx, "Trash.RestoreOrder", "restore_order", postgres.RestoreOrdersSQL, id)
+}
+
+// Purge deletes products and orders that are in the trash since before the given time.
+// Products still referenced by orders or returns are kept.
+func (r *TrashRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "TrashRepository.Purge")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Trash.Purge")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository trash operation...",
+		zap.String("operation", "purge"),
+		zap.Time("before", before),
+	)
+
+	// orders go first, their items may be the last references to a deleted product
+	orders, err := tx.Exec(ctx, postgres.PurgeOrdersSQL, before)
+	if err != nil {
+		return 0, handleDBError(logger, err, "purge_orders", start, "failed to purge orders")
+	}
+
+	products, err := tx.Exec(ctx, postgres.PurgeProductsSQL, before)
+	if err != nil {
+		return 0, handleDBError(logger, err, "purge_products", start, "failed to purge products")
+	}
+
+	r.logInfoTrashOperation(ctx, "purge", start)
+	return int(orders.RowsAffected() + products.RowsAffected()), nil
+}
+
+// restore takes the timeout of the named repository operation, operation is its log name.
+func (r *TrashRepository) restore(ctx context.Context, name, operation, sql string, id int) error {
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, name)
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository trash operation...",
+		zap.String("operation", operation),
+		zap.Int("id", id),
+	)
+
+	tag, err := r.db.Exec(ctx, sql, id)
+	if err != nil {
+		return handleDBError(logger, err, operation, start, "failed to restore from trash")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotInTrash
+	}
+
+	r.logInfoTrashOperation(ctx, operation, start)
+	return nil
+}
+
+func (r *TrashRepository) logInfoTrashOperation(ctx context.Context, operation string, start time.Time) {
+	metrics.ObserveRepositoryOperation("trash", operation, start)
+
+	reqctx.Logger(ctx, r.logger).Info("Finished repository trash operation",
+		zap.String("operation", operation),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
@@ -0,0 +1,437 @@
+package repository
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/postgres"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type WebhookRepository struct {
+	db       *pgxpool.Pool
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewWebhookRepository(db *pgxpool.Pool, timeouts Timeouts, logger *zap.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db:       db,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *WebhookRepository) CreateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) (int, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.CreateEndpoint")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.CreateEndpoint")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "create_endpoint"),
+		zap.String("url", endpoint.URL),
+		zap.Strings("events", endpoint.Events),
+	)
+
+	var id int
+
+	// endpoint insert, returning 'id'
+	err := r.db.QueryRow(ctx, postgres.InsertWebhookEndpointsSQL,
+		endpoint.URL, endpoint.Secret, endpoint.Events, start,
+	).Scan(&id)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_webhook_endpoint", start, "failed to insert webhook endpoint")
+	}
+
+	r.logInfoWebhookOperation(ctx, "create_endpoint", start, zap.Int("id", id))
+	return id, nil
+}
+func (r *WebhookRepository) GetEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.GetEndpoints")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.GetEndpoints")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "get_endpoints"),
+	)
+
+	// get all endpoints
+	rows, err := r.db.Query(ctx, postgres.GetAllWebhookEndpointsSQL)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_all_webhook_endpoints", start, "failed to get webhook endpoints")
+	}
+	defer rows.Close()
+
+	endpoints := make([]entity.WebhookEndpoint, 0)
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_webhook_endpoint", start, "failed to scan webhook endpoint")
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoWebhookOperation(ctx, "get_endpoints", start, zap.Int("count", len(endpoints)))
+	return endpoints, nil
+}
+func (r *WebhookRepository) GetEndpointById(ctx context.Context, id int) (entity.WebhookEndpoint, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.GetEndpointById")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.GetEndpointById")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "get_endpoint_by_id"),
+		zap.Int("id", id),
+	)
+
+	// get endpoint by id
+	endpoint, err := scanWebhookEndpoint(r.db.QueryRow(ctx, postgres.GetByIdWebhookEndpointsSQL, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.WebhookEndpoint{}, ErrWebhookEndpointNotFound
+	}
+	if err != nil {
+		return entity.WebhookEndpoint{}, handleDBError(logger, err, "get_by_id_webhook_endpoint", start, "failed to get webhook endpoint")
+	}
+
+	r.logInfoWebhookOperation(ctx, "get_endpoint_by_id", start, zap.Int("id", id))
+	return endpoint, nil
+}
+func (r *WebhookRepository) UpdateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) error {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.UpdateEndpoint")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.UpdateEndpoint")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "update_endpoint"),
+		zap.Int("id", endpoint.Id),
+		zap.Bool("enabled", endpoint.Enabled),
+	)
+
+	// endpoint update by id
+	tag, err := r.db.Exec(ctx, postgres.UpdateWebhookEndpointsSQL,
+		endpoint.Id, endpoint.URL, endpoint.Events, endpoint.Enabled, start,
+	)
+	if err != nil {
+		return handleDBError(logger, err, "update_webhook_endpoint", start, "failed to update webhook endpoint")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookEndpointNotFound
+	}
+
+	r.logInfoWebhookOperation(ctx, "update_endpoint", start, zap.Int("id", endpoint.Id))
+	return nil
+}
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.DeleteEndpoint")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.DeleteEndpoint")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "delete_endpoint"),
+		zap.Int("id", id),
+	)
+
+	// endpoint delete by id, its deliveries are deleted with it
+	tag, err := r.db.Exec(ctx, postgres.DeleteWebhookEndpointsSQL, id)
+	if err != nil {
+		return handleDBError(logger, err, "delete_webhook_endpoint", start, "failed to delete webhook endpoint")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookEndpointNotFound
+	}
+
+	r.logInfoWebhookOperation(ctx, "delete_endpoint", start, zap.Int("id", id))
+	return nil
+}
+
+// Enqueue creates a delivery of the event for every enabled endpoint subscribed to it.
+func (r *WebhookRepository) Enqueue(ctx context.Context, event entity.OutboxEvent, payload []byte, at time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.Enqueue")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.Enqueue")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "enqueue"),
+		zap.Int64("event_id", event.Id),
+		zap.String("event_type", event.EventType),
+	)
+
+	// deliveries insert, existing ones are kept
+	tag, err := r.db.Exec(ctx, postgres.InsertWebhookDeliveriesSQL, event.Id, event.EventType, payload, at)
+	if err != nil {
+		return 0, handleDBError(logger, err, "insert_webhook_deliveries", start, "failed to insert webhook deliveries")
+	}
+
+	queued := int(tag.RowsAffected())
+
+	r.logInfoWebhookOperation(ctx, "enqueue", start, zap.Int64("event_id", event.Id), zap.Int("queued", queued))
+	return queued, nil
+}
+
+// Claim leases up to limit due deliveries until leaseUntil, with the URL and secret of their endpoint.
+func (r *WebhookRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.Claim")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.Claim")
+	defer cancel()
+
+	start := time.Now()
+
+	// claim due deliveries
+	rows, err := r.db.Query(ctx, postgres.ClaimWebhookDeliveriesSQL, now, leaseUntil, limit)
+	if err != nil {
+		return nil, handleDBError(logger, err, "claim_webhook_deliveries", start, "failed to claim webhook deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := make([]entity.WebhookDelivery, 0)
+	for rows.Next() {
+		var d entity.WebhookDelivery
+
+		err = rows.Scan(&d.Id, &d.EndpointId, &d.EventId, &d.EventType, &d.Payload, &d.Attempts, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_webhook_delivery", start, "failed to scan webhook delivery")
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	// the worker polls often, empty claims are not worth an info line
+	if len(deliveries) > 0 {
+		r.logInfoWebhookOperation(ctx, "claim", start, zap.Int("count", len(deliveries)))
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt stores the outcome of an attempt and keeps count of the failures of the
+// endpoint in a row. It returns true if the endpoint got disabled after disableAfter failures.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, attempt entity.WebhookAttempt, disableAfter int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.RecordAttempt")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.RecordAttempt")
+	defer cancel()
+
+	start := time.Now()
+
+	// transaction initialization
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer finalizeTx(logger, ctx, tx, &err)
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "record_attempt"),
+		zap.Int64("delivery_id", attempt.DeliveryId),
+		zap.String("status", attempt.Status),
+	)
+
+	failed := attempt.Status != entity.WebhookDeliveryDelivered
+
+	var deliveredAt *time.Time
+	if !failed {
+		deliveredAt = &attempt.At
+	}
+
+	// delivery update
+	_, err = tx.Exec(ctx, postgres.UpdateWebhookDeliveriesSQL,
+		attempt.DeliveryId, attempt.Status, attempt.NextAttemptAt, attempt.ResponseCode,
+		attempt.ResponseBody, attempt.Error, deliveredAt,
+	)
+	if err != nil {
+		return false, handleDBError(logger, err, "update_webhook_delivery", start, "failed to update webhook delivery")
+	}
+
+	// endpoint failures in a row
+	disabled := false
+	if failed {
+		err = tx.QueryRow(ctx, postgres.AddFailureWebhookEndpointsSQL, attempt.EndpointId, disableAfter, attempt.At).
+			Scan(&disabled)
+	} else {
+		_, err = tx.Exec(ctx, postgres.ResetFailuresWebhookEndpointsSQL, attempt.EndpointId)
+	}
+	if err != nil {
+		return false, handleDBError(logger, err, "update_webhook_endpoint_failures", start, "failed to update webhook endpoint failures")
+	}
+
+	r.logInfoWebhookOperation(ctx, "record_attempt", start, zap.Int64("delivery_id", attempt.DeliveryId))
+	return disabled, nil
+}
+
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.GetDeliveries")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.GetDeliveries")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "get_deliveries"),
+		zap.Int("endpoint_id", filter.EndpointId),
+		zap.String("status", filter.Status),
+	)
+
+	// get deliveries of the endpoint, newest first
+	rows, err := r.db.Query(ctx, postgres.GetByEndpointIdWebhookDeliveriesSQL,
+		filter.EndpointId, filter.Status, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, handleDBError(logger, err, "get_by_endpoint_id_webhook_deliveries", start, "failed to get webhook deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := make([]entity.WebhookDelivery, 0)
+	for rows.Next() {
+		var d entity.WebhookDelivery
+
+		err = rows.Scan(
+			&d.Id,
+			&d.EndpointId,
+			&d.EventId,
+			&d.EventType,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.ResponseCode,
+			&d.ResponseBody,
+			&d.LastError,
+			&d.RedeliveryOf,
+			&d.CreatedAt,
+			&d.DeliveredAt,
+		)
+		if err != nil {
+			return nil, handleDBError(logger, err, "scan_webhook_delivery", start, "failed to scan webhook delivery")
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, handleDBError(logger, err, "rows_err", start, "failed during rows iteration")
+	}
+
+	r.logInfoWebhookOperation(ctx, "get_deliveries", start, zap.Int("endpoint_id", filter.EndpointId))
+	return deliveries, nil
+}
+
+// Redeliver queues a delivery of the endpoint again, as a new delivery.
+func (r *WebhookRepository) Redeliver(ctx context.Context, endpointId int, deliveryId int64, at time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "WebhookRepository.Redeliver")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, r.logger)
+
+	ctx, cancel := r.timeouts.withTimeout(ctx, "Webhook.Redeliver")
+	defer cancel()
+
+	start := time.Now()
+
+	logger.Debug("Starting repository webhook operation...",
+		zap.String("operation", "redeliver"),
+		zap.Int("endpoint_id", endpointId),
+		zap.Int64("delivery_id", deliveryId),
+	)
+
+	var id int64
+
+	// delivery copy insert, returning 'id'
+	err := r.db.QueryRow(ctx, postgres.RedeliverWebhookDeliveriesSQL, deliveryId, endpointId, at).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return 0, handleDBError(logger, err, "redeliver_webhook_delivery", start, "failed to redeliver webhook delivery")
+	}
+
+	r.logInfoWebhookOperation(ctx, "redeliver", start, zap.Int64("id", id))
+	return id, nil
+}
+
+func scanWebhookEndpoint(row pgx.Row) (entity.WebhookEndpoint, error) {
+	var e entity.WebhookEndpoint
+	err := row.Scan(
+		&e.Id,
+		&e.URL,
+		&e.Secret,
+		&e.Events,
+		&e.Enabled,
+		&e.ConsecutiveFailures,
+		&e.DisabledAt,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	)
+	return e, err
+}
+
+func (r *WebhookRepository) logInfoWebhookOperation(ctx context.Context, operation string, start time.Time, fields ...zap.Field) {
+	metrics.ObserveRepositoryOperation("webhook", operation, start)
+
+	fields = append(fields,
+		zap.String("operation", operation),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+	reqctx.Logger(ctx, r.logger).Info("Finished repository webhook operation", fields...)
+}
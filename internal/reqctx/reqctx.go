@@ -0,0 +1,56 @@
+package reqctx
+
+import (
+	"context"
+	"go.uber.org/zap"
+)
+
+// ActorHeader names who is making the request. The API has no authentication of its own,
+// the header is expected to be set by the gateway in front of it.
+const ActorHeader = "X-Actor"
+
+// AnonymousActor is used when a request does not name its actor.
+const AnonymousActor = "anonymous"
+
+type ctxKey int
+
+const (
+	actorKey ctxKey = iota
+	requestIdKey
+	loggerKey
+)
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns who made the request, AnonymousActor if unknown.
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, id)
+}
+
+// RequestId returns the id of the request, empty outside of a request.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the logger of the request, which tags every line with the request,
+// or fallback outside of a request.
+func Logger(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// auditState is the audited view of an entity, field name to value.
+type auditState map[string]any
+
+type auditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type AuditService struct {
+	repo   *repository.Repository
+	logger *zap.Logger
+}
+
+func NewAuditService(repo *repository.Repository, logger *zap.Logger) *AuditService {
+	return &AuditService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *AuditService) Get(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Get")
+	defer span.End()
+
+	return s.repo.Audit.Get(ctx, filter)
+}
+
+// Entry builds the audit entry of a change with the actor and request id from ctx, to be
+// stored by the repository in the transaction of the change. before is nil on create and
+// after is nil on delete; an update that changed nothing gives no entry.
+func (s *AuditService) Entry(ctx context.Context, entityType string, entityId int, action string, before, after auditState) (*entity.AuditEntry, error) {
+	changes := auditDiff(before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("marshal audit changes: %w", err)
+	}
+
+	return &entity.AuditEntry{
+		Actor:      reqctx.Actor(ctx),
+		RequestId:  reqctx.RequestId(ctx),
+		EntityType: entityType,
+		EntityId:   entityId,
+		Action:     action,
+		Changes:    raw,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// auditDiff returns the fields whose values differ between the two states.
+func auditDiff(before, after auditState) map[string]auditChange {
+	changes := make(map[string]auditChange)
+	for field, old := range before {
+		if value, ok := after[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes[field] = auditChange{Old: old, New: after[field]}
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = auditChange{New: value}
+		}
+	}
+	return changes
+}
+
+func productAuditState(p entity.BaseProduct) auditState {
+	return auditState{
+		"name":        p.Name,
+		"price":       p.Price,
+		"stock":       p.Stock,
+		"taxClass":    p.TaxClass,
+		"weightGrams": p.WeightGrams,
+	}
+}
+
+func bookAuditState(b entity.Book) auditState {
+	state := productAuditState(b.BaseProduct)
+	state["author"] = b.Author
+	state["isbn"] = b.Isbn
+	return state
+}
+
+func magazineAuditState(m entity.Magazine) auditState {
+	state := productAuditState(m.BaseProduct)
+	state["issueNumber"] = m.IssueNumber
+	state["publicationDate"] = m.PublicationDate.Format(time.DateOnly)
+	return state
+}
+
+type orderItemAuditState struct {
+	ProductId int     `json:"productId"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+func orderAuditState(o entity.Order) auditState {
+	items := make([]orderItemAuditState, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = orderItemAuditState{
+			ProductId: item.Product.Id,
+			Quantity:  item.Quantity,
+			Price:     item.Product.Price,
+		}
+	}
+	// stored items come in no particular order
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductId < items[j].ProductId })
+	return auditState{
+		"status":         o.Status,
+		"trackingNumber": o.Shipping.TrackingNumber,
+		"currency":       o.Currency,
+		"items":          items,
+	}
+}
@@ -3,24 +3,31 @@ package service
 import (
 	"BookStore_API/internal/entity"
 	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
 	"context"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 )
 
 type BookService struct {
 	repo   *repository.Repository
+	audit  *AuditService
 	logger *zap.Logger
 }
 
-func NewBookService(repo *repository.Repository, logger *zap.Logger) *BookService {
+func NewBookService(repo *repository.Repository, audit *AuditService, logger *zap.Logger) *BookService {
 	return &BookService{
 		repo:   repo,
+		audit:  audit,
 		logger: logger,
 	}
 }
 
 func (s *BookService) Create(ctx context.Context, book entity.Book) (int, error) {
+	ctx, span := tracing.Start(ctx, "BookService.Create")
+	defer span.End()
+
 	exists, err := s.repo.Book.IsbnExists(ctx, book.Isbn)
 	if err != nil {
 		return 0, fmt.Errorf("check isbn exists: %w", err)
@@ -29,7 +36,16 @@ func (s *BookService) Create(ctx context.Context, book entity.Book) (int, error)
 		return 0, fmt.Errorf("book with the same ISBN already exists")
 	}
 
-	id, err := s.repo.Book.Create(ctx, book)
+	if book.TaxClass == "" {
+		book.TaxClass = entity.TaxClassReduced
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityBook, 0, entity.AuditActionCreate, nil, bookAuditState(book))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := s.repo.Book.Create(ctx, book, audit)
 	if err != nil {
 		return 0, fmt.Errorf("create book: %w", err)
 	}
@@ -37,24 +53,69 @@ func (s *BookService) Create(ctx context.Context, book entity.Book) (int, error)
 	return id, nil
 }
 func (s *BookService) GetById(ctx context.Context, id int) (entity.Book, error) {
+	ctx, span := tracing.Start(ctx, "BookService.GetById")
+	defer span.End()
+
 	return s.repo.Book.GetById(ctx, id)
 }
 func (s *BookService) Update(ctx context.Context, book entity.Book) error {
-	exists, err := s.repo.Book.IsbnExists(ctx, book.Isbn)
+	ctx, span := tracing.Start(ctx, "BookService.Update")
+	defer span.End()
+
+	stored, err := s.repo.Book.GetById(ctx, book.Id)
 	if err != nil {
-		return fmt.Errorf("check isbn exists: %w", err)
+		return fmt.Errorf("get book: %w", err)
 	}
-	if exists {
-		return fmt.Errorf("book with the same ISBN already exists")
+
+	// the book keeps its own ISBN, only a changed one has to be unique
+	if book.Isbn != stored.Isbn {
+		exists, err := s.repo.Book.IsbnExists(ctx, book.Isbn)
+		if err != nil {
+			return fmt.Errorf("check isbn exists: %w", err)
+		}
+		if exists {
+			return fmt.Errorf("book with the same ISBN already exists")
+		}
 	}
 
-	err = s.repo.Book.Update(ctx, book)
+	// a cleared tax class falls back to the default of books
+	if book.TaxClass == "" {
+		book.TaxClass = entity.TaxClassReduced
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityBook, book.Id, entity.AuditActionUpdate, bookAuditState(stored), bookAuditState(book))
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Book.Update(ctx, book, audit)
 	if err != nil {
 		return fmt.Errorf("update book: %w", err)
 	}
 
 	return nil
 }
-func (s *BookService) Delete(ctx context.Context, id int) error {
-	return s.repo.Book.Delete(ctx, id)
+func (s *BookService) Delete(ctx context.Context, id, version int) error {
+	ctx, span := tracing.Start(ctx, "BookService.Delete")
+	defer span.End()
+
+	// deleting a missing book is a no-op, unless a version was expected
+	stored, err := s.repo.Book.GetById(ctx, id)
+	if errors.Is(err, repository.ErrProductNotFound) && version == 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get book: %w", err)
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityBook, id, entity.AuditActionDelete, bookAuditState(stored), nil)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.Book.Delete(ctx, id, version, audit); err != nil {
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"math"
+	"time"
+)
+
+var (
+	ErrBaseCurrencyRate     = errors.New("exchange rate for the base currency is always 1")
+	ErrBaseCurrencyOverride = errors.New("price override in the base currency is not allowed, update the product price instead")
+)
+
+type CurrencyService struct {
+	repo         *repository.Repository
+	baseCurrency string
+	logger       *zap.Logger
+}
+
+func NewCurrencyService(repo *repository.Repository, baseCurrency string, logger *zap.Logger) *CurrencyService {
+	return &CurrencyService{
+		repo:         repo,
+		baseCurrency: baseCurrency,
+		logger:       logger,
+	}
+}
+
+func (s *CurrencyService) BaseCurrency() string {
+	return s.baseCurrency
+}
+
+func (s *CurrencyService) CreateRate(ctx context.Context, rate entity.ExchangeRate) (int, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyService.CreateRate")
+	defer span.End()
+
+	if rate.Currency == s.baseCurrency {
+		return 0, fmt.Errorf("%w: %s", ErrBaseCurrencyRate, s.baseCurrency)
+	}
+
+	id, err := s.repo.Currency.CreateRate(ctx, rate)
+	if err != nil {
+		return 0, fmt.Errorf("create exchange rate: %w", err)
+	}
+
+	return id, nil
+}
+func (s *CurrencyService) GetRates(ctx context.Context, currency string) ([]entity.ExchangeRate, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyService.GetRates")
+	defer span.End()
+
+	return s.repo.Currency.GetRates(ctx, currency)
+}
+func (s *CurrencyService) SetPriceOverride(ctx context.Context, po entity.PriceOverride) error {
+	ctx, span := tracing.Start(ctx, "CurrencyService.SetPriceOverride")
+	defer span.End()
+
+	if po.Currency == s.baseCurrency {
+		return fmt.Errorf("%w: %s", ErrBaseCurrencyOverride, s.baseCurrency)
+	}
+
+	products, err := s.repo.Product.GetByIds(ctx, []int{po.ProductId})
+	if err != nil {
+		return fmt.Errorf("failed to get products by ids: %w", err)
+	}
+	if len(products) == 0 || products[0].DeletedAt != nil {
+		return fmt.Errorf("set price override failed: product with id %d: %w", po.ProductId, repository.ErrProductNotFound)
+	}
+
+	if err = s.repo.Currency.SetPriceOverride(ctx, po); err != nil {
+		return fmt.Errorf("set price override: %w", err)
+	}
+
+	return nil
+}
+func (s *CurrencyService) DeletePriceOverride(ctx context.Context, productId int, currency string) error {
+	ctx, span := tracing.Start(ctx, "CurrencyService.DeletePriceOverride")
+	defer span.End()
+
+	return s.repo.Currency.DeletePriceOverride(ctx, productId, currency)
+}
+func (s *CurrencyService) GetPriceOverrides(ctx context.Context, productId int) ([]entity.PriceOverride, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyService.GetPriceOverrides")
+	defer span.End()
+
+	return s.repo.Currency.GetPriceOverrides(ctx, productId)
+}
+
+// Localize converts base currency prices of the products into currency using the
+// rate effective at the given moment and returns that rate. An empty currency
+// means the base currency.
+func (s *CurrencyService) Localize(ctx context.Context, currency string, at time.Time, products ...*entity.BaseProduct) (float64, error) {
+	ctx, span := tracing.Start(ctx, "CurrencyService.Localize")
+	defer span.End()
+
+	if currency == "" || currency == s.baseCurrency {
+		return 1, s.ApplyRate(ctx, s.baseCurrency, 1, products...)
+	}
+
+	rate, err := s.repo.Currency.GetRateAt(ctx, currency, at)
+	if err != nil {
+		return 0, fmt.Errorf("get %s exchange rate: %w", currency, err)
+	}
+
+	return rate, s.ApplyRate(ctx, currency, rate, products...)
+}
+
+// ApplyRate converts base currency prices of the products into currency with the given rate,
+// preferring per-currency price overrides where they exist.
+func (s *CurrencyService) ApplyRate(ctx context.Context, currency string, rate float64, products ...*entity.BaseProduct) error {
+	ctx, span := tracing.Start(ctx, "CurrencyService.ApplyRate")
+	defer span.End()
+
+	if currency == "" || currency == s.baseCurrency {
+		for _, p := range products {
+			p.Currency = s.baseCurrency
+		}
+		return nil
+	}
+
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.Id
+	}
+
+	overrides, err := s.repo.Currency.GetPriceOverridesByIds(ctx, ids, currency)
+	if err != nil {
+		return fmt.Errorf("get price overrides: %w", err)
+	}
+
+	overrideMap := make(map[int]float64, len(overrides))
+	for _, po := range overrides {
+		overrideMap[po.ProductId] = po.Price
+	}
+
+	for _, p := range products {
+		if price, ok := overrideMap[p.Id]; ok {
+			p.Price = price
+		} else {
+			p.Price = roundPrice(p.Price * rate)
+		}
+		p.Currency = currency
+	}
+
+	return nil
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+// healthCheckTimeout bounds every dependency check, probes must answer quickly.
+const healthCheckTimeout = 2 * time.Second
+
+// WorkerStatus reports the background workers, it is implemented by the worker group.
+type WorkerStatus interface {
+	Names() []string
+	Stopped() []string
+}
+
+type HealthService struct {
+	repo *repository.Repository
+	// migrationVersion is the schema version this build expects, 0 if unknown
+	migrationVersion uint
+	workers          WorkerStatus
+	logger           *zap.Logger
+}
+
+func NewHealthService(repo *repository.Repository, migrationVersion uint, workers WorkerStatus, logger *zap.Logger) *HealthService {
+	return &HealthService{
+		repo:             repo,
+		migrationVersion: migrationVersion,
+		workers:          workers,
+		logger:           logger,
+	}
+}
+
+// Check runs every check, the report is down if any of them is.
+func (s *HealthService) Check(ctx context.Context) entity.HealthReport {
+	ctx, span := tracing.Start(ctx, "HealthService.Check")
+	defer span.End()
+
+	report := entity.HealthReport{
+		Status: entity.HealthUp,
+		Checks: []entity.HealthCheck{
+			s.checkDatabase(ctx),
+			s.checkMigrations(ctx),
+			s.checkWorkers(),
+		},
+		Pool:      s.repo.Health.PoolStats(),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	for _, check := range report.Checks {
+		if check.Status != entity.HealthUp {
+			report.Status = entity.HealthDown
+			reqctx.Logger(ctx, s.logger).Warn("health check failed",
+				zap.String("check", check.Name),
+				zap.String("error", check.Error),
+			)
+		}
+	}
+	return report
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) entity.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.repo.Health.Ping(ctx)
+
+	return newHealthCheck(entity.HealthCheckDatabase, start, err, nil)
+}
+
+// checkMigrations accepts newer versions than expected, a replica of the previous release
+// keeps serving while a new one migrates the schema.
+func (s *HealthService) checkMigrations(ctx context.Context) entity.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	version, dirty, err := s.repo.Health.MigrationVersion(ctx)
+
+	details := map[string]any{
+		"version":         version,
+		"expectedVersion": s.migrationVersion,
+		"dirty":           dirty,
+	}
+	switch {
+	case err != nil:
+	case dirty:
+		err = fmt.Errorf("migration %d failed and must be fixed by hand", version)
+	case version < s.migrationVersion:
+		err = fmt.Errorf("schema version %d is behind the expected %d", version, s.migrationVersion)
+	}
+
+	return newHealthCheck(entity.HealthCheckMigrations, start, err, details)
+}
+
+func (s *HealthService) checkWorkers() entity.HealthCheck {
+	start := time.Now()
+
+	stopped := s.workers.Stopped()
+	details := map[string]any{
+		"workers": s.workers.Names(),
+		"stopped": stopped,
+	}
+
+	var err error
+	if len(stopped) > 0 {
+		err = fmt.Errorf("%d workers are not running", len(stopped))
+	}
+
+	return newHealthCheck(entity.HealthCheckWorkers, start, err, details)
+}
+
+func newHealthCheck(name string, start time.Time, err error, details map[string]any) entity.HealthCheck {
+	check := entity.HealthCheck{
+		Name:    name,
+		Status:  entity.HealthUp,
+		Latency: time.Since(start),
+		Details: details,
+	}
+	if err != nil {
+		check.Status = entity.HealthDown
+		check.Error = err.Error()
+	}
+	return check
+}
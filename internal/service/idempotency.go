@@ -0,0 +1,82 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+// idempotencyLockTimeout is how long a request may hold its key before it is considered
+// abandoned, e.g. after the instance serving it crashed.
+const idempotencyLockTimeout = time.Minute
+
+var (
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used for a different request")
+)
+
+type IdempotencyService struct {
+	repo   *repository.Repository
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+func NewIdempotencyService(repo *repository.Repository, ttl time.Duration, logger *zap.Logger) *IdempotencyService {
+	return &IdempotencyService{
+		repo:   repo,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Begin takes the key for a request. It returns nil if the request should be processed,
+// or the stored record whose response must be replayed.
+func (s *IdempotencyService) Begin(ctx context.Context, key, route, requestHash string) (*entity.IdempotencyRecord, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	now := time.Now()
+	rec, acquired, err := s.repo.Idempotency.Acquire(ctx, entity.IdempotencyRecord{
+		Key:         key,
+		Route:       route,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}, now.Add(-idempotencyLockTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if acquired {
+		return nil, nil
+	}
+
+	if rec.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if rec.Status != entity.IdempotencyStatusCompleted {
+		return nil, ErrIdempotencyInProgress
+	}
+	return &rec, nil
+}
+func (s *IdempotencyService) Complete(ctx context.Context, key, route string, status int, body []byte) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	return s.repo.Idempotency.Complete(ctx, key, route, status, body)
+}
+func (s *IdempotencyService) Release(ctx context.Context, key, route string) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Release")
+	defer span.End()
+
+	return s.repo.Idempotency.Release(ctx, key, route)
+}
+func (s *IdempotencyService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.PurgeExpired")
+	defer span.End()
+
+	return s.repo.Idempotency.DeleteExpired(ctx, now)
+}
@@ -3,24 +3,31 @@ package service
 import (
 	"BookStore_API/internal/entity"
 	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
 	"context"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 )
 
 type MagazineService struct {
 	repo   *repository.Repository
+	audit  *AuditService
 	logger *zap.Logger
 }
 
-func NewMagazineService(repo *repository.Repository, logger *zap.Logger) *MagazineService {
+func NewMagazineService(repo *repository.Repository, audit *AuditService, logger *zap.Logger) *MagazineService {
 	return &MagazineService{
 		repo:   repo,
+		audit:  audit,
 		logger: logger,
 	}
 }
 
 func (s *MagazineService) Create(ctx context.Context, mag entity.Magazine) (int, error) {
+	ctx, span := tracing.Start(ctx, "MagazineService.Create")
+	defer span.End()
+
 	exists, err := s.repo.Magazine.ExistsIssueNumber(ctx, mag.IssueNumber)
 	if err != nil {
 		return 0, fmt.Errorf("check issue number exists: %w", err)
@@ -29,7 +36,16 @@ func (s *MagazineService) Create(ctx context.Context, mag entity.Magazine) (int,
 		return 0, fmt.Errorf("magazine with the same issue number already exists")
 	}
 
-	id, err := s.repo.Magazine.Create(ctx, mag)
+	if mag.TaxClass == "" {
+		mag.TaxClass = entity.TaxClassStandard
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityMagazine, 0, entity.AuditActionCreate, nil, magazineAuditState(mag))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := s.repo.Magazine.Create(ctx, mag, audit)
 	if err != nil {
 		return 0, fmt.Errorf("create magazine: %w", err)
 	}
@@ -37,24 +53,69 @@ func (s *MagazineService) Create(ctx context.Context, mag entity.Magazine) (int,
 	return id, nil
 }
 func (s *MagazineService) GetById(ctx context.Context, id int) (entity.Magazine, error) {
+	ctx, span := tracing.Start(ctx, "MagazineService.GetById")
+	defer span.End()
+
 	return s.repo.Magazine.GetById(ctx, id)
 }
 func (s *MagazineService) Update(ctx context.Context, mag entity.Magazine) error {
-	exists, err := s.repo.Magazine.ExistsIssueNumber(ctx, mag.IssueNumber)
+	ctx, span := tracing.Start(ctx, "MagazineService.Update")
+	defer span.End()
+
+	stored, err := s.repo.Magazine.GetById(ctx, mag.Id)
 	if err != nil {
-		return fmt.Errorf("check issue number exists: %w", err)
+		return fmt.Errorf("get magazine: %w", err)
 	}
-	if exists {
-		return fmt.Errorf("magazine with the same issue number already exists")
+
+	// the magazine keeps its own issue number, only a changed one has to be unique
+	if mag.IssueNumber != stored.IssueNumber {
+		exists, err := s.repo.Magazine.ExistsIssueNumber(ctx, mag.IssueNumber)
+		if err != nil {
+			return fmt.Errorf("check issue number exists: %w", err)
+		}
+		if exists {
+			return fmt.Errorf("magazine with the same issue number already exists")
+		}
 	}
 
-	err = s.repo.Magazine.Update(ctx, mag)
+	// a cleared tax class falls back to the default of magazines
+	if mag.TaxClass == "" {
+		mag.TaxClass = entity.TaxClassStandard
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityMagazine, mag.Id, entity.AuditActionUpdate, magazineAuditState(stored), magazineAuditState(mag))
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Magazine.Update(ctx, mag, audit)
 	if err != nil {
 		return fmt.Errorf("update magazine: %w", err)
 	}
 
 	return nil
 }
-func (s *MagazineService) Delete(ctx context.Context, id int) error {
-	return s.repo.Magazine.Delete(ctx, id)
+func (s *MagazineService) Delete(ctx context.Context, id, version int) error {
+	ctx, span := tracing.Start(ctx, "MagazineService.Delete")
+	defer span.End()
+
+	// deleting a missing magazine is a no-op, unless a version was expected
+	stored, err := s.repo.Magazine.GetById(ctx, id)
+	if errors.Is(err, repository.ErrProductNotFound) && version == 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get magazine: %w", err)
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityMagazine, id, entity.AuditActionDelete, magazineAuditState(stored), nil)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.Magazine.Delete(ctx, id, version, audit); err != nil {
+		return err
+	}
+
+	return nil
 }
@@ -3,24 +3,64 @@ package service
 import (
 	"BookStore_API/internal/entity"
 	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
 	"context"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"slices"
+	"time"
 )
 
+var (
+	ErrTrackingNumberRequired = errors.New("tracking number is required to ship the order")
+	ErrPaidStatusReserved     = errors.New("order is marked as paid only by a captured payment")
+	ErrPaidStatusTransition   = errors.New("a paid order can only move on to shipped and then delivered")
+)
+
+// paidTransitions are the statuses a paid order may move on to. Going back would let a new
+// payment be opened for an order that was already captured.
+var paidTransitions = map[string][]string{
+	entity.OrderStatusPaid:      {entity.OrderStatusShipped},
+	entity.OrderStatusShipped:   {entity.OrderStatusDelivered},
+	entity.OrderStatusDelivered: {},
+}
+
 type OrderService struct {
-	repo   *repository.Repository
-	logger *zap.Logger
+	repo     *repository.Repository
+	currency *CurrencyService
+	tax      *TaxService
+	shipping *ShippingService
+	audit    *AuditService
+	logger   *zap.Logger
 }
 
-func NewOrderService(repo *repository.Repository, logger *zap.Logger) *OrderService {
+func NewOrderService(
+	repo *repository.Repository,
+	currency *CurrencyService,
+	tax *TaxService,
+	shipping *ShippingService,
+	audit *AuditService,
+	logger *zap.Logger,
+) *OrderService {
 	return &OrderService{
-		repo:   repo,
-		logger: logger,
+		repo:     repo,
+		currency: currency,
+		tax:      tax,
+		shipping: shipping,
+		audit:    audit,
+		logger:   logger,
 	}
 }
 
 func (s *OrderService) Create(ctx context.Context, order entity.Order) (int, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.Create")
+	defer span.End()
+
+	if order.Status == entity.OrderStatusPaid {
+		return 0, ErrPaidStatusReserved
+	}
+
 	ids := make([]int, len(order.Items))
 	for i, item := range order.Items {
 		ids[i] = item.Product.Id
@@ -36,22 +76,76 @@ func (s *OrderService) Create(ctx context.Context, order entity.Order) (int, err
 		productMap[p.Id] = p
 	}
 
+	itemProducts := make([]*entity.BaseProduct, len(order.Items))
 	for i, item := range order.Items {
-		if prod, ok := productMap[item.Product.Id]; ok {
-			order.Items[i].Product.Price = prod.Price
+		if prod, ok := productMap[item.Product.Id]; ok && prod.DeletedAt == nil {
+			order.Items[i].Product = prod
+			itemProducts[i] = &order.Items[i].Product
 		} else {
-			return 0, fmt.Errorf("order creation failed: product with id %d not found in database", item.Product.Id)
+			return 0, fmt.Errorf("order creation failed: %w: id %d", repository.ErrProductNotFound, item.Product.Id)
+		}
+	}
+
+	// shipping is priced in the base currency before item prices are converted
+	var shippingCost float64
+	if order.Shipping.Method != "" {
+		quote, err := s.shipping.QuoteMethod(ctx, order.Destination, order.Items, order.Shipping.Method)
+		if err != nil {
+			return 0, fmt.Errorf("order creation failed: %w", err)
 		}
+		shippingCost = quote.Cost
+	}
+
+	// prices are converted once and stored with the rate, so order totals never change
+	if order.Currency == "" {
+		order.Currency = s.currency.BaseCurrency()
+	}
+	order.ExchangeRate, err = s.currency.Localize(ctx, order.Currency, time.Now(), itemProducts...)
+	if err != nil {
+		return 0, fmt.Errorf("order creation failed: %w", err)
+	}
+	order.Shipping.Cost = roundPrice(shippingCost * order.ExchangeRate)
+
+	// tax is computed per item and stored with the order
+	if err = s.tax.Calculate(ctx, &order); err != nil {
+		return 0, fmt.Errorf("order creation failed: %w", err)
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityOrder, 0, entity.AuditActionCreate, nil, orderAuditState(order))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := s.repo.Order.Create(ctx, order, audit)
+	if err != nil {
+		return 0, err
 	}
 
-	return s.repo.Order.Create(ctx, order)
+	return id, nil
 }
 func (s *OrderService) GetById(ctx context.Context, id int) (entity.Order, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetById")
+	defer span.End()
+
 	order, err := s.repo.Order.GetById(ctx, id)
 	if err != nil {
 		return entity.Order{}, fmt.Errorf("order get failed: %w", err)
 	}
 
+	// items are shown as they were ordered, catalog edits and deletes do not change them
+	for i := range order.Items {
+		order.Items[i].Product.Currency = order.Currency
+	}
+
+	return order, nil
+}
+
+// AnnotateProducts tells for every item whether its product still exists and what it costs now,
+// converted into the order currency at the current rate.
+func (s *OrderService) AnnotateProducts(ctx context.Context, order *entity.Order) error {
+	ctx, span := tracing.Start(ctx, "OrderService.AnnotateProducts")
+	defer span.End()
+
 	ids := make([]int, len(order.Items))
 	for i, item := range order.Items {
 		ids[i] = item.Product.Id
@@ -59,27 +153,152 @@ func (s *OrderService) GetById(ctx context.Context, id int) (entity.Order, error
 
 	products, err := s.repo.Product.GetByIds(ctx, ids)
 	if err != nil {
-		return entity.Order{}, fmt.Errorf("failed to get products by ids: %w", err)
+		return fmt.Errorf("failed to get products by ids: %w", err)
 	}
 
-	productMap := make(map[int]entity.BaseProduct)
-	for _, p := range products {
-		productMap[p.Id] = p
+	// deleted products are left out, their items are annotated as gone
+	live := make(map[int]*entity.BaseProduct, len(products))
+	toLocalize := make([]*entity.BaseProduct, 0, len(products))
+	for i := range products {
+		if products[i].DeletedAt == nil {
+			live[products[i].Id] = &products[i]
+			toLocalize = append(toLocalize, &products[i])
+		}
+	}
+
+	if len(toLocalize) > 0 {
+		if _, err = s.currency.Localize(ctx, order.Currency, time.Now(), toLocalize...); err != nil {
+			return fmt.Errorf("order annotation failed: %w", err)
+		}
+	}
+
+	for i, item := range order.Items {
+		current := &entity.CurrentProduct{}
+		if prod, ok := live[item.Product.Id]; ok {
+			current.Exists = true
+			current.Price = prod.Price
+		}
+		order.Items[i].Current = current
+	}
+
+	return nil
+}
+func (s *OrderService) Update(ctx context.Context, order entity.Order) error {
+	ctx, span := tracing.Start(ctx, "OrderService.Update")
+	defer span.End()
+
+	stored, err := s.repo.Order.GetById(ctx, order.Id)
+	if err != nil {
+		return fmt.Errorf("order get failed: %w", err)
 	}
 
+	// payments are the only way to an order being paid
+	if order.Status == entity.OrderStatusPaid && stored.Status != entity.OrderStatusPaid {
+		return ErrPaidStatusReserved
+	}
+	if next, ok := paidTransitions[stored.Status]; ok && order.Status != stored.Status && !slices.Contains(next, order.Status) {
+		return ErrPaidStatusTransition
+	}
+
+	storedProducts := make(map[int]entity.BaseProduct, len(stored.Items))
+	for _, item := range stored.Items {
+		storedProducts[item.Product.Id] = item.Product
+	}
+
+	// items already in the order keep their price and tax class
+	newIds := make([]int, 0)
 	for i, item := range order.Items {
-		if prod, ok := productMap[item.Product.Id]; ok {
+		if prod, ok := storedProducts[item.Product.Id]; ok {
 			order.Items[i].Product = prod
 		} else {
-			return entity.Order{}, fmt.Errorf("order creation failed: product with id %d not found in database", item.Product.Id)
+			newIds = append(newIds, item.Product.Id)
 		}
 	}
 
-	return order, nil
+	// new items are priced in the order currency with the rate stored on the order
+	if len(newIds) > 0 {
+		products, err := s.repo.Product.GetByIds(ctx, newIds)
+		if err != nil {
+			return fmt.Errorf("failed to get products by ids: %w", err)
+		}
+
+		productMap := make(map[int]entity.BaseProduct)
+		for _, p := range products {
+			productMap[p.Id] = p
+		}
+
+		newProducts := make([]*entity.BaseProduct, 0, len(newIds))
+		for i, item := range order.Items {
+			if _, ok := storedProducts[item.Product.Id]; ok {
+				continue
+			}
+			prod, ok := productMap[item.Product.Id]
+			if !ok || prod.DeletedAt != nil {
+				return fmt.Errorf("order update failed: %w: id %d", repository.ErrProductNotFound, item.Product.Id)
+			}
+			order.Items[i].Product = prod
+			newProducts = append(newProducts, &order.Items[i].Product)
+		}
+
+		if err = s.currency.ApplyRate(ctx, stored.Currency, stored.ExchangeRate, newProducts...); err != nil {
+			return fmt.Errorf("order update failed: %w", err)
+		}
+	}
+
+	// shipping method and cost are fixed at creation, a tracking number comes with shipping
+	order.Shipping.Method = stored.Shipping.Method
+	order.Shipping.Cost = stored.Shipping.Cost
+	if order.Status == entity.OrderStatusShipped && stored.Status != entity.OrderStatusShipped &&
+		order.Shipping.Method != entity.ShippingMethodPickup && order.Shipping.TrackingNumber == "" {
+		return ErrTrackingNumberRequired
+	}
+
+	// added and changed items are taxed for the stored destination and mode, the others keep
+	// the tax they were ordered with
+	order.Destination = stored.Destination
+	order.TaxMode = stored.TaxMode
+	if err = s.tax.Recalculate(ctx, &order, stored.Items); err != nil {
+		return fmt.Errorf("order update failed: %w", err)
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityOrder, order.Id, entity.AuditActionUpdate, orderAuditState(stored), orderAuditState(order))
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.Order.Update(ctx, order, audit); err != nil {
+		return err
+	}
+
+	return nil
 }
-func (s *OrderService) Update(ctx context.Context, order entity.Order) error {
-	return s.repo.Order.Update(ctx, order)
+func (s *OrderService) Delete(ctx context.Context, id, version int) error {
+	ctx, span := tracing.Start(ctx, "OrderService.Delete")
+	defer span.End()
+
+	// deleting a missing order is a no-op, unless a version was expected
+	stored, err := s.repo.Order.GetById(ctx, id)
+	if errors.Is(err, repository.ErrOrderNotFound) && version == 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("order get failed: %w", err)
+	}
+
+	audit, err := s.audit.Entry(ctx, entity.AuditEntityOrder, id, entity.AuditActionDelete, orderAuditState(stored), nil)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.Order.Delete(ctx, id, version, audit); err != nil {
+		return err
+	}
+
+	return nil
 }
-func (s *OrderService) Delete(ctx context.Context, id int) error {
-	return s.repo.Order.Delete(ctx, id)
+func (s *OrderService) GetHistory(ctx context.Context, orderId int) ([]entity.OrderHistoryEntry, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetHistory")
+	defer span.End()
+
+	return s.repo.Order.GetHistory(ctx, orderId)
 }
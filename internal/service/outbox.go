@@ -0,0 +1,111 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/outbox"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+// outboxRetryBase is the delay before the first retry of a failed delivery, it doubles with every attempt.
+const outboxRetryBase = time.Second
+
+type OutboxService struct {
+	repo       *repository.Repository
+	sinks      []outbox.Sink
+	batchSize  int
+	lease      time.Duration
+	maxBackoff time.Duration
+	retention  time.Duration
+	logger     *zap.Logger
+}
+
+func NewOutboxService(
+	repo *repository.Repository,
+	sinks []outbox.Sink,
+	batchSize int,
+	sinkTimeout, maxBackoff, retention time.Duration,
+	logger *zap.Logger,
+) *OutboxService {
+	return &OutboxService{
+		repo:      repo,
+		sinks:     sinks,
+		batchSize: batchSize,
+		// a claimed batch must be delivered before other dispatchers may take it again
+		lease:      sinkTimeout*time.Duration(batchSize*max(len(sinks), 1)) + time.Minute,
+		maxBackoff: maxBackoff,
+		retention:  retention,
+		logger:     logger,
+	}
+}
+
+// Dispatch delivers one batch of due events to every sink and returns the number of claimed
+// events. A failed event is retried with exponential backoff and holds back the later
+// events of its aggregate until it is delivered.
+func (s *OutboxService) Dispatch(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "OutboxService.Dispatch")
+	defer span.End()
+
+	events, err := s.repo.Outbox.Claim(ctx, now, now.Add(s.lease), s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err = s.publish(ctx, event); err != nil {
+			retryAt := time.Now().Add(s.backoff(event.Attempts))
+
+			reqctx.Logger(ctx, s.logger).Warn("failed to deliver outbox event",
+				zap.Int64("id", event.Id),
+				zap.String("event_type", event.EventType),
+				zap.Int("attempts", event.Attempts),
+				zap.Time("retry_at", retryAt),
+				zap.Error(err),
+			)
+
+			if err = s.repo.Outbox.MarkFailed(ctx, event.Id, retryAt, err.Error()); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if err = s.repo.Outbox.MarkDispatched(ctx, event.Id, time.Now()); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// PurgeDispatched deletes delivered events older than the retention period.
+func (s *OutboxService) PurgeDispatched(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "OutboxService.PurgeDispatched")
+	defer span.End()
+
+	return s.repo.Outbox.Purge(ctx, now.Add(-s.retention))
+}
+
+// publish sends the event to all sinks, it is retried as a whole so a sink that already
+// accepted it gets it again.
+func (s *OutboxService) publish(ctx context.Context, event entity.OutboxEvent) error {
+	var errs []error
+	for _, sink := range s.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *OutboxService) backoff(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.maxBackoff)
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/payment"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrOrderNotPayable        = errors.New("order can not be paid in its current status")
+	ErrPaymentStatus          = errors.New("operation is not allowed in the current payment status")
+	ErrRefundExceedsCaptured  = errors.New("refund amount exceeds the captured amount")
+	ErrUnknownPaymentProvider = errors.New("unknown payment provider")
+)
+
+type PaymentService struct {
+	repo        *repository.Repository
+	provider    payment.PaymentProvider
+	secret      string
+	autoCapture bool
+	logger      *zap.Logger
+}
+
+func NewPaymentService(
+	repo *repository.Repository,
+	provider payment.PaymentProvider,
+	secret string,
+	autoCapture bool,
+	logger *zap.Logger,
+) *PaymentService {
+	return &PaymentService{
+		repo:        repo,
+		provider:    provider,
+		secret:      secret,
+		autoCapture: autoCapture,
+		logger:      logger,
+	}
+}
+
+// Create charges the order total with the configured provider. Declined payments are
+// stored too, so every attempt stays visible on the order.
+func (s *PaymentService) Create(ctx context.Context, orderId int, token string) (entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.Create")
+	defer span.End()
+
+	order, err := s.repo.Order.GetById(ctx, orderId)
+	if err != nil {
+		return entity.Payment{}, fmt.Errorf("order get failed: %w", err)
+	}
+
+	switch order.Status {
+	case entity.OrderStatusCreated, entity.OrderStatusAccepted, entity.OrderStatusPending:
+	default:
+		return entity.Payment{}, ErrOrderNotPayable
+	}
+
+	_, _, total := order.Totals()
+	p := entity.Payment{
+		OrderId:  orderId,
+		Provider: s.provider.Name(),
+		Status:   entity.PaymentStatusPending,
+		Amount:   roundPrice(total),
+		Currency: order.Currency,
+	}
+
+	// the attempt is stored before the provider call so it is never lost
+	p.Id, err = s.repo.Payment.Create(ctx, p)
+	if err != nil {
+		return entity.Payment{}, fmt.Errorf("payment creation failed: %w", err)
+	}
+
+	res, err := s.provider.Authorize(ctx, payment.AuthorizeRequest{
+		PaymentId: p.Id,
+		OrderId:   orderId,
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+		Token:     token,
+		Capture:   s.autoCapture,
+	})
+	if err != nil {
+		p.Status = entity.PaymentStatusFailed
+		p.FailureReason = err.Error()
+	} else {
+		p.ProviderRef = res.ProviderRef
+		p.Status = res.Status
+		p.FailureReason = res.FailureReason
+	}
+
+	if err = s.repo.Payment.Update(ctx, p); err != nil {
+		return entity.Payment{}, fmt.Errorf("payment update failed: %w", err)
+	}
+
+	reqctx.Logger(ctx, s.logger).Info("payment attempt finished",
+		zap.Int("payment_id", p.Id),
+		zap.Int("order_id", orderId),
+		zap.String("status", p.Status),
+	)
+	return p, nil
+}
+func (s *PaymentService) GetByOrderId(ctx context.Context, orderId int) ([]entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.GetByOrderId")
+	defer span.End()
+
+	return s.repo.Payment.GetByOrderId(ctx, orderId)
+}
+func (s *PaymentService) Capture(ctx context.Context, id int) (entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.Capture")
+	defer span.End()
+
+	p, err := s.repo.Payment.GetById(ctx, id)
+	if err != nil {
+		return entity.Payment{}, err
+	}
+	if !entity.CanTransitionPayment(p.Status, entity.PaymentStatusCaptured) || p.ProviderRef == "" {
+		return entity.Payment{}, ErrPaymentStatus
+	}
+
+	res, err := s.provider.Capture(ctx, p.ProviderRef, p.Amount)
+	if err != nil {
+		return entity.Payment{}, fmt.Errorf("payment capture failed: %w", err)
+	}
+
+	p.Status = res.Status
+	if err = s.repo.Payment.Update(ctx, p); err != nil {
+		return entity.Payment{}, fmt.Errorf("payment update failed: %w", err)
+	}
+	return p, nil
+}
+
+// Refund returns amount to the customer, the whole remaining amount if it is zero. The refund
+// is reserved on the payment before the provider is called and taken back if the call fails,
+// so concurrent refunds never exceed the captured amount.
+func (s *PaymentService) Refund(ctx context.Context, id int, amount float64) (entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.Refund")
+	defer span.End()
+
+	p, err := s.repo.Payment.GetById(ctx, id)
+	if err != nil {
+		return entity.Payment{}, err
+	}
+	if !entity.CanTransitionPayment(p.Status, entity.PaymentStatusRefunded) {
+		return entity.Payment{}, ErrPaymentStatus
+	}
+
+	remaining := roundPrice(p.Amount - p.RefundedAmount)
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount > remaining {
+		return entity.Payment{}, ErrRefundExceedsCaptured
+	}
+
+	p, err = s.repo.Payment.ReserveRefund(ctx, id, amount)
+	if errors.Is(err, repository.ErrRefundNotAllowed) {
+		// another refund was made meanwhile
+		return entity.Payment{}, ErrRefundExceedsCaptured
+	}
+	if err != nil {
+		return entity.Payment{}, fmt.Errorf("payment refund failed: %w", err)
+	}
+
+	if _, err = s.provider.Refund(ctx, p.ProviderRef, amount); err != nil {
+		// the reservation is taken back even if the request was canceled
+		if releaseErr := s.repo.Payment.ReleaseRefund(context.WithoutCancel(ctx), id, amount); releaseErr != nil {
+			reqctx.Logger(ctx, s.logger).Error("failed to release refund, the refunded amount is too high",
+				zap.Int("payment_id", id),
+				zap.Float64("amount", amount),
+				zap.Error(releaseErr),
+			)
+		}
+		return entity.Payment{}, fmt.Errorf("payment refund failed: %w", err)
+	}
+	return p, nil
+}
+func (s *PaymentService) Void(ctx context.Context, id int) (entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.Void")
+	defer span.End()
+
+	p, err := s.repo.Payment.GetById(ctx, id)
+	if err != nil {
+		return entity.Payment{}, err
+	}
+	if !entity.CanTransitionPayment(p.Status, entity.PaymentStatusVoided) || p.ProviderRef == "" {
+		return entity.Payment{}, ErrPaymentStatus
+	}
+
+	res, err := s.provider.Void(ctx, p.ProviderRef)
+	if err != nil {
+		return entity.Payment{}, fmt.Errorf("payment void failed: %w", err)
+	}
+
+	p.Status = res.Status
+	if err = s.repo.Payment.Update(ctx, p); err != nil {
+		return entity.Payment{}, fmt.Errorf("payment update failed: %w", err)
+	}
+	return p, nil
+}
+
+// HandleWebhook verifies and applies a provider callback. Duplicate deliveries are accepted
+// and ignored.
+func (s *PaymentService) HandleWebhook(ctx context.Context, provider string, body []byte, signature string) error {
+	ctx, span := tracing.Start(ctx, "PaymentService.HandleWebhook")
+	defer span.End()
+
+	if provider != s.provider.Name() {
+		return ErrUnknownPaymentProvider
+	}
+	if err := payment.Verify(s.secret, body, signature); err != nil {
+		return err
+	}
+
+	var event payment.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	p, applied, err := s.repo.Payment.ApplyEvent(ctx, entity.PaymentEvent{
+		Provider:    provider,
+		EventId:     event.EventId,
+		ProviderRef: event.ProviderRef,
+		Status:      event.Status,
+		Amount:      event.Amount,
+		Payload:     body,
+	})
+	if err != nil {
+		return fmt.Errorf("payment event failed: %w", err)
+	}
+
+	reqctx.Logger(ctx, s.logger).Info("payment webhook handled",
+		zap.String("provider", provider),
+		zap.String("event_id", event.EventId),
+		zap.Int("payment_id", p.Id),
+		zap.String("status", p.Status),
+		zap.Bool("applied", applied),
+	)
+	return nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+type PriceService struct {
+	repo   *repository.Repository
+	logger *zap.Logger
+}
+
+func NewPriceService(repo *repository.Repository, logger *zap.Logger) *PriceService {
+	return &PriceService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *PriceService) GetHistory(ctx context.Context, productId int) ([]entity.PriceChange, error) {
+	ctx, span := tracing.Start(ctx, "PriceService.GetHistory")
+	defer span.End()
+
+	return s.repo.Price.GetHistory(ctx, productId)
+}
+func (s *PriceService) GetSchedules(ctx context.Context, productId int) ([]entity.ScheduledPrice, error) {
+	ctx, span := tracing.Start(ctx, "PriceService.GetSchedules")
+	defer span.End()
+
+	return s.repo.Price.GetSchedules(ctx, productId)
+}
+func (s *PriceService) GetPriceAt(ctx context.Context, productId int, at time.Time) (float64, error) {
+	ctx, span := tracing.Start(ctx, "PriceService.GetPriceAt")
+	defer span.End()
+
+	return s.repo.Price.GetPriceAt(ctx, productId, at)
+}
+func (s *PriceService) Schedule(ctx context.Context, sp entity.ScheduledPrice) (int, error) {
+	ctx, span := tracing.Start(ctx, "PriceService.Schedule")
+	defer span.End()
+
+	products, err := s.repo.Product.GetByIds(ctx, []int{sp.ProductId})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get products by ids: %w", err)
+	}
+	if len(products) == 0 || products[0].DeletedAt != nil {
+		return 0, fmt.Errorf("schedule price failed: product with id %d: %w", sp.ProductId, repository.ErrProductNotFound)
+	}
+
+	id, err := s.repo.Price.CreateSchedule(ctx, sp)
+	if err != nil {
+		return 0, fmt.Errorf("create scheduled price: %w", err)
+	}
+
+	return id, nil
+}
+func (s *PriceService) CancelSchedule(ctx context.Context, productId, id int) error {
+	ctx, span := tracing.Start(ctx, "PriceService.CancelSchedule")
+	defer span.End()
+
+	return s.repo.Price.CancelSchedule(ctx, productId, id)
+}
+func (s *PriceService) ApplyDue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "PriceService.ApplyDue")
+	defer span.End()
+
+	return s.repo.Price.ApplyDue(ctx, now)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrOrderNotReturnable   = errors.New("only delivered orders can be returned")
+	ErrDamagedQuantity      = errors.New("damaged quantity exceeds the returned quantity")
+	ErrRefundExceedsReturn  = errors.New("refund amount exceeds the return amount")
+	ErrNoRefundablePayment  = errors.New("order has no captured payment to refund")
+	ErrReturnItemDuplicated = errors.New("product is listed more than once in the return")
+)
+
+type ReturnService struct {
+	repo     *repository.Repository
+	payments *PaymentService
+	logger   *zap.Logger
+}
+
+func NewReturnService(repo *repository.Repository, payments *PaymentService, logger *zap.Logger) *ReturnService {
+	return &ReturnService{
+		repo:     repo,
+		payments: payments,
+		logger:   logger,
+	}
+}
+
+// Create opens a return for items of a delivered order. The refund amount is computed from
+// the prices paid, so sale prices and other reductions at order time are kept; in exclusive
+// tax mode the proportional part of the item tax is added. Shipping is not refunded.
+func (s *ReturnService) Create(ctx context.Context, ret entity.OrderReturn) (int, error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.Create")
+	defer span.End()
+
+	order, err := s.repo.Order.GetById(ctx, ret.OrderId)
+	if err != nil {
+		return 0, fmt.Errorf("order get failed: %w", err)
+	}
+	if order.Status != entity.OrderStatusDelivered {
+		return 0, ErrOrderNotReturnable
+	}
+
+	ordered := make(map[int]int, len(order.Items))
+	items := make(map[int]entity.OrderItem, len(order.Items))
+	for _, item := range order.Items {
+		ordered[item.Product.Id] = item.Quantity
+		items[item.Product.Id] = item
+	}
+
+	seen := make(map[int]struct{}, len(ret.Items))
+	ret.RefundAmount = 0
+	for i, ri := range ret.Items {
+		if _, ok := seen[ri.ProductId]; ok {
+			return 0, ErrReturnItemDuplicated
+		}
+		seen[ri.ProductId] = struct{}{}
+
+		item, ok := items[ri.ProductId]
+		if !ok || ri.Quantity > item.Quantity {
+			return 0, repository.ErrReturnQuantity
+		}
+
+		ret.Items[i].Price = item.Product.Price
+		ret.Items[i].TaxAmount = roundPrice(item.TaxAmount * float64(ri.Quantity) / float64(item.Quantity))
+
+		ret.RefundAmount += item.Product.Price * float64(ri.Quantity)
+		if order.TaxMode != entity.TaxModeInclusive {
+			ret.RefundAmount += ret.Items[i].TaxAmount
+		}
+	}
+	ret.RefundAmount = roundPrice(ret.RefundAmount)
+
+	return s.repo.Return.Create(ctx, ret, ordered)
+}
+func (s *ReturnService) GetByOrderId(ctx context.Context, orderId int) ([]entity.OrderReturn, error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.GetByOrderId")
+	defer span.End()
+
+	return s.repo.Return.GetByOrderId(ctx, orderId)
+}
+func (s *ReturnService) Approve(ctx context.Context, id int, note string) error {
+	ctx, span := tracing.Start(ctx, "ReturnService.Approve")
+	defer span.End()
+
+	return s.repo.Return.UpdateStatus(ctx, id, entity.ReturnStatusRequested, entity.ReturnStatusApproved, note)
+}
+func (s *ReturnService) Reject(ctx context.Context, id int, note string) error {
+	ctx, span := tracing.Start(ctx, "ReturnService.Reject")
+	defer span.End()
+
+	return s.repo.Return.UpdateStatus(ctx, id, entity.ReturnStatusRequested, entity.ReturnStatusRejected, note)
+}
+
+// Receive restocks the returned goods; damaged holds, per product, the quantity that
+// goes to the damaged stock instead.
+func (s *ReturnService) Receive(ctx context.Context, id int, damaged map[int]int, note string) error {
+	ctx, span := tracing.Start(ctx, "ReturnService.Receive")
+	defer span.End()
+
+	ret, err := s.repo.Return.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	matched := 0
+	for i, item := range ret.Items {
+		qty, ok := damaged[item.ProductId]
+		if !ok {
+			continue
+		}
+		if qty > item.Quantity {
+			return ErrDamagedQuantity
+		}
+		ret.Items[i].Damaged = qty
+		matched++
+	}
+	// damaged products must be part of the return
+	if matched != len(damaged) {
+		return ErrDamagedQuantity
+	}
+
+	ret.Note = note
+	return s.repo.Return.Receive(ctx, ret)
+}
+
+// Refund pays the return back through the payment of the order. A zero amount refunds the
+// whole return amount, a smaller one makes a partial refund.
+func (s *ReturnService) Refund(ctx context.Context, id int, amount float64) (entity.OrderReturn, error) {
+	ctx, span := tracing.Start(ctx, "ReturnService.Refund")
+	defer span.End()
+
+	ret, err := s.repo.Return.GetById(ctx, id)
+	if err != nil {
+		return entity.OrderReturn{}, err
+	}
+	if ret.Status != entity.ReturnStatusReceived {
+		return entity.OrderReturn{}, repository.ErrReturnStatus
+	}
+
+	if amount == 0 {
+		amount = ret.RefundAmount
+	}
+	if amount > ret.RefundAmount {
+		return entity.OrderReturn{}, ErrRefundExceedsReturn
+	}
+
+	payments, err := s.repo.Payment.GetByOrderId(ctx, ret.OrderId)
+	if err != nil {
+		return entity.OrderReturn{}, fmt.Errorf("payments get failed: %w", err)
+	}
+
+	// the latest captured payment is refunded
+	var payment *entity.Payment
+	for i := len(payments) - 1; i >= 0; i-- {
+		if entity.CanTransitionPayment(payments[i].Status, entity.PaymentStatusRefunded) {
+			payment = &payments[i]
+			break
+		}
+	}
+	if payment == nil {
+		return entity.OrderReturn{}, ErrNoRefundablePayment
+	}
+
+	// the return is claimed before any money moves, so concurrent requests refund it once
+	if err = s.repo.Return.ClaimRefund(ctx, id); err != nil {
+		return entity.OrderReturn{}, err
+	}
+
+	if _, err = s.payments.Refund(ctx, payment.Id, amount); err != nil {
+		if releaseErr := s.repo.Return.ReleaseRefund(context.WithoutCancel(ctx), id); releaseErr != nil {
+			reqctx.Logger(ctx, s.logger).Error("failed to release return after a failed refund",
+				zap.Int("return_id", id),
+				zap.Error(releaseErr),
+			)
+		}
+		return entity.OrderReturn{}, err
+	}
+
+	// the money is refunded, a failure leaves the return refunding so it is not refunded again
+	if err = s.repo.Return.MarkRefunded(context.WithoutCancel(ctx), id, payment.Id, amount); err != nil {
+		reqctx.Logger(ctx, s.logger).Error("return refunded but not marked as refunded",
+			zap.Int("return_id", id),
+			zap.Int("payment_id", payment.Id),
+			zap.Float64("amount", amount),
+			zap.Error(err),
+		)
+		return entity.OrderReturn{}, fmt.Errorf("return update failed: %w", err)
+	}
+
+	reqctx.Logger(ctx, s.logger).Info("return refunded",
+		zap.Int("return_id", id),
+		zap.Int("payment_id", payment.Id),
+		zap.Float64("amount", amount),
+	)
+	return s.repo.Return.GetById(ctx, id)
+}
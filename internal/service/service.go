@@ -1,43 +1,202 @@
 package service
 
 import (
+	"BookStore_API/internal/config"
 	"BookStore_API/internal/entity"
+	"BookStore_API/internal/outbox"
+	"BookStore_API/internal/payment"
 	"BookStore_API/internal/repository"
 	"context"
 	"go.uber.org/zap"
+	"time"
 )
 
+type Health interface {
+	Check(ctx context.Context) entity.HealthReport
+}
+
+type Stats interface {
+	Get(ctx context.Context) (entity.BusinessStats, error)
+}
+
+type Price interface {
+	GetHistory(ctx context.Context, productId int) ([]entity.PriceChange, error)
+	GetSchedules(ctx context.Context, productId int) ([]entity.ScheduledPrice, error)
+	GetPriceAt(ctx context.Context, productId int, at time.Time) (float64, error)
+	Schedule(ctx context.Context, sp entity.ScheduledPrice) (int, error)
+	CancelSchedule(ctx context.Context, productId, id int) error
+	ApplyDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type Currency interface {
+	BaseCurrency() string
+	CreateRate(ctx context.Context, rate entity.ExchangeRate) (int, error)
+	GetRates(ctx context.Context, currency string) ([]entity.ExchangeRate, error)
+	SetPriceOverride(ctx context.Context, po entity.PriceOverride) error
+	DeletePriceOverride(ctx context.Context, productId int, currency string) error
+	GetPriceOverrides(ctx context.Context, productId int) ([]entity.PriceOverride, error)
+	Localize(ctx context.Context, currency string, at time.Time, products ...*entity.BaseProduct) (float64, error)
+}
+
+type Tax interface {
+	CreateRule(ctx context.Context, rule entity.TaxRule) (int, error)
+	GetRules(ctx context.Context, country string) ([]entity.TaxRule, error)
+	DeleteRule(ctx context.Context, id int) error
+}
+
+type Shipping interface {
+	CreateZone(ctx context.Context, zone entity.ShippingZone) (int, error)
+	GetZones(ctx context.Context) ([]entity.ShippingZone, error)
+	DeleteZone(ctx context.Context, id int) error
+	CreateMethod(ctx context.Context, method entity.ShippingMethod) (int, error)
+	DeleteMethod(ctx context.Context, id int) error
+	Quote(ctx context.Context, dest entity.Address, items []entity.OrderItem, currency string) ([]entity.ShippingQuote, error)
+}
+
+type Payment interface {
+	Create(ctx context.Context, orderId int, token string) (entity.Payment, error)
+	GetByOrderId(ctx context.Context, orderId int) ([]entity.Payment, error)
+	Capture(ctx context.Context, id int) (entity.Payment, error)
+	Refund(ctx context.Context, id int, amount float64) (entity.Payment, error)
+	Void(ctx context.Context, id int) (entity.Payment, error)
+	HandleWebhook(ctx context.Context, provider string, body []byte, signature string) error
+}
+
+type Return interface {
+	Create(ctx context.Context, ret entity.OrderReturn) (int, error)
+	GetByOrderId(ctx context.Context, orderId int) ([]entity.OrderReturn, error)
+	Approve(ctx context.Context, id int, note string) error
+	Reject(ctx context.Context, id int, note string) error
+	Receive(ctx context.Context, id int, damaged map[int]int, note string) error
+	Refund(ctx context.Context, id int, amount float64) (entity.OrderReturn, error)
+}
+
+type Idempotency interface {
+	Begin(ctx context.Context, key, route, requestHash string) (*entity.IdempotencyRecord, error)
+	Complete(ctx context.Context, key, route string, status int, body []byte) error
+	Release(ctx context.Context, key, route string) error
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type Trash interface {
+	GetAll(ctx context.Context) ([]entity.TrashItem, error)
+	RestoreProduct(ctx context.Context, id int) error
+	RestoreOrder(ctx context.Context, id int) error
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type Audit interface {
+	Get(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error)
+}
+
+type Outbox interface {
+	Dispatch(ctx context.Context, now time.Time) (int, error)
+	PurgeDispatched(ctx context.Context, now time.Time) (int, error)
+}
+
+type Webhook interface {
+	CreateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error)
+	GetEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error)
+	GetEndpointById(ctx context.Context, id int) (entity.WebhookEndpoint, error)
+	UpdateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) error
+	DeleteEndpoint(ctx context.Context, id int) error
+	GetDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error)
+	Redeliver(ctx context.Context, endpointId int, deliveryId int64) (int64, error)
+	Deliver(ctx context.Context, now time.Time) (int, error)
+}
+
+type Stream interface {
+	Subscribe(filter entity.EventFilter) (<-chan entity.OutboxEvent, func())
+	Replay(ctx context.Context, afterId int64, filter entity.EventFilter, fn func(entity.OutboxEvent) error) error
+	Listen(ctx context.Context) error
+}
+
 type Book interface {
 	Create(ctx context.Context, book entity.Book) (int, error)
 	GetById(ctx context.Context, id int) (entity.Book, error)
 	Update(ctx context.Context, book entity.Book) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id, version int) error
 }
 
 type Magazine interface {
 	Create(ctx context.Context, mag entity.Magazine) (int, error)
 	GetById(ctx context.Context, id int) (entity.Magazine, error)
 	Update(ctx context.Context, mag entity.Magazine) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id, version int) error
 }
 
 type Order interface {
 	Create(ctx context.Context, order entity.Order) (int, error)
 	GetById(ctx context.Context, id int) (entity.Order, error)
 	Update(ctx context.Context, order entity.Order) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id, version int) error
+	GetHistory(ctx context.Context, orderId int) ([]entity.OrderHistoryEntry, error)
+	AnnotateProducts(ctx context.Context, order *entity.Order) error
 }
 
 type Service struct {
+	Health
+	Stats
+	Price
+	Currency
+	Tax
+	Shipping
+	Payment
+	Return
+	Idempotency
+	Trash
+	Audit
+	Outbox
+	Webhook
+	Stream
 	Book
 	Magazine
 	Order
 }
 
-func NewService(r *repository.Repository, logger *zap.Logger) *Service {
+func NewService(
+	r *repository.Repository,
+	cfg *config.Config,
+	provider payment.PaymentProvider,
+	sinks []outbox.Sink,
+	migrationVersion uint,
+	workers WorkerStatus,
+	logger *zap.Logger,
+) *Service {
+	currency := NewCurrencyService(r, cfg.BaseCurrency, logger)
+	tax := NewTaxService(r, cfg.TaxMode, logger)
+	shipping := NewShippingService(r, currency, logger)
+	payments := NewPaymentService(r, provider, cfg.PaymentCfg.WebhookSecret, cfg.PaymentCfg.AutoCapture, logger)
+	audit := NewAuditService(r, logger)
+	webhooks := NewWebhookService(r, WebhookOptions{
+		Timeout:      cfg.WebhookCfg.Timeout,
+		BatchSize:    cfg.WebhookCfg.BatchSize,
+		MaxAttempts:  cfg.WebhookCfg.MaxAttempts,
+		MaxBackoff:   cfg.WebhookCfg.MaxBackoff,
+		DisableAfter: cfg.WebhookCfg.DisableAfter,
+		AllowPrivate: cfg.WebhookCfg.AllowPrivateNetworks,
+	}, logger)
+	// webhooks receive every event, next to the configured sinks
+	events := NewOutboxService(r, append(sinks, webhooks), cfg.OutboxCfg.BatchSize,
+		cfg.OutboxCfg.Timeout, cfg.OutboxCfg.MaxBackoff, cfg.OutboxCfg.Retention, logger)
+
 	return &Service{
-		Book:     NewBookService(r, logger),
-		Magazine: NewMagazineService(r, logger),
-		Order:    NewOrderService(r, logger),
+		Health:      NewHealthService(r, migrationVersion, workers, logger),
+		Stats:       NewStatsService(r, logger),
+		Price:       NewPriceService(r, logger),
+		Currency:    currency,
+		Tax:         tax,
+		Shipping:    shipping,
+		Payment:     payments,
+		Return:      NewReturnService(r, payments, logger),
+		Idempotency: NewIdempotencyService(r, cfg.IdempotencyTTL, logger),
+		Trash:       NewTrashService(r, cfg.TrashRetention, logger),
+		Audit:       audit,
+		Outbox:      events,
+		Webhook:     webhooks,
+		Stream:      NewStreamService(r, cfg.StreamCfg.BufferSize, logger),
+		Book:        NewBookService(r, audit, logger),
+		Magazine:    NewMagazineService(r, audit, logger),
+		Order:       NewOrderService(r, currency, tax, shipping, audit, logger),
 	}
 }
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrShippingMethodNotFound = errors.New("shipping method not available for destination")
+	ErrDestinationRequired    = errors.New("destination is required for shipping")
+)
+
+type ShippingService struct {
+	repo     *repository.Repository
+	currency *CurrencyService
+	logger   *zap.Logger
+}
+
+func NewShippingService(repo *repository.Repository, currency *CurrencyService, logger *zap.Logger) *ShippingService {
+	return &ShippingService{
+		repo:     repo,
+		currency: currency,
+		logger:   logger,
+	}
+}
+
+func (s *ShippingService) CreateZone(ctx context.Context, zone entity.ShippingZone) (int, error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.CreateZone")
+	defer span.End()
+
+	id, err := s.repo.Shipping.CreateZone(ctx, zone)
+	if err != nil {
+		return 0, fmt.Errorf("create shipping zone: %w", err)
+	}
+	return id, nil
+}
+func (s *ShippingService) GetZones(ctx context.Context) ([]entity.ShippingZone, error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.GetZones")
+	defer span.End()
+
+	return s.repo.Shipping.GetZones(ctx)
+}
+func (s *ShippingService) DeleteZone(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ShippingService.DeleteZone")
+	defer span.End()
+
+	return s.repo.Shipping.DeleteZone(ctx, id)
+}
+func (s *ShippingService) CreateMethod(ctx context.Context, method entity.ShippingMethod) (int, error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.CreateMethod")
+	defer span.End()
+
+	id, err := s.repo.Shipping.CreateMethod(ctx, method)
+	if err != nil {
+		return 0, fmt.Errorf("create shipping method: %w", err)
+	}
+	return id, nil
+}
+func (s *ShippingService) DeleteMethod(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ShippingService.DeleteMethod")
+	defer span.End()
+
+	return s.repo.Shipping.DeleteMethod(ctx, id)
+}
+
+// Quote prices every shipping method available for the destination. Only product ids
+// and quantities of the items are used, products are read from the catalog.
+func (s *ShippingService) Quote(ctx context.Context, dest entity.Address, items []entity.OrderItem, currency string) ([]entity.ShippingQuote, error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.Quote")
+	defer span.End()
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.Product.Id
+	}
+
+	products, err := s.repo.Product.GetByIds(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products by ids: %w", err)
+	}
+
+	productMap := make(map[int]entity.BaseProduct)
+	for _, p := range products {
+		productMap[p.Id] = p
+	}
+
+	priced := make([]entity.OrderItem, len(items))
+	for i, item := range items {
+		prod, ok := productMap[item.Product.Id]
+		if !ok {
+			return nil, fmt.Errorf("shipping quote failed: %w: id %d", repository.ErrProductNotFound, item.Product.Id)
+		}
+		priced[i] = entity.OrderItem{Product: prod, Quantity: item.Quantity}
+	}
+
+	quotes, err := s.quoteAll(ctx, dest, priced)
+	if err != nil {
+		return nil, err
+	}
+
+	// costs are kept in the base currency unless another one is requested
+	rate, err := s.currency.Localize(ctx, currency, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("shipping quote failed: %w", err)
+	}
+	if currency == "" {
+		currency = s.currency.BaseCurrency()
+	}
+	for i := range quotes {
+		quotes[i].Cost = roundPrice(quotes[i].Cost * rate)
+		quotes[i].Currency = currency
+	}
+
+	return quotes, nil
+}
+
+// QuoteMethod prices a single shipping method in the base currency for items that
+// already carry their catalog products.
+func (s *ShippingService) QuoteMethod(ctx context.Context, dest entity.Address, items []entity.OrderItem, code string) (entity.ShippingQuote, error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.QuoteMethod")
+	defer span.End()
+
+	quotes, err := s.quoteAll(ctx, dest, items)
+	if err != nil {
+		return entity.ShippingQuote{}, err
+	}
+
+	for _, q := range quotes {
+		if q.Code == code {
+			return q, nil
+		}
+	}
+
+	return entity.ShippingQuote{}, fmt.Errorf("%w: %s", ErrShippingMethodNotFound, code)
+}
+
+func (s *ShippingService) quoteAll(ctx context.Context, dest entity.Address, items []entity.OrderItem) ([]entity.ShippingQuote, error) {
+	if dest.Country == "" {
+		return nil, ErrDestinationRequired
+	}
+
+	zone, err := s.repo.Shipping.GetZoneForCountry(ctx, dest.Country)
+	if err != nil {
+		return nil, fmt.Errorf("get shipping zone: %w", err)
+	}
+
+	var weight, count, value float64
+	for _, item := range items {
+		weight += float64(item.Product.WeightGrams * item.Quantity)
+		count += float64(item.Quantity)
+		value += item.Product.Price * float64(item.Quantity)
+	}
+
+	quotes := make([]entity.ShippingQuote, 0, len(zone.Methods))
+	for _, method := range zone.Methods {
+		var basis float64
+		switch method.Basis {
+		case entity.ShippingBasisWeight:
+			basis = weight
+		case entity.ShippingBasisItemCount:
+			basis = count
+		default:
+			basis = value
+		}
+
+		cost, ok := rateFor(method.Rates, basis)
+		if !ok {
+			// method has no rate for such an order, e.g. the parcel is too light for a pallet tariff
+			continue
+		}
+
+		quote := entity.ShippingQuote{
+			MethodId: method.Id,
+			Code:     method.Code,
+			Name:     method.Name,
+			Cost:     cost,
+		}
+		if method.FreeThreshold != nil && value >= *method.FreeThreshold {
+			quote.Cost = 0
+			quote.Free = true
+		}
+
+		quotes = append(quotes, quote)
+	}
+
+	return quotes, nil
+}
+
+// rateFor returns the price of the rate with the highest MinValue not above value.
+// Methods without rates are free.
+func rateFor(rates []entity.ShippingRate, value float64) (float64, bool) {
+	if len(rates) == 0 {
+		return 0, true
+	}
+
+	found := false
+	var price, best float64
+	for _, rate := range rates {
+		if rate.MinValue <= value && (!found || rate.MinValue >= best) {
+			price, best, found = rate.Price, rate.MinValue, true
+		}
+	}
+
+	return price, found
+}
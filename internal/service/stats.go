@@ -0,0 +1,49 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
+	"context"
+	"go.uber.org/zap"
+)
+
+// orderStatuses are always reported, so a status without orders shows up as 0.
+var orderStatuses = []string{
+	entity.OrderStatusCreated,
+	entity.OrderStatusAccepted,
+	entity.OrderStatusPending,
+	entity.OrderStatusPaid,
+	entity.OrderStatusShipped,
+	entity.OrderStatusDelivered,
+	entity.OrderStatusCanceled,
+}
+
+type StatsService struct {
+	repo   *repository.Repository
+	logger *zap.Logger
+}
+
+func NewStatsService(repo *repository.Repository, logger *zap.Logger) *StatsService {
+	return &StatsService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *StatsService) Get(ctx context.Context) (entity.BusinessStats, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.Get")
+	defer span.End()
+
+	stats, err := s.repo.Stats.Get(ctx)
+	if err != nil {
+		return entity.BusinessStats{}, err
+	}
+
+	for _, status := range orderStatuses {
+		if _, ok := stats.OrdersByStatus[status]; !ok {
+			stats.OrdersByStatus[status] = 0
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// streamPageSize is the number of stored events read at once when a client resumes.
+const streamPageSize = 500
+
+type subscription struct {
+	filter entity.EventFilter
+	events chan entity.OutboxEvent
+}
+
+// StreamService pushes new domain events to the clients connected to this replica. New
+// events are announced by Postgres notifications, so clients of every replica see all of them.
+type StreamService struct {
+	repo       *repository.Repository
+	bufferSize int
+	logger     *zap.Logger
+
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+	// lastId is the newest event announced, events after it are read again after a reconnect
+	lastId int64
+}
+
+func NewStreamService(repo *repository.Repository, bufferSize int, logger *zap.Logger) *StreamService {
+	return &StreamService{
+		repo:       repo,
+		bufferSize: bufferSize,
+		logger:     logger,
+		subs:       make(map[*subscription]struct{}),
+	}
+}
+
+// Subscribe returns the live events matching filter and a function to stop receiving them.
+// The channel is closed when the subscriber falls too far behind, it can resume with Replay.
+func (s *StreamService) Subscribe(filter entity.EventFilter) (<-chan entity.OutboxEvent, func()) {
+	sub := &subscription{
+		filter: filter,
+		events: make(chan entity.OutboxEvent, s.bufferSize),
+	}
+
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+
+	return sub.events, func() { s.unsubscribe(sub) }
+}
+
+// Replay calls fn with the stored events after afterId matching filter, oldest first.
+// Ids are taken when an event is inserted but become visible on commit, so an event whose
+// transaction committed after a later one may not be seen by a client resuming after that
+// later id. Live events are not affected, they are announced on commit.
+func (s *StreamService) Replay(ctx context.Context, afterId int64, filter entity.EventFilter, fn func(entity.OutboxEvent) error) error {
+	ctx, span := tracing.Start(ctx, "StreamService.Replay")
+	defer span.End()
+
+	for {
+		events, err := s.repo.Outbox.GetAfter(ctx, afterId, streamPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if !filter.Matches(e) {
+				continue
+			}
+			if err = fn(e); err != nil {
+				return err
+			}
+		}
+
+		if len(events) < streamPageSize {
+			return nil
+		}
+		afterId = events[len(events)-1].Id
+	}
+}
+
+// Listen announces new events to the subscribers until ctx is done or the database
+// connection fails. Events stored while it was not listening are announced first.
+func (s *StreamService) Listen(ctx context.Context) error {
+	return s.repo.Outbox.Listen(ctx, func() { s.catchUp(ctx) }, func(id int64) { s.announce(ctx, id) })
+}
+
+func (s *StreamService) catchUp(ctx context.Context) {
+	s.mu.Lock()
+	lastId := s.lastId
+	s.mu.Unlock()
+
+	if lastId == 0 {
+		return
+	}
+
+	err := s.Replay(ctx, lastId, entity.EventFilter{}, func(e entity.OutboxEvent) error {
+		s.broadcast(e)
+		return nil
+	})
+	if err != nil {
+		reqctx.Logger(ctx, s.logger).Error("failed to catch up with outbox events",
+			zap.Int64("after_id", lastId),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *StreamService) announce(ctx context.Context, id int64) {
+	s.mu.Lock()
+	s.lastId = max(s.lastId, id)
+	idle := len(s.subs) == 0
+	s.mu.Unlock()
+
+	// nobody is connected, the event is not even read
+	if idle {
+		return
+	}
+
+	e, err := s.repo.Outbox.GetById(ctx, id)
+	if errors.Is(err, repository.ErrEventNotFound) {
+		return
+	}
+	if err != nil {
+		reqctx.Logger(ctx, s.logger).Error("failed to get announced event",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		return
+	}
+	s.broadcast(e)
+}
+
+// broadcast sends the event to every matching subscriber, a subscriber whose buffer is
+// full is dropped instead of slowing down the others.
+func (s *StreamService) broadcast(e entity.OutboxEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastId = max(s.lastId, e.Id)
+
+	for sub := range s.subs {
+		if !sub.filter.Matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			s.logger.Warn("dropping slow event stream subscriber", zap.Int64("event_id", e.Id))
+			delete(s.subs, sub)
+			close(sub.events)
+		}
+	}
+}
+
+func (s *StreamService) unsubscribe(sub *subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub.events)
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+)
+
+type TaxService struct {
+	repo   *repository.Repository
+	mode   string
+	logger *zap.Logger
+}
+
+func NewTaxService(repo *repository.Repository, mode string, logger *zap.Logger) *TaxService {
+	if mode != entity.TaxModeInclusive {
+		mode = entity.TaxModeExclusive
+	}
+
+	return &TaxService{
+		repo:   repo,
+		mode:   mode,
+		logger: logger,
+	}
+}
+
+func (s *TaxService) CreateRule(ctx context.Context, rule entity.TaxRule) (int, error) {
+	ctx, span := tracing.Start(ctx, "TaxService.CreateRule")
+	defer span.End()
+
+	id, err := s.repo.Tax.CreateRule(ctx, rule)
+	if err != nil {
+		return 0, fmt.Errorf("create tax rule: %w", err)
+	}
+	return id, nil
+}
+func (s *TaxService) GetRules(ctx context.Context, country string) ([]entity.TaxRule, error) {
+	ctx, span := tracing.Start(ctx, "TaxService.GetRules")
+	defer span.End()
+
+	return s.repo.Tax.GetRules(ctx, country)
+}
+func (s *TaxService) DeleteRule(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "TaxService.DeleteRule")
+	defer span.End()
+
+	return s.repo.Tax.DeleteRule(ctx, id)
+}
+
+// Calculate computes tax of every order item for the order destination and fills
+// the order tax breakdown. Item prices must already be in the order currency.
+func (s *TaxService) Calculate(ctx context.Context, order *entity.Order) error {
+	ctx, span := tracing.Start(ctx, "TaxService.Calculate")
+	defer span.End()
+
+	return s.calculate(ctx, order, nil)
+}
+
+// Recalculate computes tax of the items added or changed since stored and keeps the stored
+// tax of the others, so later rule changes do not alter the tax of an existing order. The
+// breakdown is rebuilt from the rates of the items.
+func (s *TaxService) Recalculate(ctx context.Context, order *entity.Order, stored []entity.OrderItem) error {
+	ctx, span := tracing.Start(ctx, "TaxService.Recalculate")
+	defer span.End()
+
+	return s.calculate(ctx, order, stored)
+}
+
+func (s *TaxService) calculate(ctx context.Context, order *entity.Order, stored []entity.OrderItem) error {
+	if order.TaxMode == "" {
+		order.TaxMode = s.mode
+	}
+
+	// an item is unchanged when it orders the same quantity of the same product
+	kept := make(map[int]entity.OrderItem, len(stored))
+	for _, item := range stored {
+		kept[item.Product.Id] = item
+	}
+	unchanged := func(item entity.OrderItem) (entity.OrderItem, bool) {
+		prev, ok := kept[item.Product.Id]
+		return prev, ok && prev.Quantity == item.Quantity
+	}
+
+	// rules are read only if some item needs a rate
+	var rates map[string]float64
+	for _, item := range order.Items {
+		if _, ok := unchanged(item); !ok {
+			var err error
+			if rates, err = s.ratesFor(ctx, order.Destination); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	type lineKey struct {
+		class string
+		rate  float64
+	}
+	lines := make(map[lineKey]*entity.TaxLine)
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		if item.Product.TaxClass == "" {
+			item.Product.TaxClass = entity.TaxClassStandard
+		}
+
+		amount := item.Product.Price * float64(item.Quantity)
+
+		// net amount is the taxable base in both modes
+		var tax, net float64
+		if prev, ok := unchanged(*item); ok {
+			item.TaxRate = prev.TaxRate
+			tax = prev.TaxAmount
+		} else {
+			item.TaxRate = rates[item.Product.TaxClass]
+			if order.TaxMode == entity.TaxModeInclusive {
+				tax = roundPrice(amount - amount/(1+item.TaxRate))
+			} else {
+				tax = roundPrice(amount * item.TaxRate)
+			}
+		}
+		if order.TaxMode == entity.TaxModeInclusive {
+			net = amount - tax
+		} else {
+			net = amount
+		}
+		item.TaxAmount = tax
+
+		key := lineKey{class: item.Product.TaxClass, rate: item.TaxRate}
+		line, ok := lines[key]
+		if !ok {
+			line = &entity.TaxLine{TaxClass: key.class, Rate: key.rate}
+			lines[key] = line
+		}
+		line.TaxableAmount = roundPrice(line.TaxableAmount + net)
+		line.TaxAmount = roundPrice(line.TaxAmount + tax)
+	}
+
+	order.TaxLines = make([]entity.TaxLine, 0, len(lines))
+	for _, line := range lines {
+		order.TaxLines = append(order.TaxLines, *line)
+	}
+	sort.Slice(order.TaxLines, func(i, j int) bool {
+		if order.TaxLines[i].TaxClass != order.TaxLines[j].TaxClass {
+			return order.TaxLines[i].TaxClass < order.TaxLines[j].TaxClass
+		}
+		return order.TaxLines[i].Rate < order.TaxLines[j].Rate
+	})
+
+	return nil
+}
+
+// ratesFor returns tax rates by tax class for the destination. Region specific
+// rules take precedence over country-wide ones; unknown destinations are not taxed.
+func (s *TaxService) ratesFor(ctx context.Context, dest entity.Address) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	if dest.Country == "" {
+		return rates, nil
+	}
+
+	rules, err := s.repo.Tax.GetRulesForDestination(ctx, dest)
+	if err != nil {
+		return nil, fmt.Errorf("get tax rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		if rule.Region == "" {
+			rates[rule.TaxClass] = rule.Rate
+		}
+	}
+	for _, rule := range rules {
+		if rule.Region != "" {
+			rates[rule.TaxClass] = rule.Rate
+		}
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/tracing"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+type TrashService struct {
+	repo      *repository.Repository
+	retention time.Duration
+	logger    *zap.Logger
+}
+
+func NewTrashService(repo *repository.Repository, retention time.Duration, logger *zap.Logger) *TrashService {
+	return &TrashService{
+		repo:      repo,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+func (s *TrashService) GetAll(ctx context.Context) ([]entity.TrashItem, error) {
+	ctx, span := tracing.Start(ctx, "TrashService.GetAll")
+	defer span.End()
+
+	items, err := s.repo.Trash.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(s.retention)
+	}
+	return items, nil
+}
+func (s *TrashService) RestoreProduct(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "TrashService.RestoreProduct")
+	defer span.End()
+
+	return s.repo.Trash.RestoreProduct(ctx, id)
+}
+func (s *TrashService) RestoreOrder(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "TrashService.RestoreOrder")
+	defer span.End()
+
+	return s.repo.Trash.RestoreOrder(ctx, id)
+}
+
+// PurgeExpired deletes everything that has been in the trash longer than the retention period.
+func (s *TrashService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "TrashService.PurgeExpired")
+	defer span.End()
+
+	return s.repo.Trash.Purge(ctx, now.Add(-s.retention))
+}
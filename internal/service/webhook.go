@@ -0,0 +1,244 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/outbox"
+	"BookStore_API/internal/repository"
+	"BookStore_API/internal/reqctx"
+	"BookStore_API/internal/tracing"
+	"BookStore_API/internal/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+// webhookRetryBase is the delay before the first retry of a failed delivery, it doubles with every attempt.
+const webhookRetryBase = 10 * time.Second
+
+var (
+	ErrWebhookEndpointDisabled = errors.New("webhook endpoint is disabled")
+	ErrWebhookURLNotAllowed    = errors.New("webhook url is not allowed")
+)
+
+type WebhookOptions struct {
+	Timeout      time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	MaxBackoff   time.Duration
+	DisableAfter int
+	// AllowPrivate lets endpoints on loopback and private addresses be registered and reached.
+	AllowPrivate bool
+}
+
+// WebhookService manages partner endpoints and delivers events to them. It is an outbox
+// sink: every dispatched event becomes a delivery for each subscribed endpoint, and the
+// deliveries are sent and retried on their own so a slow partner does not hold back the others.
+type WebhookService struct {
+	repo   *repository.Repository
+	sender *webhook.Sender
+	opts   WebhookOptions
+	lease  time.Duration
+	logger *zap.Logger
+}
+
+func NewWebhookService(repo *repository.Repository, opts WebhookOptions, logger *zap.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		sender: webhook.NewSender(opts.Timeout, opts.AllowPrivate),
+		opts:   opts,
+		// a claimed batch must be sent before other instances may take it again
+		lease:  opts.Timeout*time.Duration(opts.BatchSize) + time.Minute,
+		logger: logger,
+	}
+}
+
+// CreateEndpoint registers an endpoint, a secret is generated if none is given. The URL must
+// not resolve to a private or reserved address.
+func (s *WebhookService) CreateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateEndpoint")
+	defer span.End()
+
+	if err := s.checkURL(ctx, endpoint.URL); err != nil {
+		return entity.WebhookEndpoint{}, err
+	}
+
+	if endpoint.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			return entity.WebhookEndpoint{}, err
+		}
+		endpoint.Secret = secret
+	}
+	if endpoint.Events == nil {
+		endpoint.Events = []string{}
+	}
+
+	id, err := s.repo.Webhook.CreateEndpoint(ctx, endpoint)
+	if err != nil {
+		return entity.WebhookEndpoint{}, err
+	}
+	return s.repo.Webhook.GetEndpointById(ctx, id)
+}
+func (s *WebhookService) checkURL(ctx context.Context, rawURL string) error {
+	err := s.sender.CheckURL(ctx, rawURL)
+	if errors.Is(err, webhook.ErrAddressNotAllowed) {
+		return fmt.Errorf("%w: %w", ErrWebhookURLNotAllowed, err)
+	}
+	return err
+}
+func (s *WebhookService) GetEndpoints(ctx context.Context) ([]entity.WebhookEndpoint, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetEndpoints")
+	defer span.End()
+
+	return s.repo.Webhook.GetEndpoints(ctx)
+}
+func (s *WebhookService) GetEndpointById(ctx context.Context, id int) (entity.WebhookEndpoint, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetEndpointById")
+	defer span.End()
+
+	return s.repo.Webhook.GetEndpointById(ctx, id)
+}
+
+// UpdateEndpoint changes url, events and enabled; enabling a disabled endpoint starts its
+// failure count over and sends the deliveries that were waiting.
+func (s *WebhookService) UpdateEndpoint(ctx context.Context, endpoint entity.WebhookEndpoint) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.UpdateEndpoint")
+	defer span.End()
+
+	if err := s.checkURL(ctx, endpoint.URL); err != nil {
+		return err
+	}
+
+	if endpoint.Events == nil {
+		endpoint.Events = []string{}
+	}
+	return s.repo.Webhook.UpdateEndpoint(ctx, endpoint)
+}
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteEndpoint")
+	defer span.End()
+
+	return s.repo.Webhook.DeleteEndpoint(ctx, id)
+}
+
+func (s *WebhookService) GetDeliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetDeliveries")
+	defer span.End()
+
+	if _, err := s.repo.Webhook.GetEndpointById(ctx, filter.EndpointId); err != nil {
+		return nil, err
+	}
+	return s.repo.Webhook.GetDeliveries(ctx, filter)
+}
+
+// Redeliver sends a delivery again as a new delivery, whatever the outcome of the original.
+func (s *WebhookService) Redeliver(ctx context.Context, endpointId int, deliveryId int64) (int64, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	endpoint, err := s.repo.Webhook.GetEndpointById(ctx, endpointId)
+	if err != nil {
+		return 0, err
+	}
+	if !endpoint.Enabled {
+		return 0, ErrWebhookEndpointDisabled
+	}
+	return s.repo.Webhook.Redeliver(ctx, endpointId, deliveryId, time.Now())
+}
+
+// Deliver sends one batch of due deliveries and returns the number of claimed ones.
+func (s *WebhookService) Deliver(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Deliver")
+	defer span.End()
+
+	logger := reqctx.Logger(ctx, s.logger)
+
+	deliveries, err := s.repo.Webhook.Claim(ctx, now, now.Add(s.lease), s.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range deliveries {
+		resp, sendErr := s.sender.Send(ctx, webhook.Request{
+			URL:        d.URL,
+			Secret:     d.Secret,
+			DeliveryId: d.Id,
+			EventType:  d.EventType,
+			Body:       d.Payload,
+		})
+
+		at := time.Now()
+		attempt := entity.WebhookAttempt{
+			DeliveryId:    d.Id,
+			EndpointId:    d.EndpointId,
+			Status:        entity.WebhookDeliveryDelivered,
+			ResponseBody:  resp.Body,
+			NextAttemptAt: at,
+			At:            at,
+		}
+		if resp.StatusCode != 0 {
+			attempt.ResponseCode = &resp.StatusCode
+		}
+
+		if sendErr != nil {
+			attempt.Error = sendErr.Error()
+			attempt.Status = entity.WebhookDeliveryFailed
+			if d.Attempts < s.opts.MaxAttempts {
+				attempt.Status = entity.WebhookDeliveryPending
+				attempt.NextAttemptAt = at.Add(s.backoff(d.Attempts))
+			}
+
+			logger.Warn("webhook delivery failed",
+				zap.Int64("delivery_id", d.Id),
+				zap.Int("endpoint_id", d.EndpointId),
+				zap.Int("attempts", d.Attempts),
+				zap.String("status", attempt.Status),
+				zap.Error(sendErr),
+			)
+		}
+
+		disabled, err := s.repo.Webhook.RecordAttempt(ctx, attempt, s.opts.DisableAfter)
+		if err != nil {
+			return 0, err
+		}
+		if disabled {
+			logger.Warn("webhook endpoint disabled after repeated failures",
+				zap.Int("endpoint_id", d.EndpointId),
+				zap.Int("failures", s.opts.DisableAfter),
+			)
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < s.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.opts.MaxBackoff)
+}
+
+func (s *WebhookService) Name() string {
+	return "webhooks"
+}
+
+// Publish queues the event for the subscribed endpoints, the outbox retries it if that fails.
+func (s *WebhookService) Publish(ctx context.Context, event entity.OutboxEvent) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.Publish")
+	defer span.End()
+
+	payload, err := outbox.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.Webhook.Enqueue(ctx, event, payload, time.Now())
+	return err
+}
+
+func (s *WebhookService) Close() error {
+	return nil
+}
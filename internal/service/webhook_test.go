@@ -0,0 +1,200 @@
+package service
+
+import (
+	"BookStore_API/internal/entity"
+	"BookStore_API/internal/repository"
+	"context"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeWebhookRepository keeps one delivery of one endpoint in memory. It counts failures in a
+// row and disables the endpoint like the database does.
+type fakeWebhookRepository struct {
+	repository.Webhook
+
+	delivery entity.WebhookDelivery
+	enabled  bool
+	failures int
+	attempts []entity.WebhookAttempt
+	// disableAfter is the value the service passed with the last attempt
+	disableAfter int
+}
+
+func (r *fakeWebhookRepository) Claim(_ context.Context, now, _ time.Time, _ int) ([]entity.WebhookDelivery, error) {
+	if !r.enabled || r.delivery.Status != entity.WebhookDeliveryPending || r.delivery.NextAttemptAt.After(now) {
+		return nil, nil
+	}
+	r.delivery.Attempts++
+	return []entity.WebhookDelivery{r.delivery}, nil
+}
+
+func (r *fakeWebhookRepository) RecordAttempt(_ context.Context, attempt entity.WebhookAttempt, disableAfter int) (bool, error) {
+	r.attempts = append(r.attempts, attempt)
+	r.disableAfter = disableAfter
+	r.delivery.Status = attempt.Status
+	r.delivery.NextAttemptAt = attempt.NextAttemptAt
+
+	if attempt.Status == entity.WebhookDeliveryDelivered {
+		r.failures = 0
+		return false, nil
+	}
+	r.failures++
+	disabled := r.enabled && r.failures >= disableAfter
+	r.enabled = r.enabled && !disabled
+	return disabled, nil
+}
+
+func newTestWebhookService(t *testing.T, handler http.HandlerFunc, opts WebhookOptions) (*WebhookService, *fakeWebhookRepository) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	fake := &fakeWebhookRepository{
+		delivery: entity.WebhookDelivery{
+			Id:         1,
+			EndpointId: 1,
+			EventType:  entity.EventOrderCreated,
+			Payload:    []byte(`{}`),
+			Status:     entity.WebhookDeliveryPending,
+			URL:        server.URL,
+			Secret:     "whsec_test_secret",
+		},
+		enabled: true,
+	}
+
+	// the test server listens on loopback
+	opts.Timeout = time.Second
+	opts.BatchSize = 1
+	opts.AllowPrivate = true
+	return NewWebhookService(&repository.Repository{Webhook: fake}, opts, zap.NewNop()), fake
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	s := &WebhookService{opts: WebhookOptions{MaxBackoff: time.Minute}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		{attempts: 20, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	s, fake := newTestWebhookService(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("thanks"))
+	}, WebhookOptions{MaxAttempts: 3, MaxBackoff: time.Hour, DisableAfter: 5})
+
+	n, err := s.Deliver(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 || len(fake.attempts) != 1 {
+		t.Fatalf("claimed %d, recorded %d attempts, want 1", n, len(fake.attempts))
+	}
+
+	attempt := fake.attempts[0]
+	if attempt.Status != entity.WebhookDeliveryDelivered {
+		t.Fatalf("status = %q, want %q", attempt.Status, entity.WebhookDeliveryDelivered)
+	}
+	if attempt.ResponseCode == nil || *attempt.ResponseCode != http.StatusOK || attempt.ResponseBody != "thanks" {
+		t.Fatalf("response = %v %q, want 200 thanks", attempt.ResponseCode, attempt.ResponseBody)
+	}
+}
+
+func TestWebhookDeliverRetries(t *testing.T) {
+	s, fake := newTestWebhookService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WebhookOptions{MaxAttempts: 3, MaxBackoff: time.Hour, DisableAfter: 10})
+
+	// every attempt is made once the previous one is due
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := s.Deliver(context.Background(), now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		now = fake.delivery.NextAttemptAt
+	}
+
+	if len(fake.attempts) != 3 {
+		t.Fatalf("recorded %d attempts, want 3", len(fake.attempts))
+	}
+	for i, attempt := range fake.attempts[:2] {
+		if attempt.Status != entity.WebhookDeliveryPending {
+			t.Fatalf("attempt %d: status = %q, want %q", i+1, attempt.Status, entity.WebhookDeliveryPending)
+		}
+		if delay := attempt.NextAttemptAt.Sub(attempt.At); delay != s.backoff(i+1) {
+			t.Fatalf("attempt %d: retried after %v, want %v", i+1, delay, s.backoff(i+1))
+		}
+	}
+	last := fake.attempts[2]
+	if last.Status != entity.WebhookDeliveryFailed {
+		t.Fatalf("last attempt: status = %q, want %q", last.Status, entity.WebhookDeliveryFailed)
+	}
+	if last.Error == "" || last.ResponseCode == nil || *last.ResponseCode != http.StatusServiceUnavailable {
+		t.Fatalf("last attempt: error %q, code %v, want the rejection", last.Error, last.ResponseCode)
+	}
+}
+
+func TestWebhookDisableAfterFailures(t *testing.T) {
+	failing := true
+	s, fake := newTestWebhookService(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}, WebhookOptions{MaxAttempts: 100, MaxBackoff: time.Hour, DisableAfter: 3})
+
+	// deliver ignores the backoff so every call makes an attempt
+	deliver := func() {
+		t.Helper()
+		fake.delivery.NextAttemptAt = time.Time{}
+		if _, err := s.Deliver(context.Background(), time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// a success starts the count over
+	deliver()
+	deliver()
+	failing = false
+	deliver()
+	if !fake.enabled || fake.failures != 0 {
+		t.Fatalf("enabled %v with %d failures, want enabled with none", fake.enabled, fake.failures)
+	}
+
+	fake.delivery.Status = entity.WebhookDeliveryPending
+	failing = true
+	deliver()
+	deliver()
+	if !fake.enabled {
+		t.Fatal("endpoint disabled before reaching the limit")
+	}
+	deliver()
+	if fake.enabled {
+		t.Fatal("endpoint still enabled after 3 failures in a row")
+	}
+	if fake.disableAfter != 3 {
+		t.Fatalf("disableAfter = %d, want 3", fake.disableAfter)
+	}
+
+	// a disabled endpoint gets no more attempts
+	attempts := len(fake.attempts)
+	deliver()
+	if len(fake.attempts) != attempts {
+		t.Fatal("a disabled endpoint was sent a delivery")
+	}
+}
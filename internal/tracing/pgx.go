@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// tablePattern finds the table a statement works on, the first one after FROM, INTO or UPDATE.
+var tablePattern = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+([a-z_][a-z0-9_]*)`)
+
+type querySpanKey struct{}
+
+// QueryTracer opens a span for every SQL statement of a traced request, named after the
+// operation and the table like "UPDATE products", with the statement text as an attribute.
+type QueryTracer struct {
+	names sync.Map
+}
+
+func NewQueryTracer() *QueryTracer {
+	return &QueryTracer{}
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	operation, table := t.statementName(data.SQL)
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+
+	ctx, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(querySpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.response.returned_rows", data.CommandTag.RowsAffected()))
+}
+
+// statementName returns the operation and the table of the statement, cached as the
+// statements are the constants of the postgres package.
+func (t *QueryTracer) statementName(sql string) (string, string) {
+	if name, ok := t.names.Load(sql); ok {
+		parts := name.([2]string)
+		return parts[0], parts[1]
+	}
+
+	var operation string
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	var table string
+	if m := tablePattern.FindStringSubmatch(sql); m != nil {
+		table = strings.ToLower(m[1])
+	}
+
+	t.names.Store(sql, [2]string{operation, table})
+	return operation, table
+}
@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers sent with every delivery. The signature is the hex encoded HMAC-SHA256 of
+// "<timestamp>.<body>" with the endpoint secret, so a captured request can not be replayed later.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventHeader     = "X-Webhook-Event"
+)
+
+// maxResponseBody is the part of an accepting answer kept in the delivery log.
+const maxResponseBody = 1024
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrAddressNotAllowed is returned for endpoints in private, loopback, link-local or
+	// otherwise reserved networks, so webhooks can not be used to reach internal services.
+	ErrAddressNotAllowed = errors.New("webhook address is not allowed")
+)
+
+// reservedPrefixes are the networks not covered by the checks of netip.Addr that must not be
+// reached either: "this" network, shared address space, IETF protocol assignments, benchmarking
+// and reserved.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+type Request struct {
+	URL        string
+	Secret     string
+	DeliveryId int64
+	EventType  string
+	Body       []byte
+}
+
+// Response is the answer of the receiver, StatusCode is 0 if none was received.
+type Response struct {
+	StatusCode int
+	Body       string
+}
+
+type Sender struct {
+	client *http.Client
+	// allowPrivate lets endpoints in private networks be reached, for local development
+	allowPrivate bool
+}
+
+// NewSender returns a sender that does not follow redirects and refuses to connect to
+// private and reserved addresses unless allowPrivate is set. The address is checked when
+// connecting, so a host name can not be pointed at an internal address after registration.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	s := &Sender{allowPrivate: allowPrivate}
+
+	dialer := &net.Dialer{Timeout: timeout, Control: s.checkDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	s.client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// a redirect is answered like any other non-2xx status
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return s
+}
+
+// CheckURL resolves the host of an endpoint URL and fails with ErrAddressNotAllowed if any
+// of its addresses may not be reached.
+func (s *Sender) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("%w: invalid url", ErrAddressNotAllowed)
+	}
+	if s.allowPrivate {
+		return nil
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkAddr(addr)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: can not resolve %s", ErrAddressNotAllowed, host)
+	}
+	for _, addr := range addrs {
+		if err = checkAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDial is the Control of the dialer, it runs with the resolved address of every connection.
+func (s *Sender) checkDial(_, address string, _ syscall.RawConn) error {
+	if s.allowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrAddressNotAllowed, address)
+	}
+	return checkAddr(addrPort.Addr())
+}
+
+func checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return fmt.Errorf("%w: %s", ErrAddressNotAllowed, addr)
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrAddressNotAllowed, addr)
+		}
+	}
+	return nil
+}
+
+// Send posts a signed delivery. Only a 2xx answer is a success, any other answer
+// is returned together with an error.
+func (s *Sender) Send(ctx context.Context, req Request) (Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return Response{}, err
+	}
+
+	timestamp := time.Now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(DeliveryHeader, strconv.FormatInt(req.DeliveryId, 10))
+	httpReq.Header.Set(EventHeader, req.EventType)
+	httpReq.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(SignatureHeader, Sign(req.Secret, timestamp, req.Body))
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	// the body of a rejection is not kept, so the delivery log does not echo what an
+	// unexpected receiver answers
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return Response{StatusCode: resp.StatusCode}, fmt.Errorf("delivery rejected with status %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return Response{StatusCode: resp.StatusCode, Body: string(body)}, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery in constant time, receivers written in Go can use it.
+func Verify(secret string, timestamp int64, body []byte, signature string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	actual, _ := hex.DecodeString(Sign(secret, timestamp, body))
+	if !hmac.Equal(actual, expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// NewSecret returns a random secret for an endpoint registered without one.
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	const secret = "whsec_test_secret"
+	body := []byte(`{"id":1}`)
+	timestamp := int64(1700000000)
+	signature := Sign(secret, timestamp, body)
+
+	if err := Verify(secret, timestamp, body, signature); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		signature string
+	}{
+		{name: "other secret", secret: "whsec_other", timestamp: timestamp, body: body, signature: signature},
+		{name: "other timestamp", secret: secret, timestamp: timestamp + 1, body: body, signature: signature},
+		{name: "changed body", secret: secret, timestamp: timestamp, body: []byte(`{"id":2}`), signature: signature},
+		{name: "not hex", secret: secret, timestamp: timestamp, body: body, signature: "not-a-signature"},
+		{name: "empty", secret: secret, timestamp: timestamp, body: body, signature: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.body, tt.signature)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	const secret = "whsec_test_secret"
+	body := []byte(`{"type":"OrderCreated"}`)
+
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	sender := NewSender(time.Second, true)
+	resp, err := sender.Send(context.Background(), Request{
+		URL:        server.URL,
+		Secret:     secret,
+		DeliveryId: 7,
+		EventType:  "OrderCreated",
+		Body:       body,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body != "ok" {
+		t.Fatalf("response = %+v, want 200 ok", resp)
+	}
+
+	if got := received.Header.Get(DeliveryHeader); got != "7" {
+		t.Errorf("%s = %q, want 7", DeliveryHeader, got)
+	}
+	if got := received.Header.Get(EventHeader); got != "OrderCreated" {
+		t.Errorf("%s = %q, want OrderCreated", EventHeader, got)
+	}
+	timestamp, err := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", TimestampHeader, err)
+	}
+	if err = Verify(secret, timestamp, receivedBody, received.Header.Get(SignatureHeader)); err != nil {
+		t.Fatalf("signature of the delivery rejected: %v", err)
+	}
+}
+
+func TestSendRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte("internal details"))
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "redirect is not followed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			},
+			status: http.StatusFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			resp, err := NewSender(time.Second, true).Send(context.Background(), Request{URL: server.URL, Body: []byte(`{}`)})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			// the answer of a rejection is not kept
+			if resp.Body != "" {
+				t.Fatalf("body = %q, want none", resp.Body)
+			}
+		})
+	}
+}
+
+func TestSendPrivateAddress(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// the test server listens on loopback, which is refused when connecting
+	_, err := NewSender(time.Second, false).Send(context.Background(), Request{URL: server.URL, Body: []byte(`{}`)})
+	if !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("error = %v, want %v", err, ErrAddressNotAllowed)
+	}
+	if called {
+		t.Fatal("request reached the server")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://93.184.216.34/hooks", allowed: true},
+		{url: "https://[2606:2800:220:1:248:1893:25c8:1946]/hooks", allowed: true},
+		{url: "http://127.0.0.1:8080/hooks"},
+		{url: "http://[::1]/hooks"},
+		{url: "http://10.0.0.5/hooks"},
+		{url: "http://172.16.0.1/hooks"},
+		{url: "http://192.168.1.1/hooks"},
+		{url: "http://169.254.169.254/latest/meta-data/"},
+		{url: "http://[fd00:ec2::254]/latest/meta-data/"},
+		{url: "http://[::ffff:127.0.0.1]/hooks"},
+		{url: "http://0.0.0.0/hooks"},
+		{url: "http://100.64.0.1/hooks"},
+		{url: "http://[fe80::1]/hooks"},
+		{url: "not a url"},
+	}
+
+	sender := NewSender(time.Second, false)
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := sender.CheckURL(context.Background(), tt.url)
+			if tt.allowed && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrAddressNotAllowed) {
+				t.Fatalf("error = %v, want %v", err, ErrAddressNotAllowed)
+			}
+		})
+	}
+}
+
+func TestCheckAddr(t *testing.T) {
+	for _, addr := range []string{"8.8.8.8", "1.1.1.1", "2001:4860:4860::8888"} {
+		if err := checkAddr(netip.MustParseAddr(addr)); err != nil {
+			t.Errorf("%s: unexpected error: %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"224.0.0.1", "ff02::1", "198.18.0.1", "255.255.255.255", "::"} {
+		if err := checkAddr(netip.MustParseAddr(addr)); !errors.Is(err, ErrAddressNotAllowed) {
+			t.Errorf("%s: error = %v, want %v", addr, err, ErrAddressNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	listenerMinBackoff = time.Second
+	listenerMaxBackoff = 30 * time.Second
+)
+
+// EventListener feeds the event streams with new events announced by Postgres,
+// reconnecting whenever the listening connection fails.
+type EventListener struct {
+	stream service.Stream
+	logger *zap.Logger
+}
+
+func NewEventListener(stream service.Stream, logger *zap.Logger) *EventListener {
+	return &EventListener{
+		stream: stream,
+		logger: logger,
+	}
+}
+
+func (w *EventListener) Name() string {
+	return "event_listener"
+}
+
+func (w *EventListener) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()))
+
+	backoff := listenerMinBackoff
+	for {
+		connected := time.Now()
+		err := w.stream.Listen(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+
+		// a connection that lasted a while starts the backoff over
+		if time.Since(connected) > listenerMaxBackoff {
+			backoff = listenerMinBackoff
+		}
+		w.logger.Error("event listener disconnected",
+			zap.String("worker", w.Name()),
+			zap.Duration("retry_in", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, listenerMaxBackoff)
+	}
+
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
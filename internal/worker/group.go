@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"sync"
+)
+
+type running struct {
+	worker Worker
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Group runs workers in the background and stops them one by one in the order they were started.
+type Group struct {
+	logger *zap.Logger
+	mu     sync.Mutex
+	list   []running
+}
+
+func NewGroup(logger *zap.Logger) *Group {
+	return &Group{logger: logger}
+}
+
+// Start runs every worker in its own goroutine with a context of its own.
+func (g *Group) Start(workers ...Worker) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, w := range workers {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := running{worker: w, cancel: cancel, done: make(chan struct{})}
+
+		go func() {
+			defer close(r.done)
+			w.Run(ctx)
+		}()
+
+		g.list = append(g.list, r)
+	}
+}
+
+func (g *Group) Names() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	names := make([]string, len(g.list))
+	for i, r := range g.list {
+		names[i] = r.worker.Name()
+	}
+	return names
+}
+
+// Stopped returns the names of the started workers that returned on their own.
+func (g *Group) Stopped() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	names := make([]string, 0)
+	for _, r := range g.list {
+		select {
+		case <-r.done:
+			names = append(names, r.worker.Name())
+		default:
+		}
+	}
+	return names
+}
+
+// Stop cancels the workers in order, waiting for each one to return before stopping the next,
+// and gives up on the remaining ones once ctx is done.
+func (g *Group) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	list := g.list
+	g.list = nil
+	g.mu.Unlock()
+
+	for i, r := range list {
+		r.cancel()
+
+		select {
+		case <-r.done:
+			g.logger.Debug("Worker finished", zap.String("worker", r.worker.Name()))
+		case <-ctx.Done():
+			for _, rest := range list[i+1:] {
+				rest.cancel()
+			}
+			g.logger.Warn("Workers did not stop in time", zap.String("worker", r.worker.Name()))
+			return ctx.Err()
+		}
+	}
+	return nil
+}
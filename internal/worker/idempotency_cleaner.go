@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// IdempotencyCleaner periodically deletes expired idempotency keys and their stored responses.
+type IdempotencyCleaner struct {
+	keys     service.Idempotency
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewIdempotencyCleaner(keys service.Idempotency, interval time.Duration, logger *zap.Logger) *IdempotencyCleaner {
+	return &IdempotencyCleaner{
+		keys:     keys,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *IdempotencyCleaner) Name() string {
+	return "idempotency_cleaner"
+}
+
+func (w *IdempotencyCleaner) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()), zap.Duration("interval", w.interval))
+	runEvery(ctx, w.interval, w.purge)
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
+
+func (w *IdempotencyCleaner) purge(ctx context.Context) {
+	deleted, err := w.keys.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		w.logger.Error("failed to delete expired idempotency keys",
+			zap.String("worker", w.Name()),
+			zap.Error(err),
+		)
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("Expired idempotency keys deleted",
+			zap.String("worker", w.Name()),
+			zap.Int("deleted", deleted),
+		)
+	}
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// OutboxDispatcher delivers the domain events stored in the outbox to the configured sinks.
+type OutboxDispatcher struct {
+	outbox   service.Outbox
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewOutboxDispatcher(outbox service.Outbox, interval time.Duration, logger *zap.Logger) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		outbox:   outbox,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *OutboxDispatcher) Name() string {
+	return "outbox_dispatcher"
+}
+
+func (w *OutboxDispatcher) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()), zap.Duration("interval", w.interval))
+	runEvery(ctx, w.interval, w.dispatch)
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
+
+// dispatch runs batches until nothing is due, a batch takes only the oldest pending
+// event of every aggregate so the next one becomes due once it is delivered.
+func (w *OutboxDispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := w.outbox.Dispatch(ctx, time.Now())
+		if err != nil {
+			w.logger.Error("failed to dispatch outbox events",
+				zap.String("worker", w.Name()),
+				zap.Error(err),
+			)
+			return
+		}
+		if claimed == 0 {
+			return
+		}
+	}
+}
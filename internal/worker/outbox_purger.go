@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// OutboxPurger periodically deletes delivered outbox events older than the retention period.
+type OutboxPurger struct {
+	outbox   service.Outbox
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewOutboxPurger(outbox service.Outbox, interval time.Duration, logger *zap.Logger) *OutboxPurger {
+	return &OutboxPurger{
+		outbox:   outbox,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *OutboxPurger) Name() string {
+	return "outbox_purger"
+}
+
+func (w *OutboxPurger) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()), zap.Duration("interval", w.interval))
+	runEvery(ctx, w.interval, w.purge)
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
+
+func (w *OutboxPurger) purge(ctx context.Context) {
+	deleted, err := w.outbox.PurgeDispatched(ctx, time.Now())
+	if err != nil {
+		w.logger.Error("failed to purge outbox",
+			zap.String("worker", w.Name()),
+			zap.Error(err),
+		)
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("Outbox purged",
+			zap.String("worker", w.Name()),
+			zap.Int("deleted", deleted),
+		)
+	}
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PriceScheduler periodically applies scheduled prices whose start or end time has come.
+type PriceScheduler struct {
+	prices   service.Price
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewPriceScheduler(prices service.Price, interval time.Duration, logger *zap.Logger) *PriceScheduler {
+	return &PriceScheduler{
+		prices:   prices,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *PriceScheduler) Name() string {
+	return "price_scheduler"
+}
+
+func (w *PriceScheduler) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()), zap.Duration("interval", w.interval))
+	runEvery(ctx, w.interval, w.applyDue)
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
+
+func (w *PriceScheduler) applyDue(ctx context.Context) {
+	applied, err := w.prices.ApplyDue(ctx, time.Now())
+	if err != nil {
+		w.logger.Error("failed to apply scheduled prices",
+			zap.String("worker", w.Name()),
+			zap.Error(err),
+		)
+		return
+	}
+	if applied > 0 {
+		w.logger.Info("Scheduled prices applied",
+			zap.String("worker", w.Name()),
+			zap.Int("applied", applied),
+		)
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"BookStore_API/internal/metrics"
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// StatsCollector refreshes the business metrics, which are too costly to query on every scrape.
+type StatsCollector struct {
+	stats    service.Stats
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewStatsCollector(stats service.Stats, interval time.Duration, logger *zap.Logger) *StatsCollector {
+	return &StatsCollector{
+		stats:    stats,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *StatsCollector) Name() string {
+	return "stats_collector"
+}
+
+func (w *StatsCollector) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()), zap.Duration("interval", w.interval))
+	runEvery(ctx, w.interval, w.collect)
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
+
+func (w *StatsCollector) collect(ctx context.Context) {
+	stats, err := w.stats.Get(ctx)
+	if err != nil {
+		w.logger.Error("failed to collect stats",
+			zap.String("worker", w.Name()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	metrics.SetOrdersByStatus(stats.OrdersByStatus)
+	metrics.SetLowStockProducts(stats.LowStockProducts)
+}
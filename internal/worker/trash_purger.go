@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// TrashPurger periodically deletes products and orders whose trash retention period is over.
+type TrashPurger struct {
+	trash    service.Trash
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewTrashPurger(trash service.Trash, interval time.Duration, logger *zap.Logger) *TrashPurger {
+	return &TrashPurger{
+		trash:    trash,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *TrashPurger) Name() string {
+	return "trash_purger"
+}
+
+func (w *TrashPurger) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()), zap.Duration("interval", w.interval))
+	runEvery(ctx, w.interval, w.purge)
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
+
+func (w *TrashPurger) purge(ctx context.Context) {
+	deleted, err := w.trash.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		w.logger.Error("failed to purge trash",
+			zap.String("worker", w.Name()),
+			zap.Error(err),
+		)
+		return
+	}
+	if deleted > 0 {
+		w.logger.Info("Trash purged",
+			zap.String("worker", w.Name()),
+			zap.Int("deleted", deleted),
+		)
+	}
+}
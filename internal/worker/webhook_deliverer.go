@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"BookStore_API/internal/service"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// WebhookDeliverer sends pending webhook deliveries to partner endpoints.
+type WebhookDeliverer struct {
+	webhooks service.Webhook
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewWebhookDeliverer(webhooks service.Webhook, interval time.Duration, logger *zap.Logger) *WebhookDeliverer {
+	return &WebhookDeliverer{
+		webhooks: webhooks,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *WebhookDeliverer) Name() string {
+	return "webhook_deliverer"
+}
+
+func (w *WebhookDeliverer) Run(ctx context.Context) {
+	w.logger.Info("Starting worker...", zap.String("worker", w.Name()), zap.Duration("interval", w.interval))
+	runEvery(ctx, w.interval, w.deliver)
+	w.logger.Info("Worker stopped", zap.String("worker", w.Name()))
+}
+
+// deliver runs batches until nothing is due, failed deliveries wait for their next attempt.
+func (w *WebhookDeliverer) deliver(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := w.webhooks.Deliver(ctx, time.Now())
+		if err != nil {
+			w.logger.Error("failed to deliver webhooks",
+				zap.String("worker", w.Name()),
+				zap.Error(err),
+			)
+			return
+		}
+		if claimed == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+// Worker is a long-running background job started together with the server.
+type Worker interface {
+	Name() string
+	Run(ctx context.Context)
+}
+
+// runEvery calls fn right away and then on every tick until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fn(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package zaplog
+
+import "go.uber.org/zap/zapcore"
+
+// core redacts sensitive fields and applies the package levels before handing entries
+// to the encoding core.
+type core struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func newCore(inner zapcore.Core, levels *Levels) zapcore.Core {
+	return &core{Core: inner, levels: levels}
+}
+
+func (c *core) Enabled(level zapcore.Level) bool {
+	return level >= c.levels.lowest()
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(redactFields(fields)), levels: c.levels}
+}
+
+// Check accepts every entry some package may log, the caller it is filtered by is only
+// known once the entry is written.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.levels.Enabled(ent.Caller, ent.Level) {
+		return nil
+	}
+	return c.Core.Write(ent, redactFields(fields))
+}
@@ -0,0 +1,102 @@
+package zaplog
+
+import (
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Levels is the minimum log level of every package, packages are named after their
+// directory (handler, service, repository, ...) and default to the base level. Levels
+// can be changed while logging.
+type Levels struct {
+	set atomic.Pointer[levelSet]
+	// envDefault is the base level when none is given, set by InitLogger
+	envDefault zapcore.Level
+}
+
+type levelSet struct {
+	base     zapcore.Level
+	packages map[string]zapcore.Level
+	// lowest is the lowest of all levels, entries below it are dropped right away
+	lowest zapcore.Level
+}
+
+// levels are those of the logger built by InitLogger.
+var levels Levels
+
+// SetLevels changes the levels of the application logger, level is the base level, the
+// default of the environment if empty, and packages are given as "repository=debug,handler=warn".
+func SetLevels(level, packages string) error {
+	return levels.Set(level, packages)
+}
+
+// Set parses and applies the levels, they are left unchanged on error.
+func (l *Levels) Set(level, packages string) error {
+	base := l.envDefault
+	if level != "" {
+		parsed, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("invalid log level: %w", err)
+		}
+		base = parsed
+	}
+
+	set, err := parseLevelSet(base, packages)
+	if err != nil {
+		return err
+	}
+	l.set.Store(set)
+	return nil
+}
+
+func parseLevelSet(base zapcore.Level, packages string) (*levelSet, error) {
+	set := &levelSet{
+		base:     base,
+		packages: make(map[string]zapcore.Level),
+		lowest:   base,
+	}
+
+	for _, pair := range strings.Split(packages, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		pkg, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid package level %q, expected package=level", pair)
+		}
+		level, err := zapcore.ParseLevel(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level of package %q: %w", pkg, err)
+		}
+
+		set.packages[strings.TrimSpace(pkg)] = level
+		set.lowest = min(set.lowest, level)
+	}
+	return set, nil
+}
+
+// Enabled tells whether an entry logged from the given caller is written.
+func (l *Levels) Enabled(caller zapcore.EntryCaller, level zapcore.Level) bool {
+	set := l.set.Load()
+	if len(set.packages) > 0 && caller.Defined {
+		if pkgLevel, ok := set.packages[filepath.Base(filepath.Dir(caller.File))]; ok {
+			return level >= pkgLevel
+		}
+	}
+	return level >= set.base
+}
+
+func (l *Levels) lowest() zapcore.Level {
+	return l.set.Load().lowest
+}
+
+// CheckLevels tells whether SetLevels would accept the levels, without changing any.
+func CheckLevels(level, packages string) error {
+	var l Levels
+	return l.Set(level, packages)
+}
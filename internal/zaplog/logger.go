@@ -4,9 +4,13 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"log"
+	"time"
 )
 
-func InitLogger(appEnv string, logFormat string) *zap.Logger {
+// InitLogger builds the application logger. level overrides the default level of the
+// environment and packageLevels sets the level of single packages, as "repository=debug";
+// both can be changed later with SetLevels.
+func InitLogger(appEnv, logFormat, level, packageLevels string) *zap.Logger {
 	var cfg zap.Config
 
 	if appEnv == "production" && logFormat != "console" {
@@ -30,7 +34,24 @@ func InitLogger(appEnv string, logFormat string) *zap.Logger {
 		"app_env": appEnv,
 	}
 
-	logger, err := cfg.Build()
+	// the package levels are applied by the wrapping core, cfg.Level is not consulted
+	levels.envDefault = cfg.Level.Level()
+	if err := levels.Set(level, packageLevels); err != nil {
+		// the config validation reports invalid levels, until then the defaults are used
+		_ = levels.Set("", "")
+	}
+
+	// sampling goes outside of the wrapping core, so dropped entries are not counted
+	sampling := cfg.Sampling
+	cfg.Sampling = nil
+
+	logger, err := cfg.Build(zap.WrapCore(func(inner zapcore.Core) zapcore.Core {
+		c := newCore(inner, &levels)
+		if sampling != nil {
+			c = zapcore.NewSamplerWithOptions(c, time.Second, sampling.Initial, sampling.Thereafter)
+		}
+		return c
+	}))
 	if err != nil {
 		log.Fatal("Failed to initialize logger: ", err)
 	}
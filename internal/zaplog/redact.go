@@ -0,0 +1,207 @@
+package zaplog
+
+import (
+	"encoding"
+	"encoding/json"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the values of sensitive fields.
+const Redacted = "[REDACTED]"
+
+// redactTag marks a struct field as sensitive: `log:"redact"`.
+const redactTag = "redact"
+
+var (
+	keysMu sync.RWMutex
+	// sensitiveKeys are matched against field keys and struct field names, lowercased and
+	// without separators, as a whole or as a suffix, so "db_password" and "WebhookSecret" match.
+	sensitiveKeys = []string{"password", "secret", "token", "authorization", "signature", "apikey", "email", "phone", "street"}
+
+	// redactedTypes caches whether values of a type hold anything sensitive.
+	redactedTypes sync.Map
+)
+
+// RegisterSensitiveKeys adds keys whose values are never logged.
+func RegisterSensitiveKeys(keys ...string) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	for _, key := range keys {
+		sensitiveKeys = append(sensitiveKeys, normalizeKey(key))
+	}
+	redactedTypes.Clear()
+}
+
+func isSensitiveKey(key string) bool {
+	key = normalizeKey(key)
+
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	for _, sensitive := range sensitiveKeys {
+		if strings.HasSuffix(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
+
+// redactFields masks the fields with a sensitive key and the sensitive parts of logged values.
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, ok := redactField(f)
+		if !ok {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func redactField(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.SkipType || f.Type == zapcore.ErrorType {
+		return f, false
+	}
+	if isSensitiveKey(f.Key) {
+		return zap.String(f.Key, Redacted), true
+	}
+	if f.Type == zapcore.ReflectType && f.Interface != nil && holdsSensitive(reflect.TypeOf(f.Interface)) {
+		return zap.Any(f.Key, redactValue(reflect.ValueOf(f.Interface))), true
+	}
+	return f, false
+}
+
+// holdsSensitive reports whether values of t have a field that is tagged or named as sensitive.
+func holdsSensitive(t reflect.Type) bool {
+	if cached, ok := redactedTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	// a recursive type is assumed clean while it is being checked
+	redactedTypes.Store(t, false)
+
+	var sensitive bool
+	switch {
+	case isOpaque(t):
+	case t.Kind() == reflect.Pointer, t.Kind() == reflect.Slice, t.Kind() == reflect.Array, t.Kind() == reflect.Map:
+		sensitive = holdsSensitive(t.Elem())
+	case t.Kind() == reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("log") == redactTag || isSensitiveKey(field.Name) || holdsSensitive(field.Type) {
+				sensitive = true
+				break
+			}
+		}
+	}
+
+	redactedTypes.Store(t, sensitive)
+	return sensitive
+}
+
+// redactValue copies v with its sensitive fields masked, structs become maps keyed like
+// their JSON encoding.
+func redactValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	t := v.Type()
+	if !holdsSensitive(t) {
+		return v.Interface()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = redactValue(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			out[toString(key)] = redactValue(iter.Value())
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]any, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := jsonName(field)
+			if name == "-" {
+				continue
+			}
+			if field.Tag.Get("log") == redactTag || isSensitiveKey(field.Name) {
+				out[name] = Redacted
+				continue
+			}
+			out[name] = redactValue(v.Field(i))
+		}
+		return out
+	}
+	return v.Interface()
+}
+
+// isOpaque tells whether the type encodes itself, such as time.Time, its fields are not walked.
+func isOpaque(t reflect.Type) bool {
+	marshaler := reflect.TypeFor[json.Marshaler]()
+	text := reflect.TypeFor[encoding.TextMarshaler]()
+	return t.Implements(marshaler) || t.Implements(text) ||
+		reflect.PointerTo(t).Implements(marshaler) || reflect.PointerTo(t).Implements(text)
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func toString(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	if s, ok := v.Interface().(interface{ String() string }); ok {
+		return s.String()
+	}
+	b, _ := json.Marshal(v.Interface())
+	return string(b)
+}
@@ -0,0 +1,206 @@
+package zaplog
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "password", want: true},
+		{key: "db_password", want: true},
+		{key: "WebhookSecret", want: true},
+		{key: "X-Api-Key", want: true},
+		{key: "access.token", want: true},
+		{key: "Authorization", want: true},
+		{key: "customer_email", want: true},
+		{key: "id", want: false},
+		{key: "name", want: false},
+		// keys are matched as a suffix only
+		{key: "password_changed_at", want: false},
+		{key: "tokens", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := isSensitiveKey(tt.key); got != tt.want {
+				t.Fatalf("isSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+type redactAddress struct {
+	City   string `json:"city"`
+	Street string `json:"street"`
+}
+
+type redactUser struct {
+	Name     string          `json:"name"`
+	Password string          `json:"password"`
+	Note     string          `json:"note" log:"redact"`
+	Address  redactAddress   `json:"address"`
+	Previous []redactAddress `json:"previous"`
+	Ignored  string          `json:"-"`
+	internal string
+}
+
+type plainUser struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func TestRedactFields(t *testing.T) {
+	user := redactUser{
+		Name:     "Ada",
+		Password: "hunter2",
+		Note:     "private",
+		Address:  redactAddress{City: "London", Street: "Baker Street"},
+		Previous: []redactAddress{{City: "Paris", Street: "Rue de Rivoli"}},
+		Ignored:  "ignored",
+		internal: "internal",
+	}
+	plain := plainUser{Name: "Ada", CreatedAt: time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name  string
+		field zapcore.Field
+		want  any
+	}{
+		{name: "sensitive key", field: zap.String("db_password", "hunter2"), want: Redacted},
+		{name: "sensitive key of any type", field: zap.Int("pin_token", 1234), want: Redacted},
+		{name: "plain key", field: zap.String("route", "/books"), want: "/books"},
+		{
+			name:  "struct with sensitive fields",
+			field: zap.Any("user", user),
+			want: map[string]any{
+				"name":     "Ada",
+				"password": Redacted,
+				"note":     Redacted,
+				"address":  map[string]any{"city": "London", "street": Redacted},
+				"previous": []any{map[string]any{"city": "Paris", "street": Redacted}},
+			},
+		},
+		{
+			name:  "pointer to a struct",
+			field: zap.Any("user", &redactAddress{City: "London", Street: "Baker Street"}),
+			want:  map[string]any{"city": "London", "street": Redacted},
+		},
+		{name: "struct without sensitive fields", field: zap.Any("user", plain), want: plain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			zap.New(newCore(core, newTestLevels(t, "debug", ""))).Info("test", tt.field)
+
+			got := logs.All()[0].ContextMap()[tt.field.Key]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("%s = %#v, want %#v", tt.field.Key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactWith(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(newCore(core, newTestLevels(t, "debug", ""))).With(zap.String("api_key", "abc"))
+	logger.Info("test")
+
+	if got := logs.All()[0].ContextMap()["api_key"]; got != Redacted {
+		t.Fatalf("api_key = %v, want %s", got, Redacted)
+	}
+}
+
+func TestLevelsEnabled(t *testing.T) {
+	levels := newTestLevels(t, "info", "repository=debug, handler=warn")
+
+	tests := []struct {
+		name   string
+		caller zapcore.EntryCaller
+		level  zapcore.Level
+		want   bool
+	}{
+		{name: "package below base", caller: caller("/app/internal/repository/book.go"), level: zapcore.DebugLevel, want: true},
+		{name: "package above base", caller: caller("/app/internal/handler/book.go"), level: zapcore.InfoLevel, want: false},
+		{name: "package at its level", caller: caller("/app/internal/handler/book.go"), level: zapcore.WarnLevel, want: true},
+		{name: "other package at base", caller: caller("/app/internal/service/book.go"), level: zapcore.InfoLevel, want: true},
+		{name: "other package below base", caller: caller("/app/internal/service/book.go"), level: zapcore.DebugLevel, want: false},
+		// the package is the directory of the file, not a parent of it
+		{name: "nested directory", caller: caller("/app/internal/repository/sub/book.go"), level: zapcore.DebugLevel, want: false},
+		{name: "unknown caller", caller: zapcore.EntryCaller{}, level: zapcore.DebugLevel, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := levels.Enabled(tt.caller, tt.level); got != tt.want {
+				t.Fatalf("Enabled(%s, %s) = %v, want %v", tt.caller.File, tt.level, got, tt.want)
+			}
+		})
+	}
+
+	if got := levels.lowest(); got != zapcore.DebugLevel {
+		t.Fatalf("lowest = %s, want debug", got)
+	}
+}
+
+func TestLevelsSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    string
+		packages string
+		valid    bool
+	}{
+		{name: "base only", level: "warn", valid: true},
+		{name: "environment default", level: "", packages: "service=debug", valid: true},
+		{name: "blank pairs", level: "info", packages: " , repository=error,", valid: true},
+		{name: "invalid base", level: "loud"},
+		{name: "missing level", level: "info", packages: "repository"},
+		{name: "invalid package level", level: "info", packages: "repository=loud"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels := newTestLevels(t, "info", "handler=debug")
+			err := levels.Set(tt.level, tt.packages)
+			if tt.valid != (err == nil) {
+				t.Fatalf("error = %v, want valid %v", err, tt.valid)
+			}
+			// the levels are kept when the new ones are invalid
+			if err != nil && !levels.Enabled(caller("/app/internal/handler/book.go"), zapcore.DebugLevel) {
+				t.Fatal("levels changed by an invalid update")
+			}
+		})
+	}
+}
+
+func TestCorePackageLevels(t *testing.T) {
+	inner, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(newCore(inner, newTestLevels(t, "info", "zaplog=warn")), zap.AddCaller())
+
+	// this file is in the zaplog directory, so it logs at warn and above
+	logger.Info("dropped")
+	logger.Warn("written")
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].Message != "written" {
+		t.Fatalf("entries = %v, want only the warning", entries)
+	}
+}
+
+func newTestLevels(t *testing.T, level, packages string) *Levels {
+	t.Helper()
+
+	levels := &Levels{envDefault: zapcore.InfoLevel}
+	if err := levels.Set(level, packages); err != nil {
+		t.Fatalf("invalid levels: %v", err)
+	}
+	return levels
+}
+
+func caller(file string) zapcore.EntryCaller {
+	return zapcore.EntryCaller{Defined: true, File: file, Line: 1}
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS scheduled_prices;
+DROP TYPE IF EXISTS scheduled_price_status;
+DROP TABLE IF EXISTS price_history;
\ No newline at end of file
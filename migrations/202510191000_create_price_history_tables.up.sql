@@ -0,0 +1,39 @@
+CREATE TABLE price_history (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_product_price_history
+        FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_price_history_product_changed_at ON price_history (product_id, changed_at);
+
+INSERT INTO price_history (product_id, price, source, changed_at)
+SELECT id, price, 'initial', COALESCE(created_at, NOW())
+FROM products;
+
+CREATE TYPE scheduled_price_status AS ENUM (
+    'pending',
+    'active',
+    'completed',
+    'canceled'
+);
+
+CREATE TABLE scheduled_prices (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    previous_price NUMERIC(10, 2),
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,
+    status scheduled_price_status NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_product_scheduled_price
+        FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    CONSTRAINT chk_scheduled_price_period
+        CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX idx_scheduled_prices_status_starts_at ON scheduled_prices (status, starts_at);
\ No newline at end of file
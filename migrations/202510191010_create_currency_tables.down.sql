@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS exchange_rate,
+    DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS product_prices;
+DROP TABLE IF EXISTS exchange_rates;
\ No newline at end of file
@@ -0,0 +1,24 @@
+CREATE TABLE exchange_rates (
+    id SERIAL PRIMARY KEY,
+    currency CHAR(3) NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    effective_from TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_exchange_rates_currency_effective_from ON exchange_rates (currency, effective_from);
+
+CREATE TABLE product_prices (
+    product_id INT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, currency),
+    CONSTRAINT fk_product_product_price
+        FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+-- existing orders were placed in the base currency
+ALTER TABLE orders
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR',
+    ADD COLUMN exchange_rate NUMERIC(18, 8) NOT NULL DEFAULT 1;
\ No newline at end of file
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS order_tax_lines;
+
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS tax_rate,
+    DROP COLUMN IF EXISTS tax_class;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tax_mode,
+    DROP COLUMN IF EXISTS shipping_region,
+    DROP COLUMN IF EXISTS shipping_country;
+
+DROP TABLE IF EXISTS tax_rules;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS tax_class;
\ No newline at end of file
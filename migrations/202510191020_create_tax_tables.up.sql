@@ -0,0 +1,35 @@
+ALTER TABLE products
+    ADD COLUMN tax_class VARCHAR(32) NOT NULL DEFAULT 'standard';
+
+UPDATE products SET tax_class = 'reduced' WHERE type = 'book';
+
+CREATE TABLE tax_rules (
+    id SERIAL PRIMARY KEY,
+    country CHAR(2) NOT NULL,
+    region VARCHAR(64) NOT NULL DEFAULT '',
+    tax_class VARCHAR(32) NOT NULL,
+    rate NUMERIC(6, 4) NOT NULL CHECK (rate >= 0 AND rate <= 1),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_tax_rules_country_region_class UNIQUE (country, region, tax_class)
+);
+
+ALTER TABLE orders
+    ADD COLUMN shipping_country CHAR(2) NOT NULL DEFAULT '',
+    ADD COLUMN shipping_region VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN tax_mode VARCHAR(16) NOT NULL DEFAULT 'exclusive';
+
+ALTER TABLE order_items
+    ADD COLUMN tax_class VARCHAR(32) NOT NULL DEFAULT 'standard',
+    ADD COLUMN tax_rate NUMERIC(6, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_amount NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE order_tax_lines (
+    order_id INT NOT NULL,
+    tax_class VARCHAR(32) NOT NULL,
+    rate NUMERIC(6, 4) NOT NULL,
+    taxable_amount NUMERIC(12, 2) NOT NULL,
+    tax_amount NUMERIC(12, 2) NOT NULL,
+    PRIMARY KEY (order_id, tax_class, rate),
+    CONSTRAINT fk_order_tax_line
+        FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
\ No newline at end of file
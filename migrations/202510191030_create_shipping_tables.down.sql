@@ -0,0 +1,11 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tracking_number,
+    DROP COLUMN IF EXISTS shipping_cost,
+    DROP COLUMN IF EXISTS shipping_method;
+
+DROP TABLE IF EXISTS shipping_rates;
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS shipping_zones;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS weight_grams;
\ No newline at end of file
@@ -0,0 +1,37 @@
+ALTER TABLE products
+    ADD COLUMN weight_grams INT NOT NULL DEFAULT 0;
+
+-- countries holds ISO 3166-1 alpha-2 codes, '*' matches any country not covered by another zone
+CREATE TABLE shipping_zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    countries TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE shipping_methods (
+    id SERIAL PRIMARY KEY,
+    zone_id INT NOT NULL,
+    code VARCHAR(32) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    basis VARCHAR(32) NOT NULL,
+    free_threshold NUMERIC(10, 2),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_shipping_methods_zone_code UNIQUE (zone_id, code),
+    CONSTRAINT fk_shipping_zone_method
+        FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+);
+
+CREATE TABLE shipping_rates (
+    method_id INT NOT NULL,
+    min_value NUMERIC(12, 2) NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    PRIMARY KEY (method_id, min_value),
+    CONSTRAINT fk_shipping_method_rate
+        FOREIGN KEY (method_id) REFERENCES shipping_methods(id) ON DELETE CASCADE
+);
+
+ALTER TABLE orders
+    ADD COLUMN shipping_method VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN shipping_cost NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN tracking_number VARCHAR(64) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,32 @@
+CREATE TABLE payments (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    provider_ref VARCHAR(128),
+    status VARCHAR(32) NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    refunded_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    failure_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_payments_provider_ref UNIQUE (provider, provider_ref),
+    CONSTRAINT fk_order_payment
+        FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_payments_order_id ON payments (order_id);
+
+-- provider callbacks, unique event ids make duplicate deliveries no-ops
+CREATE TABLE payment_events (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    event_id VARCHAR(128) NOT NULL,
+    payment_id INT NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_payment_events_provider_event UNIQUE (provider, event_id),
+    CONSTRAINT fk_payment_event
+        FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS order_history;
+DROP TABLE IF EXISTS return_items;
+DROP TABLE IF EXISTS returns;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS damaged_stock;
@@ -0,0 +1,49 @@
+-- returned goods that can not be sold again
+ALTER TABLE products
+    ADD COLUMN damaged_stock INT NOT NULL DEFAULT 0;
+
+CREATE TABLE returns (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    reason TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    refund_amount NUMERIC(12, 2) NOT NULL,
+    refunded_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    payment_id INT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_order_return
+        FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT fk_payment_return
+        FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_returns_order_id ON returns (order_id);
+
+-- price and tax_amount are the refundable amounts for the returned quantity
+CREATE TABLE return_items (
+    return_id INT NOT NULL,
+    product_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    damaged INT NOT NULL DEFAULT 0,
+    price NUMERIC(10, 2) NOT NULL,
+    tax_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (return_id, product_id),
+    CONSTRAINT fk_return_item
+        FOREIGN KEY (return_id) REFERENCES returns(id) ON DELETE CASCADE,
+    CONSTRAINT fk_product_return_item
+        FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE TABLE order_history (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_order_history
+        FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_order_history_order_id ON order_history (order_id);
@@ -0,0 +1,14 @@
+-- stored responses of POST requests sent with an Idempotency-Key header
+CREATE TABLE idempotency_keys (
+    key VARCHAR(255) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    response_status INT NOT NULL DEFAULT 0,
+    response_body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (key, route)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS version;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS version;
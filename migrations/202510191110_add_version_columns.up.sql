@@ -0,0 +1,6 @@
+-- row versions for optimistic concurrency control, bumped on every update
+ALTER TABLE products
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE orders
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
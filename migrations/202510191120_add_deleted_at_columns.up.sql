@@ -0,0 +1,9 @@
+-- soft delete, deleted rows stay in the trash until they are purged after the retention period
+ALTER TABLE products
+    ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE orders
+    ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_products_deleted_at ON products (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_orders_deleted_at ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS issue_number,
+    DROP COLUMN IF EXISTS isbn,
+    DROP COLUMN IF EXISTS product_type,
+    DROP COLUMN IF EXISTS product_name;
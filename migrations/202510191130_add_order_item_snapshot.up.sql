@@ -0,0 +1,16 @@
+-- product details as they were when the item was ordered, the price is already kept in 'price'
+ALTER TABLE order_items
+    ADD COLUMN product_name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN product_type VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN isbn VARCHAR(20),
+    ADD COLUMN issue_number INT;
+
+UPDATE order_items oi
+SET product_name = p.name,
+    product_type = p.type,
+    isbn = b.isbn,
+    issue_number = m.issue_number
+FROM products p
+LEFT JOIN books b ON b.product_id = p.id
+LEFT JOIN magazines m ON m.product_id = p.id
+WHERE p.id = oi.product_id;
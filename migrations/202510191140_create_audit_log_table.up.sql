@@ -0,0 +1,24 @@
+-- append-only log of catalog and order changes
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id INT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log (entity_type, entity_id, created_at);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
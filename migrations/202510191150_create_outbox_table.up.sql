@@ -0,0 +1,16 @@
+-- domain events written in the same transaction as the change, delivered by the dispatcher
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_type VARCHAR(32) NOT NULL,
+    aggregate_id INT NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    dispatched_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (aggregate_type, aggregate_id, id) WHERE dispatched_at IS NULL;
+CREATE INDEX idx_outbox_dispatched_at ON outbox (dispatched_at) WHERE dispatched_at IS NOT NULL;
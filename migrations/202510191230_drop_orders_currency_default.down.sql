@@ -0,0 +1,2 @@
+ALTER TABLE orders
+    ALTER COLUMN currency SET DEFAULT 'EUR';
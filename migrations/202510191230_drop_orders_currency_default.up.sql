@@ -0,0 +1,3 @@
+-- the base currency is configurable, orders are always written with their currency
+ALTER TABLE orders
+    ALTER COLUMN currency DROP DEFAULT;
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations, named <version>_<title>.up.sql and
+// <version>_<title>.down.sql as golang-migrate expects, into the binary.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS